@@ -0,0 +1,278 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+)
+
+const (
+	// adminUsersListLimit is the number of connected users listed by `/github admin users`.
+	adminUsersListLimit = 200
+	// lastAPICallRecordInterval is how often the last successful API call of a user is stored.
+	lastAPICallRecordInterval = time.Hour
+	// lastAPICallCacheSize is the number of users whose last recorded API call is remembered.
+	lastAPICallCacheSize = 10000
+
+	adminDateFormat = "2006-01-02 15:04 MST"
+
+	adminDisconnectMessage = "Your GitHub account was disconnected by a System Admin. Reconnect your account using `/github connect`."
+)
+
+// connectedUser is a Mattermost user connected to a GitHub account, as listed to System Admins.
+type connectedUser struct {
+	Username            string
+	GitHubUsername      string
+	ConnectedAt         int64
+	AllowedPrivateRepos bool
+	LastAPICallAt       int64
+}
+
+// recordSuccessfulAPICall stores when the user last used their token successfully. Calls are
+// only stored once per lastAPICallRecordInterval to keep KV writes off the request path.
+func (p *Plugin) recordSuccessfulAPICall(userID string) {
+	if _, ok := p.lastAPICallCache.get(userID); ok {
+		return
+	}
+	p.lastAPICallCache.add(userID, true)
+
+	if _, err := p.store.Set(userID+githubLastAPICallKey, model.GetMillis()); err != nil {
+		p.client.Log.Warn("Failed to store the last GitHub API call", "user_id", userID, "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleAdmin(_ *plugin.Context, args *model.CommandArgs, parameters []string) string {
+	isSysAdmin, err := p.isAuthorizedSysAdmin(args.UserId)
+	if err != nil {
+		p.client.Log.Warn("Failed to check if user is System Admin", "error", err.Error())
+
+		return "Error checking user's permissions"
+	}
+
+	if !isSysAdmin {
+		return "Only System Admins are allowed to use this command."
+	}
+
+	if len(parameters) == 0 {
+		return "Please specify a subcommand. Available subcommands are `users`, `disconnect` and `mappings`."
+	}
+
+	switch parameters[0] {
+	case "users":
+		return p.handleAdminUsers()
+	case "disconnect":
+		if len(parameters) != 2 {
+			return "Please specify the user to disconnect: `/github admin disconnect @username`."
+		}
+		return p.handleAdminDisconnect(args.UserId, parameters[1])
+	case "mappings":
+		return p.handleAdminMappings()
+	default:
+		return fmt.Sprintf("Unknown subcommand %v", parameters[0])
+	}
+}
+
+func (p *Plugin) listConnectedUsers() ([]*connectedUser, error) {
+	keys, _, err := p.listUserTokenKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*connectedUser, 0, len(keys))
+	for _, key := range keys {
+		userID := strings.TrimSuffix(key, githubTokenKey)
+
+		// The tokens aren't needed, so the user info is read without decrypting them.
+		var userInfo *GitHubUserInfo
+		if err := p.store.Get(key, &userInfo); err != nil {
+			p.client.Log.Warn("Failed to load user info", "user_id", userID, "error", err.Error())
+			continue
+		}
+		if userInfo == nil {
+			continue
+		}
+
+		username := userID
+		if user, err := p.client.User.Get(userID); err == nil {
+			username = user.Username
+		}
+
+		var lastAPICallAt int64
+		if err := p.store.Get(userID+githubLastAPICallKey, &lastAPICallAt); err != nil {
+			p.client.Log.Warn("Failed to load the last GitHub API call", "user_id", userID, "error", err.Error())
+		}
+		// A call recorded before the current connection belongs to a previous one.
+		if lastAPICallAt < userInfo.ConnectedAt {
+			lastAPICallAt = 0
+		}
+
+		users = append(users, &connectedUser{
+			Username:            username,
+			GitHubUsername:      userInfo.GitHubUsername,
+			ConnectedAt:         userInfo.ConnectedAt,
+			AllowedPrivateRepos: userInfo.AllowedPrivateRepos,
+			LastAPICallAt:       lastAPICallAt,
+		})
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+
+	return users, nil
+}
+
+func (p *Plugin) handleAdminUsers() string {
+	users, err := p.listConnectedUsers()
+	if err != nil {
+		p.client.Log.Warn("Failed to list connected users", "error", err.Error())
+		return "Failed to list the connected users."
+	}
+
+	if len(users) == 0 {
+		return "No users are connected to GitHub."
+	}
+
+	txt := fmt.Sprintf("### Connected users (%d)\n\n", len(users))
+	txt += "| Mattermost user | GitHub account | Connected | Private repositories | Last API call |\n"
+	txt += "| --- | --- | --- | --- | --- |\n"
+	for i, user := range users {
+		if i == adminUsersListLimit {
+			txt += fmt.Sprintf("\n_And %d more._\n", len(users)-adminUsersListLimit)
+			break
+		}
+
+		privateRepos := "No"
+		if user.AllowedPrivateRepos {
+			privateRepos = "Yes"
+		}
+
+		txt += fmt.Sprintf("| @%s | %s | %s | %s | %s |\n",
+			user.Username,
+			user.GitHubUsername,
+			formatAdminDate(user.ConnectedAt, "Unknown"),
+			privateRepos,
+			formatAdminDate(user.LastAPICallAt, "Unknown"),
+		)
+	}
+
+	return txt
+}
+
+func formatAdminDate(millis int64, fallback string) string {
+	if millis == 0 {
+		return fallback
+	}
+
+	return time.UnixMilli(millis).UTC().Format(adminDateFormat)
+}
+
+func (p *Plugin) handleAdminDisconnect(adminUserID, username string) string {
+	auditRec := plugin.MakeAuditRecord("adminDisconnectUser", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = adminUserID
+
+	username = strings.TrimPrefix(username, "@")
+	model.AddEventParameterAuditableToAuditRec(auditRec, "disconnect", AdminDisconnectUserAuditParams{
+		Username: username,
+	})
+
+	user, err := p.client.User.GetByUsername(username)
+	if err != nil {
+		auditRec.AddErrorDesc(err.Error())
+		return fmt.Sprintf("User @%s not found.", username)
+	}
+
+	var userInfo *GitHubUserInfo
+	if err = p.store.Get(user.Id+githubTokenKey, &userInfo); err != nil {
+		p.client.Log.Warn("Failed to load user info", "user_id", user.Id, "error", err.Error())
+		auditRec.AddErrorDesc(err.Error())
+		return fmt.Sprintf("Failed to load the GitHub connection of @%s.", username)
+	}
+	if userInfo == nil {
+		auditRec.AddErrorDesc("user is not connected to GitHub")
+		return fmt.Sprintf("@%s is not connected to GitHub.", username)
+	}
+
+	// The adminDisconnectUser record covers the disconnect, so it doesn't get a forceDisconnectUser record.
+	if _, err = p.removeGitHubConnection(user.Id, userInfo.GitHubUsername, adminDisconnectMessage); err != nil {
+		auditRec.AddErrorDesc(err.Error())
+		return fmt.Sprintf("Failed to disconnect @%s from GitHub.", username)
+	}
+
+	auditRec.Success()
+	auditRec.AddEventResultState(AdminDisconnectUserAuditResult{
+		UserID:         user.Id,
+		GitHubUsername: userInfo.GitHubUsername,
+	})
+
+	return fmt.Sprintf("@%s has been disconnected from the GitHub account %s.", username, userInfo.GitHubUsername)
+}
+
+func (p *Plugin) handleAdminMappings() string {
+	report, err := p.checkUserMappings()
+	if err != nil {
+		p.client.Log.Warn("Failed to check the GitHub account mappings", "error", err.Error())
+		return "Failed to check the GitHub account mappings."
+	}
+
+	if len(report.Orphaned) == 0 && len(report.Conflicting) == 0 && len(report.Unmapped) == 0 {
+		return "The GitHub account mappings are consistent."
+	}
+
+	txt := ""
+	if len(report.Conflicting) > 0 {
+		txt += "### Accounts connected by several users\nOnly the mapped user gets the notifications of these accounts.\n"
+		for _, issue := range report.Conflicting {
+			txt += fmt.Sprintf("* %s: mapped to %s, connected by %s\n", issue.GitHubUsername, p.formatMappedUser(issue.MappedUserID), p.formatUsers(issue.ConnectedUserIDs))
+		}
+	}
+	if len(report.Orphaned) > 0 {
+		txt += "### Orphaned mappings\nThese accounts are mapped to users who aren't connected to them.\n"
+		for _, issue := range report.Orphaned {
+			connectedBy := "nobody"
+			if len(issue.ConnectedUserIDs) > 0 {
+				connectedBy = p.formatUsers(issue.ConnectedUserIDs)
+			}
+			txt += fmt.Sprintf("* %s: mapped to %s, connected by %s\n", issue.GitHubUsername, p.formatMappedUser(issue.MappedUserID), connectedBy)
+		}
+	}
+	if len(report.Unmapped) > 0 {
+		txt += "### Unmapped accounts\nThe users connected to these accounts don't get their notifications.\n"
+		for _, issue := range report.Unmapped {
+			txt += fmt.Sprintf("* %s: connected by %s\n", issue.GitHubUsername, p.formatUsers(issue.ConnectedUserIDs))
+		}
+	}
+
+	return txt
+}
+
+func (p *Plugin) formatMappedUser(userID string) string {
+	if userID == "" {
+		return "nobody"
+	}
+
+	return p.formatUsers([]string{userID})
+}
+
+// formatUsers mentions the users, falling back to their IDs for users who can't be found.
+func (p *Plugin) formatUsers(userIDs []string) string {
+	names := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		user, err := p.client.User.Get(userID)
+		if err != nil {
+			names = append(names, fmt.Sprintf("unknown user `%s`", userID))
+			continue
+		}
+		names = append(names, "@"+user.Username)
+	}
+
+	return strings.Join(names, ", ")
+}
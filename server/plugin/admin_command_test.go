@@ -0,0 +1,147 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mattermost/mattermost-plugin-github/server/mocks"
+)
+
+func TestHandleAdmin(t *testing.T) {
+	connectedAt := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC).UnixMilli()
+	lastAPICallAt := time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC).UnixMilli()
+
+	expectUserInfo := func(mockKvStore *mocks.MockKvStore, info *GitHubUserInfo) {
+		mockKvStore.EXPECT().Get("user1"+githubTokenKey, gomock.Any()).DoAndReturn(func(key string, value any) error {
+			*value.(**GitHubUserInfo) = info
+			return nil
+		})
+	}
+
+	tests := map[string]struct {
+		parameters  []string
+		roles       string
+		setup       func(*plugintest.API, *mocks.MockKvStore)
+		expectedMsg string
+	}{
+		"not a system admin": {
+			parameters:  []string{"users"},
+			roles:       model.SystemUserRoleId,
+			setup:       func(*plugintest.API, *mocks.MockKvStore) {},
+			expectedMsg: "Only System Admins are allowed to use this command.",
+		},
+		"unknown subcommand": {
+			parameters:  []string{"unknown"},
+			roles:       model.SystemAdminRoleId,
+			setup:       func(*plugintest.API, *mocks.MockKvStore) {},
+			expectedMsg: "Unknown subcommand unknown",
+		},
+		"no connected users": {
+			parameters: []string{"users"},
+			roles:      model.SystemAdminRoleId,
+			setup: func(_ *plugintest.API, mockKvStore *mocks.MockKvStore) {
+				mockKvStore.EXPECT().ListKeys(0, keysPerPage, gomock.Any()).Return([]string{}, nil)
+			},
+			expectedMsg: "No users are connected to GitHub.",
+		},
+		"list connected users": {
+			parameters: []string{"users"},
+			roles:      model.SystemAdminRoleId,
+			setup: func(mockAPI *plugintest.API, mockKvStore *mocks.MockKvStore) {
+				mockKvStore.EXPECT().ListKeys(0, keysPerPage, gomock.Any()).Return([]string{"user1" + githubTokenKey}, nil)
+				expectUserInfo(mockKvStore, &GitHubUserInfo{
+					UserID:              "user1",
+					GitHubUsername:      "ghuser1",
+					ConnectedAt:         connectedAt,
+					AllowedPrivateRepos: true,
+				})
+				mockKvStore.EXPECT().Get("user1"+githubLastAPICallKey, gomock.Any()).DoAndReturn(func(key string, value any) error {
+					*value.(*int64) = lastAPICallAt
+					return nil
+				})
+				mockAPI.On("GetUser", "user1").Return(&model.User{Id: "user1", Username: "alice"}, nil)
+			},
+			expectedMsg: "| @alice | ghuser1 | 2024-03-01 12:30 UTC | Yes | 2024-03-05 08:00 UTC |\n",
+		},
+		"disconnect without a user": {
+			parameters:  []string{"disconnect"},
+			roles:       model.SystemAdminRoleId,
+			setup:       func(*plugintest.API, *mocks.MockKvStore) {},
+			expectedMsg: "Please specify the user to disconnect: `/github admin disconnect @username`.",
+		},
+		"disconnect a user who isn't connected": {
+			parameters: []string{"disconnect", "@alice"},
+			roles:      model.SystemAdminRoleId,
+			setup: func(mockAPI *plugintest.API, mockKvStore *mocks.MockKvStore) {
+				mockAPI.On("GetUserByUsername", "alice").Return(&model.User{Id: "user1", Username: "alice"}, nil)
+				expectUserInfo(mockKvStore, nil)
+				mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+					return rec.EventName == "adminDisconnectUser" && rec.Status == model.AuditStatusFail
+				})).Return().Once()
+			},
+			expectedMsg: "@alice is not connected to GitHub.",
+		},
+		"disconnect a user": {
+			parameters: []string{"disconnect", "@alice"},
+			roles:      model.SystemAdminRoleId,
+			setup: func(mockAPI *plugintest.API, mockKvStore *mocks.MockKvStore) {
+				mockAPI.On("GetUserByUsername", "alice").Return(&model.User{Id: "user1", Username: "alice"}, nil)
+				expectUserInfo(mockKvStore, &GitHubUserInfo{UserID: "user1", GitHubUsername: "ghuser1"})
+
+				mockKvStore.EXPECT().Delete("user1" + githubTokenKey).Return(nil)
+				mockKvStore.EXPECT().Delete("user1" + githubPrivateRepoKey).Return(nil)
+				expectUserIDMapping(mockKvStore, "ghuser1", "user1")
+				mockKvStore.EXPECT().Delete("ghuser1" + githubUsernameKey).Return(nil)
+				mockAPI.On("GetUser", "user1").Return(&model.User{Id: "user1", Props: model.StringMap{}}, nil)
+				mockAPI.On("PublishWebSocketEvent", wsEventDisconnect, map[string]any(nil),
+					&model.WebsocketBroadcast{UserId: "user1"}).Once()
+				mockAPI.On("GetDirectChannel", "user1", MockBotID).Return(&model.Channel{Id: "dmchannel"}, nil)
+				mockAPI.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+					return post.ChannelId == "dmchannel" && post.Message == adminDisconnectMessage
+				})).Return(&model.Post{}, nil).Once()
+
+				// The disconnect is only recorded once, as done by the admin.
+				mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+					return rec.EventName == "adminDisconnectUser" &&
+						rec.Status == model.AuditStatusSuccess &&
+						rec.Actor.UserId == MockUserID
+				})).Return().Once()
+			},
+			expectedMsg: "@alice has been disconnected from the GitHub account ghuser1.",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+			p := getPluginTest(mockAPI, mockKvStore)
+			mockAPI.On("GetUser", MockUserID).Return(&model.User{Id: MockUserID, Roles: tc.roles}, nil)
+			tc.setup(mockAPI, mockKvStore)
+
+			msg := p.handleAdmin(&plugin.Context{}, &model.CommandArgs{UserId: MockUserID}, tc.parameters)
+
+			assert.Contains(t, msg, tc.expectedMsg)
+			mockAPI.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRecordSuccessfulAPICall(t *testing.T) {
+	mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKvStore)
+
+	mockKvStore.EXPECT().Set("user1"+githubLastAPICallKey, gomock.Any()).Return(true, nil).Times(1)
+
+	p.recordSuccessfulAPICall("user1")
+	// Calls within the record interval aren't stored again.
+	p.recordSuccessfulAPICall("user1")
+}
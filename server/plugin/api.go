@@ -181,6 +181,13 @@ func (p *Plugin) initializeAPI() {
 	apiRouter.HandleFunc("/settings", p.checkAuth(p.attachUserContext(p.updateSettings), ResponseTypePlain)).Methods(http.MethodPost)
 	apiRouter.HandleFunc("/issue", p.checkAuth(p.attachUserContext(p.getIssueByNumber), ResponseTypePlain)).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/pr", p.checkAuth(p.attachUserContext(p.getPrByNumber), ResponseTypePlain)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/takeover", p.checkAuth(p.attachContext(p.handleTakeOverAction), ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/review-reminder", p.checkAuth(p.attachContext(p.handleReviewReminderAction), ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/notification-action", p.checkAuth(p.attachContext(p.handleNotificationAction), ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/notification-action/dialog", p.checkAuth(p.attachContext(p.handleNotificationActionDialog), ResponseTypeJSON)).Methods(http.MethodPost)
+	// Pull request notifications posted before the routes were renamed still point to /pr-action.
+	apiRouter.HandleFunc("/pr-action", p.checkAuth(p.attachContext(p.handleLegacyPRAction), ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/pr-action/dialog", p.checkAuth(p.attachContext(p.handleLegacyPRActionDialog), ResponseTypeJSON)).Methods(http.MethodPost)
 	apiRouter.HandleFunc("/lhs-content", p.checkAuth(p.attachUserContext(p.getSidebarContent), ResponseTypePlain)).Methods(http.MethodGet)
 
 	apiRouter.HandleFunc("/config", checkPluginRequest(p.getConfig)).Methods(http.MethodGet)
@@ -443,11 +450,27 @@ func (p *Plugin) completeConnectUserToGitHub(c *Context, w http.ResponseWriter,
 		return
 	}
 
+	if p.getConfiguration().RequireMatchingIdentity {
+		if rErr = p.checkGitHubIdentity(ctx, c, w, githubClient, gitUser.GetLogin(), state.UserID); rErr != nil {
+			return
+		}
+	}
+
+	if p.getConfiguration().isOrganizationMembershipEnforced() {
+		if rErr = p.checkOrganizationMembership(ctx, c, w, githubClient, gitUser.GetLogin()); rErr != nil {
+			return
+		}
+	}
+
+	// A login connected by another user is only taken over once the connecting user confirms it.
+	previousInfo := p.getOtherConnectedUser(gitUser.GetLogin(), state.UserID)
+
 	userInfo := &GitHubUserInfo{
 		UserID:         state.UserID,
 		Token:          tok,
 		GitHubUsername: gitUser.GetLogin(),
 		LastToDoPostAt: model.GetMillis(),
+		ConnectedAt:    model.GetMillis(),
 		Settings: &UserSettings{
 			SidebarButtons: settingButtonsTeam,
 			DailyReminder:  true,
@@ -463,7 +486,9 @@ func (p *Plugin) completeConnectUserToGitHub(c *Context, w http.ResponseWriter,
 		return
 	}
 
-	if err = p.storeGitHubToUserIDMapping(gitUser.GetLogin(), state.UserID); err != nil {
+	if previousInfo != nil {
+		p.offerGitHubAccountTakeover(state.UserID, gitUser.GetLogin(), previousInfo.UserID)
+	} else if err = p.storeGitHubToUserIDMapping(gitUser.GetLogin(), state.UserID); err != nil {
 		c.Log.WithError(err).Warnf("Failed to store GitHub user info mapping")
 	}
 
@@ -653,7 +678,9 @@ func (p *Plugin) getConnected(c *Context, w http.ResponseWriter, r *http.Request
 					c.Log.WithError(err).Warnf("Failed to create GitHub todo message")
 				}
 				info.LastToDoPostAt = now
-				if err := p.storeGitHubUserInfo(info, p.getConfiguration().EncryptionKey); err != nil {
+				if err := p.updateGitHubUserInfo(info.UserID, func(stored *GitHubUserInfo) {
+					stored.LastToDoPostAt = now
+				}); err != nil {
 					c.Log.WithError(err).Warnf("Failed to store github info for new user")
 				}
 			}
@@ -1051,6 +1078,15 @@ func (p *Plugin) createIssueComment(c *UserContext, w http.ResponseWriter, r *ht
 		Body: &req.Comment,
 	}
 
+	auditRec := plugin.MakeAuditRecord("createIssueComment", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = c.UserID
+	model.AddEventParameterAuditableToAuditRec(auditRec, "comment", CreateIssueCommentAuditParams{
+		Repository: fullNameFromOwnerAndRepo(req.Owner, req.Repo),
+		Number:     req.Number,
+		PostID:     req.PostID,
+	})
+
 	var result *github.IssueComment
 	var rawResponse *github.Response
 	if cErr := p.useGitHubClient(c.GHInfo, func(info *GitHubUserInfo, token *oauth2.Token) error {
@@ -1060,6 +1096,7 @@ func (p *Plugin) createIssueComment(c *UserContext, w http.ResponseWriter, r *ht
 		}
 		return nil
 	}); cErr != nil {
+		auditRec.AddErrorDesc(cErr.Error())
 		statusCode := 500
 		if rawResponse != nil {
 			statusCode = rawResponse.StatusCode
@@ -1073,6 +1110,11 @@ func (p *Plugin) createIssueComment(c *UserContext, w http.ResponseWriter, r *ht
 		return
 	}
 
+	auditRec.Success()
+	auditRec.AddEventResultState(CreateIssueCommentAuditResult{
+		URL: result.GetHTMLURL(),
+	})
+
 	rootID := req.PostID
 	if post.RootId != "" {
 		// the original post was a reply
@@ -1108,6 +1150,15 @@ func (p *Plugin) getLHSData(c *UserContext) (reviewResp []*graphql.GithubPRDetai
 }
 
 func (p *Plugin) getSidebarData(c *UserContext) (*SidebarContent, error) {
+	if p.isMembershipDowngraded(c.GHInfo) {
+		return &SidebarContent{
+			PRs:         []*graphql.GithubPRDetails{},
+			Reviews:     []*graphql.GithubPRDetails{},
+			Assignments: []*github.Issue{},
+			Unreads:     []*FilteredNotification{},
+		}, nil
+	}
+
 	reviewResp, assignmentResp, openPRResp, err := p.getLHSData(c)
 	if err != nil {
 		return nil, err
@@ -1167,16 +1218,20 @@ func (p *Plugin) updateSettings(c *UserContext, w http.ResponseWriter, r *http.R
 		return
 	}
 
-	info := c.GHInfo
-	info.Settings = settings
+	if (settings.Notifications || settings.DailyReminder || settings.StalePRReminder || settings.ThreadReplySync) && p.isMembershipDowngraded(c.GHInfo) {
+		p.writeAPIError(w, &APIErrorResponse{Message: membershipDowngradedSettingsMessage, StatusCode: http.StatusForbidden})
+		return
+	}
 
-	if err := p.storeGitHubUserInfo(info, p.getConfiguration().EncryptionKey); err != nil {
+	if err := p.updateGitHubUserInfo(c.UserID, func(stored *GitHubUserInfo) {
+		stored.Settings = settings
+	}); err != nil {
 		c.Log.WithError(err).Errorf("Failed to store GitHub user info")
 		p.writeAPIError(w, &APIErrorResponse{Message: "error occurred while updating settings", StatusCode: http.StatusInternalServerError})
 		return
 	}
 
-	p.writeJSON(w, info.Settings)
+	p.writeJSON(w, settings)
 }
 
 func (p *Plugin) getIssueByNumber(c *UserContext, w http.ResponseWriter, r *http.Request) {
@@ -1281,26 +1336,34 @@ func (p *Plugin) getLabels(c *UserContext, w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	githubClient := p.githubConnectUser(c.Ctx, c.GHInfo)
+	allLabels, err := p.listRepoLabels(c.Ctx, c.GHInfo, owner, repo)
+	if err != nil {
+		c.Log.WithError(err).With(logger.LogContext{
+			"owner": owner,
+			"repo":  repo,
+		}).Errorf("Failed to list labels")
+		p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch labels", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	p.writeJSON(w, allLabels)
+}
+
+// listRepoLabels returns all labels of the repository visible to the user.
+func (p *Plugin) listRepoLabels(ctx context.Context, info *GitHubUserInfo, owner, repo string) ([]*github.Label, error) {
+	githubClient := p.githubConnectUser(ctx, info)
 	var allLabels []*github.Label
 	opt := github.ListOptions{PerPage: 50}
 
 	for {
 		var labels []*github.Label
 		var resp *github.Response
-		if cErr := p.useGitHubClient(c.GHInfo, func(info *GitHubUserInfo, token *oauth2.Token) error {
-			labels, resp, err = githubClient.Issues.ListLabels(c.Ctx, owner, repo, &opt)
-			if err != nil {
-				return err
-			}
-			return nil
-		}); cErr != nil {
-			c.Log.WithError(cErr).With(logger.LogContext{
-				"owner": owner,
-				"repo":  repo,
-			}).Errorf("Failed to list labels")
-			p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch labels", StatusCode: http.StatusInternalServerError})
-			return
+		if err := p.useGitHubClient(info, func(info *GitHubUserInfo, token *oauth2.Token) error {
+			var err error
+			labels, resp, err = githubClient.Issues.ListLabels(ctx, owner, repo, &opt)
+			return err
+		}); err != nil {
+			return nil, err
 		}
 		allLabels = append(allLabels, labels...)
 		if resp.NextPage == 0 {
@@ -1309,7 +1372,7 @@ func (p *Plugin) getLabels(c *UserContext, w http.ResponseWriter, r *http.Reques
 		opt.Page = resp.NextPage
 	}
 
-	p.writeJSON(w, allLabels)
+	return allLabels, nil
 }
 
 func (p *Plugin) getAssignees(c *UserContext, w http.ResponseWriter, r *http.Request) {
@@ -1319,26 +1382,34 @@ func (p *Plugin) getAssignees(c *UserContext, w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	githubClient := p.githubConnectUser(c.Ctx, c.GHInfo)
+	allAssignees, err := p.listRepoAssignees(c.Ctx, c.GHInfo, owner, repo)
+	if err != nil {
+		c.Log.WithError(err).With(logger.LogContext{
+			"owner": owner,
+			"repo":  repo,
+		}).Errorf("Failed to list assignees")
+		p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch assignees", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	p.writeJSON(w, allAssignees)
+}
+
+// listRepoAssignees returns all assignees of the repository visible to the user.
+func (p *Plugin) listRepoAssignees(ctx context.Context, info *GitHubUserInfo, owner, repo string) ([]*github.User, error) {
+	githubClient := p.githubConnectUser(ctx, info)
 	var allAssignees []*github.User
 	opt := github.ListOptions{PerPage: 50}
 
 	for {
 		var assignees []*github.User
 		var resp *github.Response
-		if cErr := p.useGitHubClient(c.GHInfo, func(info *GitHubUserInfo, token *oauth2.Token) error {
-			assignees, resp, err = githubClient.Issues.ListAssignees(c.Ctx, owner, repo, &opt)
-			if err != nil {
-				return err
-			}
-			return nil
-		}); cErr != nil {
-			c.Log.WithError(cErr).With(logger.LogContext{
-				"owner": owner,
-				"repo":  repo,
-			}).Errorf("Failed to list assignees")
-			p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch assignees", StatusCode: http.StatusInternalServerError})
-			return
+		if err := p.useGitHubClient(info, func(info *GitHubUserInfo, token *oauth2.Token) error {
+			var err error
+			assignees, resp, err = githubClient.Issues.ListAssignees(ctx, owner, repo, &opt)
+			return err
+		}); err != nil {
+			return nil, err
 		}
 		allAssignees = append(allAssignees, assignees...)
 		if resp.NextPage == 0 {
@@ -1347,7 +1418,7 @@ func (p *Plugin) getAssignees(c *UserContext, w http.ResponseWriter, r *http.Req
 		opt.Page = resp.NextPage
 	}
 
-	p.writeJSON(w, allAssignees)
+	return allAssignees, nil
 }
 
 func (p *Plugin) getMilestones(c *UserContext, w http.ResponseWriter, r *http.Request) {
@@ -1357,26 +1428,34 @@ func (p *Plugin) getMilestones(c *UserContext, w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	githubClient := p.githubConnectUser(c.Ctx, c.GHInfo)
+	allMilestones, err := p.listRepoMilestones(c.Ctx, c.GHInfo, owner, repo)
+	if err != nil {
+		c.Log.WithError(err).With(logger.LogContext{
+			"owner": owner,
+			"repo":  repo,
+		}).Errorf("Failed to list milestones")
+		p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch milestones", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	p.writeJSON(w, allMilestones)
+}
+
+// listRepoMilestones returns all milestones of the repository visible to the user.
+func (p *Plugin) listRepoMilestones(ctx context.Context, info *GitHubUserInfo, owner, repo string) ([]*github.Milestone, error) {
+	githubClient := p.githubConnectUser(ctx, info)
 	var allMilestones []*github.Milestone
 	opt := github.ListOptions{PerPage: 50}
 
 	for {
 		var milestones []*github.Milestone
 		var resp *github.Response
-		if cErr := p.useGitHubClient(c.GHInfo, func(info *GitHubUserInfo, token *oauth2.Token) error {
-			milestones, resp, err = githubClient.Issues.ListMilestones(c.Ctx, owner, repo, &github.MilestoneListOptions{ListOptions: opt})
-			if err != nil {
-				return err
-			}
-			return nil
-		}); cErr != nil {
-			c.Log.WithError(cErr).With(logger.LogContext{
-				"owner": owner,
-				"repo":  repo,
-			}).Errorf("Failed to list milestones")
-			p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch milestones", StatusCode: http.StatusInternalServerError})
-			return
+		if err := p.useGitHubClient(info, func(info *GitHubUserInfo, token *oauth2.Token) error {
+			var err error
+			milestones, resp, err = githubClient.Issues.ListMilestones(ctx, owner, repo, &github.MilestoneListOptions{ListOptions: opt})
+			return err
+		}); err != nil {
+			return nil, err
 		}
 		allMilestones = append(allMilestones, milestones...)
 		if resp.NextPage == 0 {
@@ -1385,7 +1464,7 @@ func (p *Plugin) getMilestones(c *UserContext, w http.ResponseWriter, r *http.Re
 		opt.Page = resp.NextPage
 	}
 
-	p.writeJSON(w, allMilestones)
+	return allMilestones, nil
 }
 
 func getOrganizationList(c context.Context, userName string, githubClient *github.Client, opt github.ListOptions) ([]*github.Organization, error) {
@@ -1801,6 +1880,19 @@ func (p *Plugin) createIssue(c *UserContext, w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	auditChannelID := issue.ChannelID
+	if post != nil {
+		auditChannelID = post.ChannelId
+	}
+	auditRec := plugin.MakeAuditRecord("createIssue", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = c.UserID
+	model.AddEventParameterAuditableToAuditRec(auditRec, "issue", CreateIssueAuditParams{
+		Repository: fullNameFromOwnerAndRepo(owner, repoName),
+		ChannelID:  auditChannelID,
+		PostID:     issue.PostID,
+	})
+
 	githubClient := p.githubConnectUser(c.Ctx, c.GHInfo)
 	var resp *github.Response
 	var result *github.Issue
@@ -1811,6 +1903,7 @@ func (p *Plugin) createIssue(c *UserContext, w http.ResponseWriter, r *http.Requ
 		}
 		return nil
 	}); cErr != nil {
+		auditRec.AddErrorDesc(cErr.Error())
 		if resp != nil && resp.StatusCode == http.StatusGone {
 			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Issues are disabled on this repository.", StatusCode: http.StatusMethodNotAllowed})
 			return
@@ -1829,6 +1922,12 @@ func (p *Plugin) createIssue(c *UserContext, w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	auditRec.Success()
+	auditRec.AddEventResultState(CreateIssueAuditResult{
+		Number: result.GetNumber(),
+		URL:    result.GetHTMLURL(),
+	})
+
 	rootID := issue.PostID
 	channelID := issue.ChannelID
 	message := fmt.Sprintf("Created GitHub issue [#%v](%v)", result.GetNumber(), result.GetHTMLURL())
@@ -1882,7 +1981,22 @@ func (p *Plugin) getToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	p.writeJSON(w, info.Token)
+	token, err := p.newUserTokenSource(info).Token()
+	if err != nil {
+		p.client.Log.Error("error occurred while refreshing the github token", "UserID", userID, "error", err.Error())
+		if errors.Is(err, errTokenRefreshFailed) {
+			p.handleRevokedToken(info)
+		}
+		p.writeAPIError(w, &APIErrorResponse{Message: "failed to refresh the GitHub token", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	// The refresh token stays with the plugin, as GitHub refresh tokens can only be used once.
+	p.writeJSON(w, &oauth2.Token{
+		AccessToken: token.AccessToken,
+		TokenType:   token.TokenType,
+		Expiry:      token.Expiry,
+	})
 }
 
 // parseRepo parses the owner & repository name from the repo query parameter
@@ -613,6 +613,7 @@ func TestUpdateSettings(t *testing.T) {
 	p := getPluginTest(mockAPI, mockKvStore)
 	mockGHContext, err := GetMockUserContext(p, mockLogger)
 	assert.NoError(t, err)
+	mockAPI.On("KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
 
 	tests := []struct {
 		name               string
@@ -640,6 +641,7 @@ func TestUpdateSettings(t *testing.T) {
 				p.setConfiguration(&Configuration{
 					EncryptionKey: "dummyEncryptKey1",
 				})
+				ExpectStoredGitHubUserInfo(mockKvStore, mockGHContext.GHInfo).Times(1)
 				mockKvStore.EXPECT().Set(gomock.Any(), gomock.Any()).Return(false, errors.New("store error")).Times(1)
 				mockLogger.EXPECT().WithError(gomock.Any()).Return(mockLoggerWith).Times(1)
 				mockLoggerWith.EXPECT().Errorf("Failed to store GitHub user info").Times(1)
@@ -653,6 +655,7 @@ func TestUpdateSettings(t *testing.T) {
 			name:        "Successful Update",
 			requestBody: `{"access_token": "mockAccessToken"}`,
 			setup: func() {
+				ExpectStoredGitHubUserInfo(mockKvStore, mockGHContext.GHInfo).Times(1)
 				mockKvStore.EXPECT().Set(gomock.Any(), gomock.Any()).Return(true, nil).Times(1)
 			},
 			expectedStatusCode: http.StatusOK,
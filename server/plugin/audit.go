@@ -26,3 +26,270 @@ func (p ReEncryptUserDataAuditResult) Auditable() map[string]any {
 		"force_disconnected": p.ForceDisconnected,
 	}
 }
+
+// MigrateTokenEncryptionAuditParams holds request audit data for the migrateTokenEncryption transaction.
+type MigrateTokenEncryptionAuditParams struct {
+	LegacyTokens int `json:"legacy_tokens"`
+}
+
+func (p MigrateTokenEncryptionAuditParams) Auditable() map[string]any {
+	return map[string]any{
+		"legacy_tokens": p.LegacyTokens,
+	}
+}
+
+// MigrateTokenEncryptionAuditResult holds the outcome of the migrateTokenEncryption transaction.
+type MigrateTokenEncryptionAuditResult struct {
+	Migrated int `json:"migrated"`
+	Failed   int `json:"failed"`
+}
+
+func (p MigrateTokenEncryptionAuditResult) Auditable() map[string]any {
+	return map[string]any{
+		"migrated": p.Migrated,
+		"failed":   p.Failed,
+	}
+}
+
+// AdminDisconnectUserAuditParams holds request audit data for the adminDisconnectUser transaction.
+type AdminDisconnectUserAuditParams struct {
+	Username string `json:"username"`
+}
+
+func (p AdminDisconnectUserAuditParams) Auditable() map[string]any {
+	return map[string]any{
+		"username": p.Username,
+	}
+}
+
+// AdminDisconnectUserAuditResult holds the outcome of the adminDisconnectUser transaction.
+type AdminDisconnectUserAuditResult struct {
+	UserID         string `json:"user_id"`
+	GitHubUsername string `json:"github_username"`
+}
+
+func (p AdminDisconnectUserAuditResult) Auditable() map[string]any {
+	return map[string]any{
+		"user_id":         p.UserID,
+		"github_username": p.GitHubUsername,
+	}
+}
+
+// VerifyGitHubIdentityAuditParams holds request audit data for the verifyGitHubIdentity transaction.
+type VerifyGitHubIdentityAuditParams struct {
+	UserID         string `json:"user_id"`
+	GitHubUsername string `json:"github_username"`
+}
+
+func (p VerifyGitHubIdentityAuditParams) Auditable() map[string]any {
+	return map[string]any{
+		"user_id":         p.UserID,
+		"github_username": p.GitHubUsername,
+	}
+}
+
+// VerifyGitHubIdentityAuditResult holds the outcome of the verifyGitHubIdentity transaction.
+type VerifyGitHubIdentityAuditResult struct {
+	MatchedBy string `json:"matched_by"`
+}
+
+func (p VerifyGitHubIdentityAuditResult) Auditable() map[string]any {
+	return map[string]any{
+		"matched_by": p.MatchedBy,
+	}
+}
+
+// TakeOverGitHubAccountAuditParams holds request audit data for the takeOverGitHubAccount transaction.
+type TakeOverGitHubAccountAuditParams struct {
+	GitHubUsername string `json:"github_username"`
+	UserID         string `json:"user_id"`
+	PreviousUserID string `json:"previous_user_id"`
+}
+
+func (p TakeOverGitHubAccountAuditParams) Auditable() map[string]any {
+	return map[string]any{
+		"github_username":  p.GitHubUsername,
+		"user_id":          p.UserID,
+		"previous_user_id": p.PreviousUserID,
+	}
+}
+
+// EnforceOrganizationMembershipAuditParams holds request audit data for the enforceOrganizationMembership transaction.
+type EnforceOrganizationMembershipAuditParams struct {
+	Action         string `json:"action"`
+	ConnectedUsers int    `json:"connected_users"`
+}
+
+func (p EnforceOrganizationMembershipAuditParams) Auditable() map[string]any {
+	return map[string]any{
+		"action":          p.Action,
+		"connected_users": p.ConnectedUsers,
+	}
+}
+
+// EnforceOrganizationMembershipAuditResult holds the outcome of the enforceOrganizationMembership transaction.
+type EnforceOrganizationMembershipAuditResult struct {
+	Checked int `json:"checked"`
+	Removed int `json:"removed"`
+	Skipped int `json:"skipped"`
+}
+
+func (p EnforceOrganizationMembershipAuditResult) Auditable() map[string]any {
+	return map[string]any{
+		"checked": p.Checked,
+		"removed": p.Removed,
+		"skipped": p.Skipped,
+	}
+}
+
+// SubscribeAuditParams holds request audit data for the subscribe transaction.
+type SubscribeAuditParams struct {
+	ChannelID  string `json:"channel_id"`
+	Repository string `json:"repository"`
+	Features   string `json:"features"`
+	Flags      string `json:"flags"`
+}
+
+func (p SubscribeAuditParams) Auditable() map[string]any {
+	return map[string]any{
+		"channel_id": p.ChannelID,
+		"repository": p.Repository,
+		"features":   p.Features,
+		"flags":      p.Flags,
+	}
+}
+
+// SubscribeAuditResult holds the outcome of the subscribe transaction.
+type SubscribeAuditResult struct {
+	PrivateRepository bool `json:"private_repository"`
+}
+
+func (p SubscribeAuditResult) Auditable() map[string]any {
+	return map[string]any{
+		"private_repository": p.PrivateRepository,
+	}
+}
+
+// ChannelRepositoryAuditParams holds request audit data for the unsubscribe, setDefaultRepo and
+// unsetDefaultRepo transactions.
+type ChannelRepositoryAuditParams struct {
+	ChannelID  string `json:"channel_id"`
+	Repository string `json:"repository"`
+}
+
+func (p ChannelRepositoryAuditParams) Auditable() map[string]any {
+	return map[string]any{
+		"channel_id": p.ChannelID,
+		"repository": p.Repository,
+	}
+}
+
+// SetupAuditParams holds request audit data for the setup transaction.
+type SetupAuditParams struct {
+	Wizard string `json:"wizard"`
+}
+
+func (p SetupAuditParams) Auditable() map[string]any {
+	return map[string]any{
+		"wizard": p.Wizard,
+	}
+}
+
+// UpdateMutesAuditParams holds request audit data for the updateMutes transaction.
+type UpdateMutesAuditParams struct {
+	Action string `json:"action"`
+	Scope  string `json:"scope"`
+	Value  string `json:"value"`
+}
+
+func (p UpdateMutesAuditParams) Auditable() map[string]any {
+	return map[string]any{
+		"action": p.Action,
+		"scope":  p.Scope,
+		"value":  p.Value,
+	}
+}
+
+// CreateIssueAuditParams holds request audit data for the createIssue transaction.
+type CreateIssueAuditParams struct {
+	Repository string `json:"repository"`
+	ChannelID  string `json:"channel_id"`
+	PostID     string `json:"post_id"`
+}
+
+func (p CreateIssueAuditParams) Auditable() map[string]any {
+	return map[string]any{
+		"repository": p.Repository,
+		"channel_id": p.ChannelID,
+		"post_id":    p.PostID,
+	}
+}
+
+// CreateIssueAuditResult holds the outcome of the createIssue transaction.
+type CreateIssueAuditResult struct {
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+}
+
+func (p CreateIssueAuditResult) Auditable() map[string]any {
+	return map[string]any{
+		"number": p.Number,
+		"url":    p.URL,
+	}
+}
+
+// CreateIssueCommentAuditParams holds request audit data for the createIssueComment transaction.
+type CreateIssueCommentAuditParams struct {
+	Repository string `json:"repository"`
+	Number     int    `json:"number"`
+	PostID     string `json:"post_id"`
+}
+
+func (p CreateIssueCommentAuditParams) Auditable() map[string]any {
+	return map[string]any{
+		"repository": p.Repository,
+		"number":     p.Number,
+		"post_id":    p.PostID,
+	}
+}
+
+// CreateIssueCommentAuditResult holds the outcome of the createIssueComment transaction.
+type CreateIssueCommentAuditResult struct {
+	URL string `json:"url"`
+}
+
+func (p CreateIssueCommentAuditResult) Auditable() map[string]any {
+	return map[string]any{
+		"url": p.URL,
+	}
+}
+
+// NotificationActionAuditParams holds request audit data for the notificationAction transaction.
+type NotificationActionAuditParams struct {
+	Action     string `json:"action"`
+	Repository string `json:"repository"`
+	Number     int    `json:"number"`
+	Value      string `json:"value"`
+}
+
+func (p NotificationActionAuditParams) Auditable() map[string]any {
+	return map[string]any{
+		"action":     p.Action,
+		"repository": p.Repository,
+		"number":     p.Number,
+		"value":      p.Value,
+	}
+}
+
+// ForceDisconnectUserAuditParams holds request audit data for the forceDisconnectUser transaction.
+type ForceDisconnectUserAuditParams struct {
+	UserID         string `json:"user_id"`
+	GitHubUsername string `json:"github_username"`
+}
+
+func (p ForceDisconnectUserAuditParams) Auditable() map[string]any {
+	return map[string]any{
+		"user_id":         p.UserID,
+		"github_username": p.GitHubUsername,
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"slices"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/google/go-github/v54/github"
@@ -176,14 +177,41 @@ func (p *Plugin) handleMuteList(_ *model.CommandArgs, userInfo *GitHubUserInfo)
 		return "An error occurred getting muted users. Please try again later"
 	}
 
+	rules, err := p.getMuteRules(userInfo.UserID)
+	if err != nil {
+		p.client.Log.Error("error occurred getting mute rules.", "UserID", userInfo.UserID, "Error", err)
+		return "An error occurred getting your mute rules. Please try again later"
+	}
+
 	var mutedUsers string
 	for _, user := range mutedUsernames {
 		mutedUsers += fmt.Sprintf("- %v\n", user)
 	}
-	if len(mutedUsers) == 0 {
+
+	var mutedRules string
+	now := time.Now()
+	for _, rule := range rules {
+		if rule.isExpired(now) {
+			continue
+		}
+		mutedRules += fmt.Sprintf("- %v\n", rule.String())
+	}
+
+	if len(mutedUsers) == 0 && len(mutedRules) == 0 {
 		return "You have no muted users"
 	}
-	return "Your muted users:\n" + mutedUsers
+
+	var text string
+	if len(mutedUsers) > 0 {
+		text = "Your muted users:\n" + mutedUsers
+	}
+	if len(mutedRules) > 0 {
+		if text != "" {
+			text += "\n"
+		}
+		text += "Your muted repositories, organizations and keywords:\n" + mutedRules
+	}
+	return text
 }
 
 func (p *Plugin) isValidGitHubUsername(username string, userInfo *GitHubUserInfo) (bool, error) {
@@ -244,10 +272,21 @@ func (p *Plugin) handleMuteAdd(_ *model.CommandArgs, username string, userInfo *
 		mutedUsers = username
 	}
 
+	auditRec := plugin.MakeAuditRecord("updateMutes", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = userInfo.UserID
+	model.AddEventParameterAuditableToAuditRec(auditRec, "mute", UpdateMutesAuditParams{
+		Action: "mute",
+		Scope:  "user",
+		Value:  username,
+	})
+
 	_, err = p.store.Set(userInfo.UserID+"-muted-users", []byte(mutedUsers))
 	if err != nil {
+		auditRec.AddErrorDesc(err.Error())
 		return "Error occurred saving list of muted users"
 	}
+	auditRec.Success()
 
 	return fmt.Sprintf("`%v`", username) + " is now muted. You'll no longer receive notifications for comments in your PRs and issues."
 }
@@ -265,10 +304,21 @@ func (p *Plugin) handleUnmute(_ *model.CommandArgs, username string, userInfo *G
 		return username + " is not muted"
 	}
 
+	auditRec := plugin.MakeAuditRecord("updateMutes", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = userInfo.UserID
+	model.AddEventParameterAuditableToAuditRec(auditRec, "mute", UpdateMutesAuditParams{
+		Action: "unmute",
+		Scope:  "user",
+		Value:  username,
+	})
+
 	_, err = p.store.Set(userInfo.UserID+"-muted-users", []byte(strings.Join(newMutedList, ",")))
 	if err != nil {
+		auditRec.AddErrorDesc(err.Error())
 		return "Error occurred unmuting users"
 	}
+	auditRec.Success()
 
 	return fmt.Sprintf("`%v`", username) + " is no longer muted"
 }
@@ -284,17 +334,28 @@ func (p *Plugin) handleUnmuteAll(_ *model.CommandArgs, userInfo *GitHubUserInfo)
 		return "You have no muted users"
 	}
 
+	auditRec := plugin.MakeAuditRecord("updateMutes", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = userInfo.UserID
+	model.AddEventParameterAuditableToAuditRec(auditRec, "mute", UpdateMutesAuditParams{
+		Action: "unmuteAll",
+		Scope:  "user",
+		Value:  strings.Join(mutedUsernames, ","),
+	})
+
 	_, err = p.store.Set(userInfo.UserID+"-muted-users", []byte(""))
 	if err != nil {
+		auditRec.AddErrorDesc(err.Error())
 		return "Error occurred unmuting users"
 	}
+	auditRec.Success()
 
 	return "Unmuted all users"
 }
 
 func (p *Plugin) handleMuteCommand(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
 	if len(parameters) == 0 {
-		return "Invalid mute command. Available commands are 'list', 'add' and 'delete'."
+		return "Invalid mute command. Available commands are 'list', 'add', 'repo', 'org', 'keyword' and 'delete'."
 	}
 
 	command := parameters[0]
@@ -307,7 +368,12 @@ func (p *Plugin) handleMuteCommand(_ *plugin.Context, args *model.CommandArgs, p
 			return "Invalid number of parameters supplied to " + command
 		}
 		return p.handleMuteAdd(args, parameters[1], userInfo)
+	case muteScopeRepo, muteScopeOrg, muteScopeKeyword:
+		return p.handleMuteRuleAdd(command, parameters[1:], userInfo)
 	case "delete":
+		if len(parameters) == 3 && isValidMuteScope(parameters[1]) {
+			return p.handleMuteRuleDelete(parameters[1], parameters[2], userInfo)
+		}
 		if len(parameters) != 2 {
 			return "Invalid number of parameters supplied to " + command
 		}
@@ -414,6 +480,15 @@ func (p *Plugin) checkIfConfiguredWebhookExists(ctx context.Context, githubClien
 		return false, err
 	}
 
+	// The GitHub App installation can see the hooks even when the user can't administer them.
+	if p.getConfiguration().IsGitHubAppConfigured() {
+		if installationClient, iErr := p.getInstallationClient(ctx, owner); iErr == nil {
+			githubClient = installationClient
+		} else {
+			p.client.Log.Debug("Failed to get GitHub App installation client", "owner", owner, "error", iErr.Error())
+		}
+	}
+
 	listOrgHooks := func(opt *github.ListOptions) ([]*github.Hook, *github.Response, error) {
 		return githubClient.Organizations.ListHooks(ctx, owner, opt)
 	}
@@ -685,7 +760,17 @@ func (p *Plugin) handleUnsubscribe(_ *plugin.Context, args *model.CommandArgs, p
 
 	owner = strings.ToLower(owner)
 	repo = strings.ToLower(repo)
+
+	auditRec := plugin.MakeAuditRecord("unsubscribe", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = args.UserId
+	model.AddEventParameterAuditableToAuditRec(auditRec, "subscription", ChannelRepositoryAuditParams{
+		ChannelID:  args.ChannelId,
+		Repository: fullNameFromOwnerAndRepo(owner, repo),
+	})
+
 	if sErr := p.Unsubscribe(args.ChannelId, repo, owner); sErr != nil {
+		auditRec.AddErrorDesc(sErr.Error.Error())
 		if sErr.Code == SubscriptionNotFound {
 			return sErr.Error.Error()
 		}
@@ -693,6 +778,7 @@ func (p *Plugin) handleUnsubscribe(_ *plugin.Context, args *model.CommandArgs, p
 		p.client.Log.Warn("Failed to unsubscribe", "repo", repo, "error", sErr.Error.Error())
 		return "Encountered an error trying to unsubscribe. Please try again."
 	}
+	auditRec.Success()
 
 	baseURL := config.getBaseURL()
 	user, err := p.client.User.Get(args.UserId)
@@ -725,7 +811,7 @@ func (p *Plugin) handleUnsubscribe(_ *plugin.Context, args *model.CommandArgs, p
 }
 
 func (p *Plugin) handleDisconnect(_ *plugin.Context, args *model.CommandArgs, _ []string, _ *GitHubUserInfo) string {
-	p.disconnectGitHubAccount(args.UserId)
+	p.disconnectGitHubAccount(args.UserId, args.UserId)
 	return "Disconnected your GitHub account."
 }
 
@@ -778,6 +864,10 @@ func (p *Plugin) handleSettings(_ *plugin.Context, _ *model.CommandArgs, paramet
 	setting := parameters[0]
 	settingValue := parameters[1]
 
+	if settingValue != settingOff && p.isMembershipDowngraded(userInfo) {
+		return membershipDowngradedSettingsMessage
+	}
+
 	switch setting {
 	case settingNotifications:
 		switch settingValue {
@@ -802,6 +892,24 @@ func (p *Plugin) handleSettings(_ *plugin.Context, _ *model.CommandArgs, paramet
 		default:
 			return "Invalid value. Accepted values are: \"on\" or \"off\" or \"on-change\" ."
 		}
+	case settingStalePRs:
+		switch settingValue {
+		case settingOn:
+			userInfo.Settings.StalePRReminder = true
+		case settingOff:
+			userInfo.Settings.StalePRReminder = false
+		default:
+			return "Invalid value. Accepted values are: \"on\" or \"off\"."
+		}
+	case settingThreadReplies:
+		switch settingValue {
+		case settingOn:
+			userInfo.Settings.ThreadReplySync = true
+		case settingOff:
+			userInfo.Settings.ThreadReplySync = false
+		default:
+			return "Invalid value. Accepted values are: \"on\" or \"off\"."
+		}
 	default:
 		return "Unknown setting " + setting
 	}
@@ -816,7 +924,7 @@ func (p *Plugin) handleSettings(_ *plugin.Context, _ *model.CommandArgs, paramet
 					"error", err.Error())
 			}
 		} else {
-			err := p.store.Delete(userInfo.GitHubUsername + githubUsernameKey)
+			err := p.deleteGitHubToUserIDMapping(userInfo.GitHubUsername, userInfo.UserID)
 			if err != nil {
 				p.client.Log.Warn("Failed to delete GitHub to userID mapping",
 					"userID", userInfo.UserID,
@@ -826,7 +934,9 @@ func (p *Plugin) handleSettings(_ *plugin.Context, _ *model.CommandArgs, paramet
 		}
 	}
 
-	err := p.storeGitHubUserInfo(userInfo, p.getConfiguration().EncryptionKey)
+	err := p.updateGitHubUserInfo(userInfo.UserID, func(stored *GitHubUserInfo) {
+		stored.Settings = userInfo.Settings
+	})
 	if err != nil {
 		p.client.Log.Warn("Failed to store github user info", "error", err.Error())
 		return "Failed to store settings"
@@ -903,9 +1013,19 @@ func (p *Plugin) handleSetDefaultRepo(args *model.CommandArgs, parameters []stri
 		return fmt.Sprintf("Unknown repository %s", fullNameFromOwnerAndRepo(owner, repo))
 	}
 
+	auditRec := plugin.MakeAuditRecord("setDefaultRepo", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = userInfo.UserID
+	model.AddEventParameterAuditableToAuditRec(auditRec, "defaultRepo", ChannelRepositoryAuditParams{
+		ChannelID:  args.ChannelId,
+		Repository: fullNameFromOwnerAndRepo(owner, repo),
+	})
+
 	if _, err := p.store.Set(fmt.Sprintf(DefaultRepoKey, args.ChannelId, userInfo.UserID), fmt.Appendf(nil, "%s/%s", owner, repo)); err != nil {
+		auditRec.AddErrorDesc(err.Error())
 		return "Error occurred saving the default repo"
 	}
+	auditRec.Success()
 
 	repoLink := fmt.Sprintf("%s%s/%s", baseURL, owner, repo)
 	successMsg := fmt.Sprintf("The default repo has been set to [%s/%s](%s) for this channel", owner, repo, repoLink)
@@ -949,9 +1069,19 @@ func (p *Plugin) handleUnSetDefaultRepo(args *model.CommandArgs, userInfo *GitHu
 		return "You have not set a default repository for this channel"
 	}
 
+	auditRec := plugin.MakeAuditRecord("unsetDefaultRepo", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = userInfo.UserID
+	model.AddEventParameterAuditableToAuditRec(auditRec, "defaultRepo", ChannelRepositoryAuditParams{
+		ChannelID:  args.ChannelId,
+		Repository: defaultRepo,
+	})
+
 	if err := p.store.Delete(fmt.Sprintf(DefaultRepoKey, args.ChannelId, userInfo.UserID)); err != nil {
+		auditRec.AddErrorDesc(err.Error())
 		return "Error occurred while unsetting the repo for this channel"
 	}
+	auditRec.Success()
 
 	return "The default repository has been unset successfully"
 }
@@ -972,14 +1102,29 @@ func (p *Plugin) isOrgInLockedOrgs(configuredOrgs, owner string) bool {
 
 func (p *Plugin) handleSetup(_ *plugin.Context, args *model.CommandArgs, parameters []string) string {
 	userID := args.UserId
+
+	wizard := "setup"
+	if len(parameters) > 0 {
+		wizard = parameters[0]
+	}
+
+	auditRec := plugin.MakeAuditRecord("setup", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = userID
+	model.AddEventParameterAuditableToAuditRec(auditRec, "setup", SetupAuditParams{
+		Wizard: wizard,
+	})
+
 	isSysAdmin, err := p.isAuthorizedSysAdmin(userID)
 	if err != nil {
 		p.client.Log.Warn("Failed to check if user is System Admin", "error", err.Error())
+		auditRec.AddErrorDesc(err.Error())
 
 		return "Error checking user's permissions"
 	}
 
 	if !isSysAdmin {
+		auditRec.AddErrorDesc("user is not a System Admin")
 		return "Only System Admins are allowed to set up the plugin."
 	}
 
@@ -996,14 +1141,17 @@ func (p *Plugin) handleSetup(_ *plugin.Context, args *model.CommandArgs, paramet
 		case "announcement":
 			err = p.flowManager.StartAnnouncementWizard(userID)
 		default:
+			auditRec.AddErrorDesc("unknown subcommand")
 			return fmt.Sprintf("Unknown subcommand %v", command)
 		}
 	}
 
 	if err != nil {
+		auditRec.AddErrorDesc(err.Error())
 		return err.Error()
 	}
 
+	auditRec.Success()
 	return ""
 }
 
@@ -1048,6 +1196,14 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 		return &model.CommandResponse{}, nil
 	}
 
+	if action == "admin" {
+		message := p.handleAdmin(c, args, parameters)
+		if message != "" {
+			p.postCommandResponse(args, message)
+		}
+		return &model.CommandResponse{}, nil
+	}
+
 	if action == "help" {
 		message := p.handleHelp(c, args, parameters, nil)
 		if message != "" {
@@ -1112,7 +1268,7 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 	}
 
 	if action == "disconnect" {
-		p.disconnectGitHubAccount(args.UserId)
+		p.disconnectGitHubAccount(args.UserId, args.UserId)
 		p.postCommandResponse(args, "Disconnected your GitHub account.")
 		return &model.CommandResponse{}, nil
 	}
@@ -1220,6 +1376,10 @@ func getAutocompleteData(config *Configuration) *model.AutocompleteData {
 			Item:     "collapsed",
 			HelpText: "Notifications come in a one-line format, without enlarged fonts or advanced layouts.",
 		},
+		{
+			Item:     "live",
+			HelpText: "Each pull request gets a single post that is kept up to date, with its events posted as replies.",
+		},
 	})
 
 	subscriptionsAdd.AddNamedTextArgument("exclude", "Comma separated list of the repositories to exclude getting the notifications. Only supported for subscriptions to an organization", "", `/[^,-\s]+(,[^,-\s]+)*/`, false)
@@ -1256,14 +1416,29 @@ func getAutocompleteData(config *Configuration) *model.AutocompleteData {
 	me := model.NewAutocompleteData("me", "", "Display the connected GitHub account")
 	github.AddCommand(me)
 
-	mute := model.NewAutocompleteData("mute", "[command]", "Available commands: list, add, delete, delete-all")
+	mute := model.NewAutocompleteData("mute", "[command]", "Available commands: list, add, repo, org, keyword, delete, delete-all")
 
 	muteAdd := model.NewAutocompleteData("add", "[github username]", "Mute notifications from the provided GitHub user")
 	muteAdd.AddTextArgument("GitHub user to mute", "[username]", "")
 	mute.AddCommand(muteAdd)
 
-	muteDelete := model.NewAutocompleteData("delete", "[github username]", "Unmute notifications from the provided GitHub user")
-	muteDelete.AddTextArgument("GitHub user to unmute", "[username]", "")
+	muteRepo := model.NewAutocompleteData(muteScopeRepo, "[owner/repo] [--for duration]", "Mute notifications about the provided repository")
+	muteRepo.AddTextArgument("Owner/repo to mute", "[owner/repo]", "")
+	muteRepo.AddNamedTextArgument(flagMuteFor, "Mute only for a while, e.g. 12h, 7d or 2w", "", "", false)
+	mute.AddCommand(muteRepo)
+
+	muteOrg := model.NewAutocompleteData(muteScopeOrg, "[owner] [--for duration]", "Mute notifications about the provided organization")
+	muteOrg.AddTextArgument("Organization to mute", "[owner]", "")
+	muteOrg.AddNamedTextArgument(flagMuteFor, "Mute only for a while, e.g. 12h, 7d or 2w", "", "", false)
+	mute.AddCommand(muteOrg)
+
+	muteKeyword := model.NewAutocompleteData(muteScopeKeyword, "[keyword] [--for duration]", "Mute notifications about issues and pull requests whose title contains the keyword")
+	muteKeyword.AddTextArgument("Keyword to mute. Use double quotes for keywords with spaces", "[keyword]", "")
+	muteKeyword.AddNamedTextArgument(flagMuteFor, "Mute only for a while, e.g. 12h, 7d or 2w", "", "", false)
+	mute.AddCommand(muteKeyword)
+
+	muteDelete := model.NewAutocompleteData("delete", "[github username] or [repo|org|keyword] [value]", "Unmute notifications from the provided GitHub user, repository, organization or keyword")
+	muteDelete.AddTextArgument("GitHub user, or repo/org/keyword followed by the value to unmute", "[username]", "")
 	mute.AddCommand(muteDelete)
 
 	github.AddCommand(mute)
@@ -1301,6 +1476,28 @@ func getAutocompleteData(config *Configuration) *model.AutocompleteData {
 	remainderNotifications.AddStaticListArgument("", true, settingValue)
 	settings.AddCommand(remainderNotifications)
 
+	stalePRReminders := model.NewAutocompleteData(settingStalePRs, "", "Turn the weekly reminder about your stale pull requests on/off")
+	settingValue = []model.AutocompleteListItem{{
+		HelpText: "Turn stale pull request reminders on",
+		Item:     "on",
+	}, {
+		HelpText: "Turn stale pull request reminders off",
+		Item:     "off",
+	}}
+	stalePRReminders.AddStaticListArgument("", true, settingValue)
+	settings.AddCommand(stalePRReminders)
+
+	threadReplies := model.NewAutocompleteData(settingThreadReplies, "", "Turn posting your replies in notification threads to GitHub on/off")
+	settingValue = []model.AutocompleteListItem{{
+		HelpText: "Post your replies in notification threads to GitHub as comments",
+		Item:     "on",
+	}, {
+		HelpText: "Keep your replies in notification threads in Mattermost",
+		Item:     "off",
+	}}
+	threadReplies.AddStaticListArgument("", true, settingValue)
+	settings.AddCommand(threadReplies)
+
 	github.AddCommand(settings)
 
 	setup := model.NewAutocompleteData("setup", "[command]", "Available commands: oauth, webhook, announcement")
@@ -1310,6 +1507,15 @@ func getAutocompleteData(config *Configuration) *model.AutocompleteData {
 	setup.AddCommand(model.NewAutocompleteData("announcement", "", "Announce to your team that they can use GitHub integration"))
 	github.AddCommand(setup)
 
+	admin := model.NewAutocompleteData("admin", "[command]", "Available commands: users, disconnect, mappings")
+	admin.RoleID = model.SystemAdminRoleId
+	admin.AddCommand(model.NewAutocompleteData("users", "", "List the users connected to GitHub"))
+	adminDisconnect := model.NewAutocompleteData("disconnect", "[@username]", "Disconnect a user from their GitHub account")
+	adminDisconnect.AddTextArgument("Mattermost user to disconnect", "[@username]", "")
+	admin.AddCommand(adminDisconnect)
+	admin.AddCommand(model.NewAutocompleteData("mappings", "", "Report GitHub accounts mapped inconsistently to users"))
+	github.AddCommand(admin)
+
 	help := model.NewAutocompleteData("help", "", "Display Slash Command help text")
 	github.AddCommand(help)
 
@@ -415,6 +415,7 @@ func TestHandleMuteList(t *testing.T) {
 					*value = []byte("")
 					return nil
 				}).Times(1)
+				mockKvStore.EXPECT().Get("mockUserID-mute-rules", gomock.Any()).Return(nil).Times(1)
 			},
 			assertions: func(t *testing.T, result string) {
 				assert.Equal(t, "You have no muted users", result)
@@ -428,12 +429,33 @@ func TestHandleMuteList(t *testing.T) {
 					*value = mutedUsernames
 					return nil
 				}).Times(1)
+				mockKvStore.EXPECT().Get("mockUserID-mute-rules", gomock.Any()).Return(nil).Times(1)
 			},
 			assertions: func(t *testing.T, result string) {
 				expectedOutput := "Your muted users:\n- user1\n- user2\n- user3\n"
 				assert.Equal(t, expectedOutput, result)
 			},
 		},
+		{
+			name: "Successfully formats muted users and active mute rules",
+			setup: func() {
+				mockKvStore.EXPECT().Get("mockUserID-muted-users", gomock.Any()).DoAndReturn(func(key string, value *[]byte) error {
+					*value = []byte("user1")
+					return nil
+				}).Times(1)
+				mockKvStore.EXPECT().Get("mockUserID-mute-rules", gomock.Any()).DoAndReturn(func(key string, value *[]MuteRule) error {
+					*value = []MuteRule{
+						{Scope: muteScopeRepo, Value: "mattermost/docs"},
+						{Scope: muteScopeKeyword, Value: "flaky", ExpiresAt: 1},
+					}
+					return nil
+				}).Times(1)
+			},
+			assertions: func(t *testing.T, result string) {
+				expectedOutput := "Your muted users:\n- user1\n\nYour muted repositories, organizations and keywords:\n- repo `mattermost/docs`\n"
+				assert.Equal(t, expectedOutput, result)
+			},
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -579,6 +601,10 @@ func TestHandleUnmute(t *testing.T) {
 					return nil
 				}).Times(1)
 				mockKvStore.EXPECT().Set(userInfo.UserID+"-muted-users", gomock.Any()).Return(false, errors.New("error saving muted users")).Times(1)
+				mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+					return rec.EventName == "updateMutes" && rec.Status == model.AuditStatusFail &&
+						rec.EventData.Parameters["mute"].(map[string]any)["action"] == "unmute"
+				})).Return().Once()
 			},
 			expectedResult: "Error occurred unmuting users",
 		},
@@ -592,6 +618,10 @@ func TestHandleUnmute(t *testing.T) {
 					return nil
 				}).Times(1)
 				mockKvStore.EXPECT().Set(userInfo.UserID+"-muted-users", gomock.Any()).Return(true, nil).Times(1)
+				mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+					return rec.EventName == "updateMutes" && rec.Status == model.AuditStatusSuccess &&
+						rec.EventData.Parameters["mute"].(map[string]any)["action"] == "unmute"
+				})).Return().Once()
 			},
 			expectedResult: "`user1` is no longer muted",
 		},
@@ -637,6 +667,10 @@ func TestHandleUnmuteAll(t *testing.T) {
 					}).Times(1)
 
 				mockKvStore.EXPECT().Set(userInfo.UserID+"-muted-users", []byte("")).Return(false, errors.New("error saving muted users")).Times(1)
+				mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+					return rec.EventName == "updateMutes" && rec.Status == model.AuditStatusFail &&
+						rec.EventData.Parameters["mute"].(map[string]any)["action"] == "unmuteAll"
+				})).Return().Once()
 			},
 			assertions: func(expectedResult string) {
 				assert.Equal(t, "Error occurred unmuting users", expectedResult)
@@ -652,6 +686,10 @@ func TestHandleUnmuteAll(t *testing.T) {
 						return nil
 					}).Times(1)
 				mockKvStore.EXPECT().Set(userInfo.UserID+"-muted-users", []byte("")).Return(true, nil).Times(1)
+				mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+					return rec.EventName == "updateMutes" && rec.Status == model.AuditStatusSuccess &&
+						rec.EventData.Parameters["mute"].(map[string]any)["action"] == "unmuteAll"
+				})).Return().Once()
 			},
 			assertions: func(expectedResult string) {
 				assert.Equal(t, expectedResult, "Unmuted all users")
@@ -688,6 +726,7 @@ func TestHandleMuteCommand(t *testing.T) {
 					*value = mutedUsernames
 					return nil
 				}).Times(1)
+				mockKvStore.EXPECT().Get("mockUserID-mute-rules", gomock.Any()).Return(nil).Times(1)
 			},
 			assertions: func(t *testing.T, response string) {
 				assert.Equal(t, "Your muted users:\n- user1\n- user2\n- user3\n", response)
@@ -726,6 +765,10 @@ func TestHandleMuteCommand(t *testing.T) {
 					return nil
 				}).Times(1)
 				mockKvStore.EXPECT().Set(userInfo.UserID+"-muted-users", gomock.Any()).Return(true, nil).Times(1)
+				mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+					return rec.EventName == "updateMutes" && rec.Status == model.AuditStatusSuccess &&
+						rec.EventData.Parameters["mute"].(map[string]any)["action"] == "unmute"
+				})).Return().Once()
 			},
 			assertions: func(t *testing.T, response string) {
 				assert.Equal(t, "`user1` is no longer muted", response)
@@ -750,6 +793,10 @@ func TestHandleMuteCommand(t *testing.T) {
 						return nil
 					}).Times(1)
 				mockKvStore.EXPECT().Set(userInfo.UserID+"-muted-users", []byte("")).Return(true, nil).Times(1)
+				mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+					return rec.EventName == "updateMutes" && rec.Status == model.AuditStatusSuccess &&
+						rec.EventData.Parameters["mute"].(map[string]any)["action"] == "unmuteAll"
+				})).Return().Once()
 			},
 			assertions: func(t *testing.T, response string) {
 				assert.Equal(t, "Unmuted all users", response)
@@ -768,7 +815,50 @@ func TestHandleMuteCommand(t *testing.T) {
 			parameters: []string{},
 			setup:      func() {},
 			assertions: func(t *testing.T, response string) {
-				assert.Equal(t, "Invalid mute command. Available commands are 'list', 'add' and 'delete'.", response)
+				assert.Equal(t, "Invalid mute command. Available commands are 'list', 'add', 'repo', 'org', 'keyword' and 'delete'.", response)
+			},
+		},
+		{
+			name:       "Success - mute repository",
+			parameters: []string{"repo", "mattermost/docs"},
+			setup: func() {
+				mockKvStore.EXPECT().Get("mockUserID-mute-rules", gomock.Any()).Return(nil).Times(1)
+				mockKvStore.EXPECT().Set("mockUserID-mute-rules", []MuteRule{{Scope: muteScopeRepo, Value: "mattermost/docs"}}).Return(true, nil).Times(1)
+				mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+					return rec.EventName == "updateMutes" && rec.Status == model.AuditStatusSuccess &&
+						rec.EventData.Parameters["mute"].(map[string]any)["action"] == "mute" &&
+						rec.EventData.Parameters["mute"].(map[string]any)["scope"] == muteScopeRepo
+				})).Return().Once()
+			},
+			assertions: func(t *testing.T, response string) {
+				assert.Equal(t, "Muted repo `mattermost/docs`. You'll no longer receive notifications about it.", response)
+			},
+		},
+		{
+			name:       "Error - invalid mute duration",
+			parameters: []string{"org", "mattermost", "--for", "soon"},
+			setup:      func() {},
+			assertions: func(t *testing.T, response string) {
+				assert.Equal(t, "Invalid duration. Use a number followed by `m`, `h`, `d` or `w`, for example `--for 7d`.", response)
+			},
+		},
+		{
+			name:       "Success - delete muted keyword",
+			parameters: []string{"delete", "keyword", "flaky"},
+			setup: func() {
+				mockKvStore.EXPECT().Get("mockUserID-mute-rules", gomock.Any()).DoAndReturn(func(key string, value *[]MuteRule) error {
+					*value = []MuteRule{{Scope: muteScopeKeyword, Value: "Flaky"}}
+					return nil
+				}).Times(1)
+				mockKvStore.EXPECT().Delete("mockUserID-mute-rules").Return(nil).Times(1)
+				mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+					return rec.EventName == "updateMutes" && rec.Status == model.AuditStatusSuccess &&
+						rec.EventData.Parameters["mute"].(map[string]any)["action"] == "unmute" &&
+						rec.EventData.Parameters["mute"].(map[string]any)["scope"] == muteScopeKeyword
+				})).Return().Once()
+			},
+			assertions: func(t *testing.T, response string) {
+				assert.Equal(t, "keyword `flaky` is no longer muted", response)
 			},
 		},
 	}
@@ -1133,6 +1223,11 @@ func TestHandleUnsubscribe(t *testing.T) {
 			setup: func() {
 				mockKVStore.EXPECT().Get(SubscriptionsKey, gomock.Any()).Return(errors.New("error occurred getting subscriptions"))
 				mockAPI.On("LogWarn", "Failed to unsubscribe", "repo", "repo", "error", "could not get subscriptions: could not get subscriptions from KVStore: error occurred getting subscriptions")
+				mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+					return rec.EventName == "unsubscribe" && rec.Status == model.AuditStatusFail &&
+						rec.Actor.UserId == MockUserID &&
+						rec.EventData.Parameters["subscription"].(map[string]any)["repository"] == "owner/repo"
+				})).Return().Once()
 			},
 			assertions: func(result string) {
 				assert.Equal(t, "Encountered an error trying to unsubscribe. Please try again.", result)
@@ -1149,6 +1244,9 @@ func TestHandleUnsubscribe(t *testing.T) {
 				mockAPI.On("GetUser", MockUserID).Return(nil, &model.AppError{Message: "error getting user"}).Times(1)
 				mockAPI.On("LogWarn", "Error while fetching user details", "error", "error getting user").Times(1)
 				mockKVStore.EXPECT().SetAtomicWithRetries(SubscriptionsKey, gomock.Any()).Return(nil).Times(1)
+				mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+					return rec.EventName == "unsubscribe" && rec.Status == model.AuditStatusFail
+				})).Return().Once()
 			},
 			assertions: func(result string) {
 				assert.Equal(t, "no subscription exists for `owner/repo` in the channel", result)
@@ -1255,6 +1353,9 @@ func TestHandleUnsubscribe(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			mockAPI.ExpectedCalls = nil
 			tc.setup()
+			mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+				return rec.EventName == "unsubscribe" && rec.Status == model.AuditStatusSuccess
+			})).Return().Maybe()
 
 			args := &model.CommandArgs{
 				UserId:    MockUserID,
@@ -1310,6 +1411,7 @@ func TestHandleSettings(t *testing.T) {
 			},
 			setup: func() {
 				mockKvStore.EXPECT().Set(userInfo.GitHubUsername+githubUsernameKey, gomock.Any()).Return(true, nil).Times(1)
+				ExpectStoredGitHubUserInfo(mockKvStore, userInfo).Times(1)
 				mockKvStore.EXPECT().Set(userInfo.UserID+githubTokenKey, gomock.Any()).Return(true, nil).Times(1)
 			},
 			assertions: func(result string) {
@@ -1325,6 +1427,7 @@ func TestHandleSettings(t *testing.T) {
 			setup: func() {
 				mockKvStore.EXPECT().Set(userInfo.GitHubUsername+githubUsernameKey, gomock.Any()).Return(false, errors.New("error setting notification")).Times(1)
 				mockAPI.On("LogWarn", "Failed to store GitHub to userID mapping", "userID", "mockUserID", "GitHub username", "mockUsername", "error", "encountered error saving github username mapping: error setting notification").Times(1)
+				ExpectStoredGitHubUserInfo(mockKvStore, userInfo).Times(1)
 				mockKvStore.EXPECT().Set(userInfo.UserID+githubTokenKey, gomock.Any()).Return(true, nil).Times(1)
 			},
 			assertions: func(result string) {
@@ -1339,7 +1442,9 @@ func TestHandleSettings(t *testing.T) {
 			},
 			setup: func() {
 				mockKvStore.EXPECT().Set(userInfo.GitHubUsername+githubUsernameKey, gomock.Any()).Return(true, nil).Times(1)
+				ExpectStoredGitHubUserInfo(mockKvStore, userInfo).Times(1)
 				mockKvStore.EXPECT().Set(userInfo.UserID+githubTokenKey, gomock.Any()).Return(true, nil).Times(1)
+				expectUserIDMapping(mockKvStore, userInfo.GitHubUsername, userInfo.UserID)
 				mockKvStore.EXPECT().Delete(userInfo.GitHubUsername + githubUsernameKey).Return(nil).Times(1)
 			},
 			assertions: func(result string) {
@@ -1354,9 +1459,11 @@ func TestHandleSettings(t *testing.T) {
 			},
 			setup: func() {
 				mockKvStore.EXPECT().Set(userInfo.GitHubUsername+githubUsernameKey, gomock.Any()).Return(true, nil).Times(1)
+				ExpectStoredGitHubUserInfo(mockKvStore, userInfo).Times(1)
 				mockKvStore.EXPECT().Set(userInfo.UserID+githubTokenKey, gomock.Any()).Return(true, nil).Times(1)
+				expectUserIDMapping(mockKvStore, userInfo.GitHubUsername, userInfo.UserID)
 				mockKvStore.EXPECT().Delete(userInfo.GitHubUsername + githubUsernameKey).Return(errors.New("error setting notification")).Times(1)
-				mockAPI.On("LogWarn", "Failed to delete GitHub to userID mapping", "userID", "mockUserID", "GitHub username", "mockUsername", "error", "error setting notification").Times(1)
+				mockAPI.On("LogWarn", "Failed to delete GitHub to userID mapping", "userID", "mockUserID", "GitHub username", "mockUsername", "error", "encountered error deleting github username mapping: error setting notification").Times(1)
 			},
 			assertions: func(result string) {
 				assert.Equal(t, result, "Settings updated.")
@@ -1369,6 +1476,7 @@ func TestHandleSettings(t *testing.T) {
 				settingReminders, settingOn,
 			},
 			setup: func() {
+				ExpectStoredGitHubUserInfo(mockKvStore, userInfo).Times(1)
 				mockKvStore.EXPECT().Set(userInfo.UserID+githubTokenKey, gomock.Any()).Return(true, nil).Times(1)
 			},
 			assertions: func(result string) {
@@ -1382,6 +1490,7 @@ func TestHandleSettings(t *testing.T) {
 				settingReminders, settingOff,
 			},
 			setup: func() {
+				ExpectStoredGitHubUserInfo(mockKvStore, userInfo).Times(1)
 				mockKvStore.EXPECT().Set(userInfo.UserID+githubTokenKey, gomock.Any()).Return(true, nil).Times(1)
 			},
 			assertions: func(result string) {
@@ -1395,6 +1504,7 @@ func TestHandleSettings(t *testing.T) {
 				settingReminders, settingOnChange,
 			},
 			setup: func() {
+				ExpectStoredGitHubUserInfo(mockKvStore, userInfo).Times(1)
 				mockKvStore.EXPECT().Set(userInfo.UserID+githubTokenKey, gomock.Any()).Return(true, nil).Times(1)
 			},
 			assertions: func(result string) {
@@ -1413,6 +1523,32 @@ func TestHandleSettings(t *testing.T) {
 			},
 			expectedResult: "Invalid value. Accepted values are: \"on\" or \"off\" or \"on-change\" .",
 		},
+		{
+			name: "Successfully set stale pull request reminders to on",
+			parameters: []string{
+				settingStalePRs, settingOn,
+			},
+			setup: func() {
+				ExpectStoredGitHubUserInfo(mockKvStore, userInfo).Times(1)
+				mockKvStore.EXPECT().Set(userInfo.UserID+githubTokenKey, gomock.Any()).Return(true, nil).Times(1)
+			},
+			assertions: func(result string) {
+				assert.Equal(t, result, "Settings updated.")
+				assert.True(t, userInfo.Settings.StalePRReminder)
+			},
+			expectedResult: "Settings updated.",
+		},
+		{
+			name: "Invalid setting value for stale pull request reminders",
+			parameters: []string{
+				settingStalePRs, settingOnChange,
+			},
+			setup: func() {},
+			assertions: func(result string) {
+				assert.Equal(t, result, "Invalid value. Accepted values are: \"on\" or \"off\".")
+			},
+			expectedResult: "Invalid value. Accepted values are: \"on\" or \"off\".",
+		},
 		{
 			name: "Unknown setting",
 			parameters: []string{
@@ -1430,6 +1566,7 @@ func TestHandleSettings(t *testing.T) {
 				settingReminders, settingOnChange,
 			},
 			setup: func() {
+				ExpectStoredGitHubUserInfo(mockKvStore, userInfo).Times(1)
 				mockKvStore.EXPECT().Set(userInfo.UserID+githubTokenKey, gomock.Any()).Return(false, errors.New("error storing user info")).Times(1)
 				mockAPI.On("LogWarn", "Failed to store github user info", "error", "error occurred while trying to store user info into KV store: error storing user info").Times(1)
 			},
@@ -1442,6 +1579,7 @@ func TestHandleSettings(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mockAPI.ExpectedCalls = nil
+			mockAPI.On("KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
 			tc.setup()
 
 			result := p.handleSettings(nil, nil, tc.parameters, userInfo)
@@ -1820,6 +1958,7 @@ func TestCheckIfConfiguredWebhookExists(t *testing.T) {
 		userInfo, err := GetMockGHUserInfo(p)
 		require.NoError(t, err)
 		configureSiteURL(mockAPI)
+		mockKvStore.EXPECT().Set(MockUserID+githubLastAPICallKey, gomock.Any()).Return(true, nil)
 
 		var orgHooksCalls int32
 		mux := http.NewServeMux()
@@ -1848,6 +1987,7 @@ func TestCheckIfConfiguredWebhookExists(t *testing.T) {
 		userInfo, err := GetMockGHUserInfo(p)
 		require.NoError(t, err)
 		configureSiteURL(mockAPI)
+		mockKvStore.EXPECT().Set(MockUserID+githubLastAPICallKey, gomock.Any()).Return(true, nil)
 
 		mux := http.NewServeMux()
 		mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/hooks", owner, repo), func(w http.ResponseWriter, _ *http.Request) {
@@ -1867,6 +2007,7 @@ func TestCheckIfConfiguredWebhookExists(t *testing.T) {
 		userInfo, err := GetMockGHUserInfo(p)
 		require.NoError(t, err)
 		configureSiteURL(mockAPI)
+		mockKvStore.EXPECT().Set(MockUserID+githubLastAPICallKey, gomock.Any()).Return(true, nil)
 
 		mux := http.NewServeMux()
 		mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/hooks", owner, repo), func(w http.ResponseWriter, _ *http.Request) {
@@ -1892,6 +2033,7 @@ func TestCheckIfConfiguredWebhookExists(t *testing.T) {
 		userInfo, err := GetMockGHUserInfo(p)
 		require.NoError(t, err)
 		configureSiteURL(mockAPI)
+		mockKvStore.EXPECT().Set(MockUserID+githubLastAPICallKey, gomock.Any()).Return(true, nil)
 		mockAPI.On("LogWarn", "Not able to get the list of webhooks", "Owner", owner, "Repo", repo, "error", mock.Anything).Maybe()
 		mockAPI.On("LogWarn", "Error occurred while using the Github client", "error", mock.Anything).Maybe()
 
@@ -1944,3 +2086,44 @@ func TestToSlice(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleSetup(t *testing.T) {
+	tests := map[string]struct {
+		parameters  []string
+		roles       string
+		expectedMsg string
+		expectedErr string
+	}{
+		"user is not a System Admin": {
+			parameters:  []string{"oauth"},
+			roles:       model.SystemUserRoleId,
+			expectedMsg: "Only System Admins are allowed to set up the plugin.",
+			expectedErr: "user is not a System Admin",
+		},
+		"unknown subcommand": {
+			parameters:  []string{"unknown"},
+			roles:       model.SystemAdminRoleId,
+			expectedMsg: "Unknown subcommand unknown",
+			expectedErr: "unknown subcommand",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+			p := getPluginTest(mockAPI, mockKvStore)
+			mockAPI.On("GetUser", MockUserID).Return(&model.User{Id: MockUserID, Roles: tc.roles}, nil)
+			mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+				return rec.EventName == "setup" &&
+					rec.Status == model.AuditStatusFail &&
+					rec.Actor.UserId == MockUserID &&
+					rec.Error.Description == tc.expectedErr &&
+					rec.EventData.Parameters["setup"].(map[string]any)["wizard"] == tc.parameters[0]
+			})).Return().Once()
+
+			msg := p.handleSetup(nil, &model.CommandArgs{UserId: MockUserID}, tc.parameters)
+
+			assert.Equal(t, tc.expectedMsg, msg)
+			mockAPI.AssertExpectations(t)
+		})
+	}
+}
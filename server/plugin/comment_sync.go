@@ -0,0 +1,182 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v54/github"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"golang.org/x/oauth2"
+)
+
+// settingThreadReplies is the user setting opting in to posting thread replies to GitHub.
+const settingThreadReplies = "thread-replies"
+
+// mattermostMentionRegex matches @mentions in a Mattermost message, which GitHub would take for
+// mentions of its own users.
+var mattermostMentionRegex = regexp.MustCompile(`\B@([a-zA-Z0-9][a-zA-Z0-9._-]*)`)
+
+// syncedCommentMarkerRegex matches the hidden marker appended to GitHub comments posted from a
+// Mattermost thread. It records the channel of the thread, so the webhook for the comment isn't
+// posted back to that channel.
+var syncedCommentMarkerRegex = regexp.MustCompile(`\s*<!-- mattermost-thread-reply channel=([a-z0-9]{26}) -->`)
+
+func getSyncedCommentMarker(channelID string) string {
+	return fmt.Sprintf("<!-- mattermost-thread-reply channel=%s -->", channelID)
+}
+
+// getSyncedCommentChannelID returns the channel a GitHub comment was replied from, or an empty
+// string when it wasn't posted from Mattermost.
+func getSyncedCommentChannelID(body string) string {
+	m := syncedCommentMarkerRegex.FindStringSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// commentSyncTarget is the GitHub issue, pull request or review comment a thread reply is posted to.
+type commentSyncTarget struct {
+	owner      string
+	repo       string
+	objectType string
+	number     int   // the issue or pull request number
+	commentID  int64 // the review comment replied to
+}
+
+// getCommentSyncTarget returns where replies to the notification post go on GitHub.
+func (p *Plugin) getCommentSyncTarget(root *model.Post) (commentSyncTarget, bool) {
+	owner, repo, id, objectType, ok := p.getGitHubPostProps(root)
+	if !ok {
+		return commentSyncTarget{}, false
+	}
+
+	target := commentSyncTarget{owner: owner, repo: repo, objectType: objectType}
+	switch objectType {
+	case githubObjectTypeIssue:
+		target.number = int(id)
+	case githubObjectTypeIssueComment, githubObjectTypePRReviewComment:
+		// Posts created before replies were synced don't record the issue number.
+		number, ok := root.GetProp(postPropGithubIssueNumber).(float64)
+		if !ok || number == 0 {
+			return commentSyncTarget{}, false
+		}
+		target.number = int(number)
+		target.commentID = int64(id)
+	default:
+		return commentSyncTarget{}, false
+	}
+
+	return target, true
+}
+
+// convertMentionsForGitHub replaces the @mentions of Mattermost users connected to GitHub with
+// their GitHub login, and escapes the other mentions so they don't notify whoever has the same
+// login on GitHub.
+func (p *Plugin) convertMentionsForGitHub(message string) string {
+	converted := map[string]string{}
+	return mattermostMentionRegex.ReplaceAllStringFunc(message, func(mention string) string {
+		// A mention ending a sentence keeps its period.
+		name := strings.TrimRight(mention[1:], ".")
+		suffix := mention[1+len(name):]
+
+		replacement, ok := converted[name]
+		if !ok {
+			replacement = "`@" + name + "`"
+			if user, err := p.client.User.GetByUsername(strings.ToLower(name)); err == nil {
+				if username, err := p.getUsername(user.Id); err == nil {
+					replacement = username
+				}
+			}
+			converted[name] = replacement
+		}
+
+		return replacement + suffix
+	})
+}
+
+// MessageHasBeenPosted posts replies in threads of issue, pull request and comment notifications
+// back to GitHub on behalf of the connected replier, when they opted in with
+// `/github settings thread-replies on`.
+func (p *Plugin) MessageHasBeenPosted(c *plugin.Context, post *model.Post) {
+	if !p.getConfiguration().EnableThreadReplySync {
+		return
+	}
+
+	if post.RootId == "" || post.UserId == p.BotUserID || post.IsSystemMessage() || post.Message == "" {
+		return
+	}
+	for _, prop := range []string{model.PostPropsFromPlugin, model.PostPropsFromWebhook, model.PostPropsFromBot, model.PostPropsFromOAuthApp} {
+		if post.GetProp(prop) != nil {
+			return
+		}
+	}
+
+	root, err := p.client.Post.GetPost(post.RootId)
+	if err != nil {
+		p.client.Log.Debug("Error fetching root post of thread reply", "error", err.Error())
+		return
+	}
+
+	target, ok := p.getCommentSyncTarget(root)
+	if !ok {
+		return
+	}
+
+	info, apiErr := p.getGitHubUserInfo(post.UserId)
+	if apiErr != nil {
+		if apiErr.ID != apiErrorIDNotConnected {
+			p.client.Log.Debug("Error in getting user info", "error", apiErr.Error())
+		}
+		return
+	}
+	if info.Settings == nil || !info.Settings.ThreadReplySync {
+		return
+	}
+
+	auditRec := plugin.MakeAuditRecord("createIssueComment", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = post.UserId
+	model.AddEventParameterAuditableToAuditRec(auditRec, "comment", CreateIssueCommentAuditParams{
+		Repository: fullNameFromOwnerAndRepo(target.owner, target.repo),
+		Number:     target.number,
+		PostID:     post.Id,
+	})
+
+	body := p.convertMentionsForGitHub(post.Message) + "\n\n" + getSyncedCommentMarker(post.ChannelId)
+	if err := p.postThreadReplyToGitHub(info, target, body); err != nil {
+		auditRec.AddErrorDesc(err.Error())
+		p.client.Log.Warn("Failed to post thread reply to GitHub", "userID", post.UserId, "repo", target.owner+"/"+target.repo, "error", err.Error())
+		p.client.Post.SendEphemeralPost(post.UserId, &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: post.ChannelId,
+			RootId:    post.RootId,
+			Message:   "Your reply couldn't be posted to GitHub.",
+		})
+		return
+	}
+
+	auditRec.Success()
+}
+
+func (p *Plugin) postThreadReplyToGitHub(info *GitHubUserInfo, target commentSyncTarget, body string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	githubClient := p.githubConnectUser(ctx, info)
+	return p.useGitHubClient(info, func(info *GitHubUserInfo, token *oauth2.Token) error {
+		var err error
+		if target.objectType == githubObjectTypePRReviewComment {
+			_, _, err = githubClient.PullRequests.CreateCommentInReplyTo(ctx, target.owner, target.repo, target.number, body, target.commentID)
+		} else {
+			_, _, err = githubClient.Issues.CreateComment(ctx, target.owner, target.repo, target.number, &github.IssueComment{Body: &body})
+		}
+		return err
+	})
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSyncedCommentChannelID(t *testing.T) {
+	channelID := model.NewId()
+
+	assert.Equal(t, channelID, getSyncedCommentChannelID("LGTM\n\n"+getSyncedCommentMarker(channelID)))
+	assert.Equal(t, "", getSyncedCommentChannelID("LGTM <!-- some other comment -->"))
+}
+
+func TestGetCommentSyncTarget(t *testing.T) {
+	p := NewPlugin()
+	p.BotUserID = "bot_user_id"
+
+	makePost := func(userID, objectType string, issueNumber float64) *model.Post {
+		post := &model.Post{UserId: userID}
+		post.AddProp(postPropGithubRepo, "mattermost/mattermost-plugin-github")
+		post.AddProp(postPropGithubObjectID, float64(42))
+		post.AddProp(postPropGithubObjectType, objectType)
+		if issueNumber != 0 {
+			post.AddProp(postPropGithubIssueNumber, issueNumber)
+		}
+		return post
+	}
+
+	tcs := []struct {
+		name     string
+		post     *model.Post
+		expected commentSyncTarget
+		ok       bool
+	}{
+		{
+			name:     "issue notification",
+			post:     makePost("bot_user_id", githubObjectTypeIssue, 0),
+			expected: commentSyncTarget{owner: "mattermost", repo: "mattermost-plugin-github", objectType: githubObjectTypeIssue, number: 42},
+			ok:       true,
+		},
+		{
+			name:     "issue comment notification",
+			post:     makePost("bot_user_id", githubObjectTypeIssueComment, 7),
+			expected: commentSyncTarget{owner: "mattermost", repo: "mattermost-plugin-github", objectType: githubObjectTypeIssueComment, number: 7, commentID: 42},
+			ok:       true,
+		},
+		{
+			name:     "review comment notification",
+			post:     makePost("bot_user_id", githubObjectTypePRReviewComment, 7),
+			expected: commentSyncTarget{owner: "mattermost", repo: "mattermost-plugin-github", objectType: githubObjectTypePRReviewComment, number: 7, commentID: 42},
+			ok:       true,
+		},
+		{
+			name: "comment notification without issue number",
+			post: makePost("bot_user_id", githubObjectTypeIssueComment, 0),
+		},
+		{
+			name: "discussion comment notification",
+			post: makePost("bot_user_id", githubObjectTypeDiscussionComment, 7),
+		},
+		{
+			name: "post not by the bot",
+			post: makePost("other_user_id", githubObjectTypeIssue, 0),
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			target, ok := p.getCommentSyncTarget(tc.post)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, target)
+		})
+	}
+}
+
+func TestMessageHasBeenPostedWithoutSettings(t *testing.T) {
+	mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKvStore)
+	info, err := GetMockGHUserInfo(p)
+	require.NoError(t, err)
+	info.Settings = nil
+	p.setConfiguration(&Configuration{EncryptionKey: "dummyEncryptKey1", EnableThreadReplySync: true})
+
+	root := &model.Post{Id: "root_post_id", UserId: MockBotID}
+	root.AddProp(postPropGithubRepo, "mattermost/mattermost-plugin-github")
+	root.AddProp(postPropGithubObjectID, float64(42))
+	root.AddProp(postPropGithubObjectType, githubObjectTypeIssue)
+	mockAPI.On("GetPost", root.Id).Return(root, nil)
+	ExpectStoredGitHubUserInfo(mockKvStore, info)
+
+	// Users connected before settings were stored don't have replies synced, nor make it panic.
+	p.MessageHasBeenPosted(nil, &model.Post{UserId: MockUserID, RootId: root.Id, Message: "LGTM"})
+}
+
+func TestConvertMentionsForGitHub(t *testing.T) {
+	mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKvStore)
+	info, err := GetMockGHUserInfo(p)
+	require.NoError(t, err)
+
+	mockAPI.On("GetUserByUsername", "alice").Return(&model.User{Id: MockUserID, Username: "alice"}, nil)
+	ExpectStoredGitHubUserInfo(mockKvStore, info).Times(1)
+	mockAPI.On("GetUserByUsername", "bob").Return(&model.User{Id: "bobID", Username: "bob"}, nil)
+	mockKvStore.EXPECT().Get("bobID"+githubTokenKey, gomock.Any()).Return(nil).Times(1)
+	mockAPI.On("GetUser", "bobID").Return(&model.User{Id: "bobID", Username: "bob"}, nil)
+	mockAPI.On("GetUserByUsername", "channel").Return(nil, &model.AppError{Message: "not found"})
+
+	assert.Equal(t,
+		"@"+MockUsername+" and `@bob`, please have a look. `@channel`, thanks @"+MockUsername+". Mail me at me@example.com",
+		p.convertMentionsForGitHub("@alice and @bob, please have a look. @channel, thanks @alice. Mail me at me@example.com"),
+	)
+}
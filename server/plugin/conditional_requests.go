@@ -0,0 +1,140 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// conditionalRequestCacheMaxBytes is the total size of the responses kept for conditional requests.
+	conditionalRequestCacheMaxBytes = 32 << 20
+	// conditionalRequestMaxBodySize is the largest response body kept for conditional requests.
+	conditionalRequestMaxBodySize = 1 << 20
+)
+
+// cachedResponse is a GitHub response kept to answer a later request GitHub reports as not modified.
+type cachedResponse struct {
+	ETag         string
+	LastModified string
+	Header       http.Header
+	Body         []byte
+}
+
+// size returns the approximate number of bytes the response takes up in the cache.
+func (r *cachedResponse) size() int {
+	size := len(r.ETag) + len(r.LastModified) + len(r.Body)
+	for name, values := range r.Header {
+		size += len(name)
+		for _, value := range values {
+			size += len(value)
+		}
+	}
+	return size
+}
+
+// conditionalRequestTransport sends the ETag and Last-Modified of the previous response to the
+// same GET request, and serves the previous response when GitHub replies 304 Not Modified, which
+// doesn't count against the rate limit. Responses are kept per token, so users never share them.
+// It sits below the oauth2 transport so it sees the token of each request.
+type conditionalRequestTransport struct {
+	base  http.RoundTripper
+	cache *lruCache
+}
+
+func conditionalRequestCacheKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Header.Get("Authorization")))
+	return strings.Join([]string{hex.EncodeToString(sum[:]), req.Header.Get("Accept"), req.URL.String()}, " ")
+}
+
+func (t *conditionalRequestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Authorization") == "" {
+		return t.base.RoundTrip(req)
+	}
+
+	key := conditionalRequestCacheKey(req)
+	var cached *cachedResponse
+	if value, ok := t.cache.get(key); ok {
+		cached = value.(*cachedResponse)
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return makeCachedResponse(req, resp, cached), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return resp, nil
+	}
+	if resp.ContentLength > conditionalRequestMaxBodySize {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, conditionalRequestMaxBodySize+1))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if len(body) > conditionalRequestMaxBodySize {
+		return resp, nil
+	}
+
+	cached = &cachedResponse{
+		ETag:         etag,
+		LastModified: lastModified,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+	}
+	t.cache.addWithSize(key, cached, cached.size()+len(key))
+
+	return resp, nil
+}
+
+// makeCachedResponse answers req with the cached response, keeping the rate limit headers of
+// the 304 response so callers see the current budget.
+func makeCachedResponse(req *http.Request, notModified *http.Response, cached *cachedResponse) *http.Response {
+	_, _ = io.Copy(io.Discard, notModified.Body)
+	notModified.Body.Close()
+
+	header := cached.Header.Clone()
+	for _, name := range []string{headerRateLimitLimit, headerRateLimitRemaining, headerRateLimitReset, headerRateLimitResource} {
+		if value := notModified.Header.Get(name); value != "" {
+			header.Set(name, value)
+		}
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Request:       req,
+	}
+}
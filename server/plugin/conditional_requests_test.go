@@ -0,0 +1,76 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestConditionalRequestTransport(t *testing.T) {
+	var requests, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set(headerRateLimitRemaining, strconv.Itoa(5000-requests))
+		etag := `"` + r.Header.Get("Authorization") + `"`
+		if r.Method == http.MethodGet && r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if r.URL.Path != "/uncached" {
+			w.Header().Set("ETag", etag)
+		}
+		fmt.Fprintf(w, "response %d", requests)
+	}))
+	t.Cleanup(server.Close)
+
+	p := NewPlugin()
+	client1 := p.newGitHubHTTPClient(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token1"}))
+	client2 := p.newGitHubHTTPClient(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token2"}))
+
+	do := func(t *testing.T, client *http.Client, method, path string) (*http.Response, string) {
+		req, err := http.NewRequestWithContext(context.Background(), method, server.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(body)
+	}
+
+	resp, body := do(t, client1, http.MethodGet, "/repos")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "response 1", body)
+
+	resp, body = do(t, client1, http.MethodGet, "/repos")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "response 1", body)
+	assert.Equal(t, "4998", resp.Header.Get(headerRateLimitRemaining))
+	assert.Equal(t, 1, notModified)
+
+	// Responses aren't shared between tokens.
+	_, body = do(t, client2, http.MethodGet, "/repos")
+	assert.Equal(t, "response 3", body)
+	assert.Equal(t, 1, notModified)
+
+	_, body = do(t, client1, http.MethodPost, "/repos")
+	assert.Equal(t, "response 4", body)
+
+	_, body = do(t, client1, http.MethodGet, "/uncached")
+	assert.Equal(t, "response 5", body)
+	_, body = do(t, client1, http.MethodGet, "/uncached")
+	assert.Equal(t, "response 6", body)
+	assert.Equal(t, 1, notModified)
+}
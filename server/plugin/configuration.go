@@ -8,6 +8,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"reflect"
+	"regexp"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -49,6 +50,32 @@ type Configuration struct {
 	// DigestServiceUsername is the Mattermost username whose GitHub connection runs the overdue review digest.
 	// When empty, the plugin falls back to the lexicographically-first connected user.
 	DigestServiceUsername string `json:"digestserviceusername"`
+	// StalePullRequestDays is the number of days without activity after which an open PR is listed in its
+	// author's weekly stale pull request reminder (0 = reminder disabled).
+	StalePullRequestDays int `json:"stalepullrequestdays"`
+	// CodePreviewMaxLines is the maximum number of lines shown in a permalink code preview.
+	CodePreviewMaxLines int `json:"codepreviewmaxlines"`
+	// CodePreviewContextLines is the number of lines shown before and after a permalink to a single line.
+	CodePreviewContextLines int `json:"codepreviewcontextlines"`
+	// EnableThreadReplySync posts replies in threads of issue, pull request and comment notifications
+	// back to GitHub as comments by the replier.
+	EnableThreadReplySync bool `json:"enablethreadreplysync"`
+	// EnableReactionSync mirrors GitHub reactions onto recent issue, pull request and comment notifications.
+	EnableReactionSync bool `json:"enablereactionsync"`
+	// EnablePullRequestActions adds buttons to review, label, assign and merge pull requests to their notifications.
+	EnablePullRequestActions bool `json:"enablepullrequestactions"`
+	// EnableIssueActions adds buttons to close, reopen, assign, label and set the milestone of issues to their notifications.
+	EnableIssueActions bool `json:"enableissueactions"`
+	// GitHubAppID is the ID of the GitHub App whose installations run the organization-wide work.
+	GitHubAppID string `json:"githubappid"`
+	// GitHubAppPrivateKey is the PEM encoded private key of the GitHub App.
+	GitHubAppPrivateKey string `json:"githubappprivatekey"`
+	// RequireMatchingIdentity only lets users connect a GitHub account with a verified email, or a
+	// SAML identity in the configured organizations, matching their Mattermost email.
+	RequireMatchingIdentity bool `json:"requirematchingidentity"`
+	// OrganizationMembershipEnforcement requires users to be members of one of the configured
+	// organizations to connect, and is what happens daily to connected users who aren't anymore.
+	OrganizationMembershipEnforcement string `json:"organizationmembershipenforcement"`
 }
 
 func (c *Configuration) ToMap() (map[string]any, error) {
@@ -96,12 +123,20 @@ func (c *Configuration) setDefaults(isCloud bool) (bool, error) {
 	return changed, nil
 }
 
+const defaultGitHubBaseURL = "https://github.com/"
+
+const (
+	defaultCodePreviewMaxLines = 10
+	// maxCodePreviewLines bounds the configurable code preview size.
+	maxCodePreviewLines = 50
+)
+
 func (c *Configuration) getBaseURL() string {
 	if c.EnterpriseBaseURL != "" {
 		return c.EnterpriseBaseURL + "/"
 	}
 
-	return "https://github.com/"
+	return defaultGitHubBaseURL
 }
 
 func (c *Configuration) sanitize() {
@@ -112,11 +147,35 @@ func (c *Configuration) sanitize() {
 	if c.ReviewTargetDays < 0 {
 		c.ReviewTargetDays = 0
 	}
+	if c.StalePullRequestDays < 0 {
+		c.StalePullRequestDays = 0
+	}
+	if c.CodePreviewMaxLines > maxCodePreviewLines {
+		c.CodePreviewMaxLines = maxCodePreviewLines
+	}
+	if c.CodePreviewContextLines < 0 {
+		c.CodePreviewContextLines = 0
+	}
+	if c.CodePreviewContextLines > maxCodePreviewLines/2 {
+		c.CodePreviewContextLines = maxCodePreviewLines / 2
+	}
 
 	// Trim spaces around org and OAuth credentials
 	c.GitHubOrg = strings.TrimSpace(c.GitHubOrg)
 	c.GitHubOAuthClientID = strings.TrimSpace(c.GitHubOAuthClientID)
 	c.GitHubOAuthClientSecret = strings.TrimSpace(c.GitHubOAuthClientSecret)
+	c.GitHubAppID = strings.TrimSpace(c.GitHubAppID)
+	c.GitHubAppPrivateKey = strings.TrimSpace(c.GitHubAppPrivateKey)
+}
+
+// getCodePreviewMaxLines returns the maximum number of lines shown in a code preview,
+// falling back to the default when it isn't set.
+func (c *Configuration) getCodePreviewMaxLines() int {
+	if c.CodePreviewMaxLines <= 0 {
+		return defaultCodePreviewMaxLines
+	}
+
+	return c.CodePreviewMaxLines
 }
 
 func (c *Configuration) IsOAuthConfigured() bool {
@@ -124,6 +183,11 @@ func (c *Configuration) IsOAuthConfigured() bool {
 		c.UsePreregisteredApplication
 }
 
+// IsGitHubAppConfigured returns if the plugin authenticates as a GitHub App for organization-wide work.
+func (c *Configuration) IsGitHubAppConfigured() bool {
+	return c.GitHubAppID != "" && c.GitHubAppPrivateKey != ""
+}
+
 // IsSASS return if SASS GitHub at https://github.com is used.
 func (c *Configuration) IsSASS() bool {
 	return c.EnterpriseBaseURL == "" && c.EnterpriseUploadURL == ""
@@ -162,6 +226,19 @@ func (c *Configuration) IsValid() error {
 		return errors.New("must have an encryption key")
 	}
 
+	if (c.GitHubAppID == "") != (c.GitHubAppPrivateKey == "") {
+		return errors.New("must have both a github app id and a github app private key")
+	}
+	if c.GitHubAppPrivateKey != "" {
+		if _, err := parseGitHubAppPrivateKey(c.GitHubAppPrivateKey); err != nil {
+			return errors.Wrap(err, "invalid github app private key")
+		}
+	}
+
+	if c.isOrganizationMembershipEnforced() && len(c.getOrganizations()) == 0 {
+		return errors.New("must have a github organization to enforce organization membership")
+	}
+
 	return nil
 }
 
@@ -204,6 +281,29 @@ func (p *Plugin) setConfiguration(configuration *Configuration) {
 	}
 
 	p.configuration = configuration
+
+	if configuration != nil {
+		baseURL := configuration.getBaseURL()
+		p.githubPermalinkRegex = newGitHubPermalinkRegex(baseURL)
+		p.githubLinkRegex = newGitHubLinkRegex(baseURL)
+		p.githubDiffLinkRegex = newGitHubDiffLinkRegex(baseURL)
+	}
+}
+
+// getGitHubPermalinkRegex returns the regex matching permalinks on the configured GitHub host.
+func (p *Plugin) getGitHubPermalinkRegex() *regexp.Regexp {
+	p.configurationLock.RLock()
+	defer p.configurationLock.RUnlock()
+
+	return p.githubPermalinkRegex
+}
+
+// getGitHubLinkRegex returns the regex matching issue, pull request and commit links on the configured GitHub host.
+func (p *Plugin) getGitHubLinkRegex() *regexp.Regexp {
+	p.configurationLock.RLock()
+	defer p.configurationLock.RUnlock()
+
+	return p.githubLinkRegex
 }
 
 // OnConfigurationChange is invoked when configuration changes may have been made.
@@ -225,6 +325,11 @@ func (p *Plugin) OnConfigurationChange() error {
 
 	p.sendWebsocketEventIfNeeded(previousConfig, configuration)
 
+	if previousConfig.GitHubAppID != configuration.GitHubAppID ||
+		previousConfig.GitHubAppPrivateKey != configuration.GitHubAppPrivateKey {
+		p.resetInstallationTokens()
+	}
+
 	if previousEncryptionKey != "" && configuration.EncryptionKey != "" &&
 		previousEncryptionKey != configuration.EncryptionKey {
 		go p.reEncryptUserData(configuration.EncryptionKey, previousEncryptionKey)
@@ -271,6 +376,12 @@ func generateSecret() (string, error) {
 	return s, nil
 }
 
+// isOrganizationMembershipEnforced returns if users must be members of one of the configured organizations.
+func (c *Configuration) isOrganizationMembershipEnforced() bool {
+	return c.OrganizationMembershipEnforcement == membershipEnforcementDisconnect ||
+		c.OrganizationMembershipEnforcement == membershipEnforcementDowngrade
+}
+
 func (c *Configuration) getOrganizations() []string {
 	if c.GitHubOrg == "" {
 		return []string{}
@@ -287,3 +398,11 @@ func (c *Configuration) getOrganizations() []string {
 
 	return allOrgs
 }
+
+// getGitHubDiffLinkRegex returns the regex matching links to files in diffs on the configured GitHub host.
+func (p *Plugin) getGitHubDiffLinkRegex() *regexp.Regexp {
+	p.configurationLock.RLock()
+	defer p.configurationLock.RUnlock()
+
+	return p.githubDiffLinkRegex
+}
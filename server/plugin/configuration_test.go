@@ -49,6 +49,15 @@ func TestIsValid(t *testing.T) {
 			},
 			errMsg: "cannot use pre-registered application with GitHub enterprise",
 		},
+		{
+			description: "invalid configuration: organization membership enforced without an organization",
+			config: &Configuration{
+				UsePreregisteredApplication:       true,
+				EncryptionKey:                     "abcd",
+				OrganizationMembershipEnforcement: membershipEnforcementDisconnect,
+			},
+			errMsg: "must have a github organization to enforce organization membership",
+		},
 	} {
 		t.Run(testCase.description, func(t *testing.T) {
 			err := testCase.config.IsValid()
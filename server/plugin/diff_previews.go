@@ -0,0 +1,283 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // GitHub uses MD5 for legacy diff anchors, not for security.
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v54/github"
+)
+
+// maxDiffFilePages bounds how many pages of pull request files are searched for a diff anchor.
+// GitHub lists at most 3000 files per pull request.
+const maxDiffFilePages = 30
+
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// diffInfo holds the metadata of a link to a file in a pull request or commit diff.
+type diffInfo struct {
+	pull   int    // the pull request number, or 0 for commit links
+	commit string // the commit SHA of commit links
+	hash   string // the diff anchor, a hash of the file path
+	line   string // the linked diff lines, e.g. R40 or L10-R12
+}
+
+// cacheKey identifies the patch in permalinkContentCache, in the "diff:" namespace.
+func (d *diffInfo) cacheKey(user, repo string) string {
+	if d.pull != 0 {
+		return "diff:" + strings.ToLower(user+"/"+repo) + "#" + strconv.Itoa(d.pull) + ":" + d.hash
+	}
+	return "diff:" + strings.ToLower(user+"/"+repo+"@"+d.commit) + ":" + d.hash
+}
+
+// newGitHubDiffLinkRegex returns the regex matching links to a file in a pull request or commit
+// diff on the GitHub host of baseURL.
+func newGitHubDiffLinkRegex(baseURL string) *regexp.Regexp {
+	return regexp.MustCompile(`https?://(?P<haswww>www\.)?` + githubHostPattern(baseURL) + `/(?P<user>[\w-]+)/(?P<repo>[\w-.]+)/(?:pull/(?P<pull>\d+)/files|commit/(?P<commit>[0-9a-fA-F]{7,40}))#diff-(?P<hash>[0-9a-f]{64}|[0-9a-f]{32})(?P<line>[LR]\d+(?:-[LR]\d+)?)?`)
+}
+
+// getDiffReplacements returns the replacements for the diff links in msg.
+func (p *Plugin) getDiffReplacements(msg string) []replacement {
+	diffLinkRegex := p.getGitHubDiffLinkRegex()
+	matches := diffLinkRegex.FindAllStringSubmatch(msg, -1)
+	indices := diffLinkRegex.FindAllStringIndex(msg, -1)
+	var replacements []replacement
+	for i, m := range matches {
+		if isInsideLink(msg, indices[i][0]) {
+			continue
+		}
+
+		r := replacement{
+			index: indices[i][0],
+			word:  m[0],
+			diff:  &diffInfo{},
+		}
+		for j, name := range diffLinkRegex.SubexpNames() {
+			switch name {
+			case "haswww":
+				r.permalinkInfo.haswww = m[j]
+			case "user":
+				r.permalinkInfo.user = m[j]
+			case "repo":
+				r.permalinkInfo.repo = m[j]
+			case "pull":
+				r.diff.pull, _ = strconv.Atoi(m[j])
+			case "commit":
+				r.diff.commit = m[j]
+			case "hash":
+				r.diff.hash = m[j]
+			case "line":
+				r.diff.line = m[j]
+			}
+		}
+		replacements = append(replacements, r)
+	}
+	return replacements
+}
+
+// getDiffPatch returns the patch of the file the diff link points to, found by matching the diff
+// anchor against the files of the pull request or commit. Patches of public commits are cached.
+func (p *Plugin) getDiffPatch(ctx context.Context, r replacement, ghClient *github.Client) (permalinkContent, bool) {
+	owner, repo := r.permalinkInfo.user, r.permalinkInfo.repo
+	allowed, checkedPublic := p.checkPermalinkRepo(ctx, owner, repo, ghClient)
+	if !allowed {
+		return permalinkContent{}, false
+	}
+
+	// Commits never change, unlike the files of a pull request.
+	cacheable := checkedPublic && r.diff.pull == 0 && isCommitSHA(r.diff.commit)
+	key := r.diff.cacheKey(owner, repo)
+	if cacheable {
+		if cached, ok := p.permalinkContentCache.get(key); ok {
+			if content, ok := cached.(permalinkContent); ok {
+				return content, true
+			}
+		}
+	}
+
+	var file *github.CommitFile
+	var err error
+	if r.diff.pull != 0 {
+		file, err = findPullRequestDiffFile(ctx, ghClient, owner, repo, r.diff.pull, r.diff.hash)
+	} else {
+		file, err = findCommitDiffFile(ctx, ghClient, owner, repo, r.diff.commit, r.diff.hash)
+	}
+	if err != nil {
+		p.client.Log.Warn("Error while fetching diff files", "error", err.Error(), "repo", repo, "user", owner)
+		return permalinkContent{}, false
+	}
+	if file == nil || file.GetPatch() == "" {
+		// The anchor doesn't match a file, or the diff is binary or too large for GitHub to show.
+		return permalinkContent{}, false
+	}
+
+	content := permalinkContent{decoded: file.GetPatch(), commit: r.diff.commit, path: file.GetFilename()}
+	if cacheable && len(content.decoded) <= maxCachedPermalinkContentSize {
+		p.permalinkContentCache.add(key, content)
+	}
+
+	return content, true
+}
+
+func findPullRequestDiffFile(ctx context.Context, ghClient *github.Client, owner, repo string, number int, hash string) (*github.CommitFile, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	for page := 0; page < maxDiffFilePages; page++ {
+		files, resp, err := ghClient.PullRequests.ListFiles(ctx, owner, repo, number, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		if file := findDiffFile(files, hash); file != nil {
+			return file, nil
+		}
+
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return nil, nil
+}
+
+func findCommitDiffFile(ctx context.Context, ghClient *github.Client, owner, repo, sha, hash string) (*github.CommitFile, error) {
+	commit, _, err := ghClient.Repositories.GetCommit(ctx, owner, repo, sha, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return findDiffFile(commit.Files, hash), nil
+}
+
+// findDiffFile returns the file whose diff anchor is hash. GitHub anchors are the SHA-256 of the
+// file name, or the MD5 on older links.
+func findDiffFile(files []*github.CommitFile, hash string) *github.CommitFile {
+	for _, file := range files {
+		if getDiffAnchor(file.GetFilename(), len(hash)) == hash {
+			return file
+		}
+	}
+	return nil
+}
+
+func getDiffAnchor(filename string, length int) string {
+	if length == hex.EncodedLen(md5.Size) {
+		sum := md5.Sum([]byte(filename)) //nolint:gosec // Not used for security.
+		return hex.EncodeToString(sum[:])
+	}
+
+	sum := sha256.Sum256([]byte(filename))
+	return hex.EncodeToString(sum[:])
+}
+
+// getDiffPreviewMarkdown returns the preview of the diff lines the link points to.
+func (p *Plugin) getDiffPreviewMarkdown(r replacement, content permalinkContent, maxLines, contextLines int) (string, bool) {
+	lines, isTruncated := getDiffHunkLines(content.decoded, r.diff.line, contextLines, maxLines)
+	if lines == "" {
+		p.client.Log.Warn("Diff lines out of range. Skipping.", "file", content.path, "line", r.diff.line)
+		return "", false
+	}
+
+	return getCodeMarkdown(r.permalinkInfo.user, r.permalinkInfo.repo, content.path, r.word, lines, "diff", isTruncated, "", ""), true
+}
+
+// diffLine is a line of a patch along with its line numbers on the left (old) and right (new)
+// side of the diff, which are zero when the line isn't on that side.
+type diffLine struct {
+	text  string
+	left  int
+	right int
+}
+
+func parsePatch(patch string) []diffLine {
+	var lines []diffLine
+	left, right := 0, 0
+	for _, text := range strings.Split(strings.TrimSuffix(patch, "\n"), "\n") {
+		if m := hunkHeaderRegex.FindStringSubmatch(text); m != nil {
+			left, _ = strconv.Atoi(m[1])
+			right, _ = strconv.Atoi(m[2])
+			lines = append(lines, diffLine{text: text})
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(text, "+"):
+			lines = append(lines, diffLine{text: text, right: right})
+			right++
+		case strings.HasPrefix(text, "-"):
+			lines = append(lines, diffLine{text: text, left: left})
+			left++
+		case strings.HasPrefix(text, `\`):
+			// "\ No newline at end of file"
+			lines = append(lines, diffLine{text: text})
+		default:
+			lines = append(lines, diffLine{text: text, left: left, right: right})
+			left++
+			right++
+		}
+	}
+	return lines
+}
+
+// findDiffLine returns the index of the line referenced by anchor, e.g. L10 or R40, or -1.
+func findDiffLine(lines []diffLine, anchor string) int {
+	if len(anchor) < 2 {
+		return -1
+	}
+	n, err := strconv.Atoi(anchor[1:])
+	if err != nil || n <= 0 {
+		return -1
+	}
+
+	for i, line := range lines {
+		if (anchor[0] == 'L' && line.left == n) || (anchor[0] == 'R' && line.right == n) {
+			return i
+		}
+	}
+	return -1
+}
+
+// getDiffHunkLines returns the lines of the patch referenced by anchor, with contextLines around
+// a single line. Without an anchor the patch is shown from the start. At most maxLines lines are
+// returned, reporting whether the rest were cut off.
+func getDiffHunkLines(patch, anchor string, contextLines, maxLines int) (string, bool) {
+	lines := parsePatch(patch)
+
+	start, end := 0, len(lines)-1
+	if anchor != "" {
+		first, last, isRange := strings.Cut(anchor, "-")
+		start = findDiffLine(lines, first)
+		if start == -1 {
+			return "", false
+		}
+		if isRange {
+			end = findDiffLine(lines, last)
+			if end < start {
+				return "", false
+			}
+		} else {
+			end = min(start+contextLines, len(lines)-1)
+			start = max(start-contextLines, 0)
+		}
+	}
+
+	isTruncated := false
+	if end-start+1 > maxLines {
+		end = start + maxLines - 1
+		isTruncated = true
+	}
+
+	var buf strings.Builder
+	for _, line := range lines[start : end+1] {
+		buf.WriteString(collapseLine(line.text, maxPreviewLineLength))
+		buf.WriteByte('\n')
+	}
+	return buf.String(), isTruncated
+}
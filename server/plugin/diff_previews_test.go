@@ -0,0 +1,141 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v54/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+)
+
+const testPatch = `@@ -1,5 +1,6 @@
+ package app
+
+-import "fmt"
++import (
++	"fmt"
++)
+
+ func a() {}
+@@ -20,3 +21,3 @@ func b() {
+ 	x := 1
+-	y := 2
++	y := 3
+ 	return`
+
+func TestGetDiffReplacements(t *testing.T) {
+	p := NewPlugin()
+	hash := getDiffAnchor("app/file.go", 64)
+
+	msg := "see https://github.com/mattermost/mattermost/pull/12/files#diff-" + hash + "R4-R6 and https://github.com/mattermost/mattermost/commit/cbb2583#diff-" + getDiffAnchor("app/file.go", 32) + "L3"
+	replacements := p.getReplacements(msg)
+
+	require.Len(t, replacements, 2)
+	assert.Equal(t, "mattermost", replacements[0].permalinkInfo.user)
+	assert.Equal(t, &diffInfo{pull: 12, hash: hash, line: "R4-R6"}, replacements[0].diff)
+	assert.Equal(t, &diffInfo{commit: "cbb2583", hash: getDiffAnchor("app/file.go", 32), line: "L3"}, replacements[1].diff)
+}
+
+func TestGetDiffHunkLines(t *testing.T) {
+	tcs := []struct {
+		name        string
+		anchor      string
+		maxLines    int
+		expected    string
+		isTruncated bool
+	}{
+		{
+			name:     "right side range",
+			anchor:   "R3-R5",
+			maxLines: 10,
+			expected: "+import (\n+\t\"fmt\"\n+)\n",
+		},
+		{
+			name:     "single left line with context",
+			anchor:   "L21",
+			maxLines: 10,
+			expected: "@@ -20,3 +21,3 @@ func b() {\n \tx := 1\n-\ty := 2\n+\ty := 3\n \treturn\n",
+		},
+		{
+			name:     "range across sides",
+			anchor:   "L3-R3",
+			maxLines: 10,
+			expected: "-import \"fmt\"\n+import (\n",
+		},
+		{
+			name:        "no anchor shows the start of the patch",
+			maxLines:    2,
+			expected:    "@@ -1,5 +1,6 @@\n package app\n",
+			isTruncated: true,
+		},
+		{
+			name:     "line outside the patch",
+			anchor:   "R100",
+			maxLines: 10,
+		},
+		{
+			name:     "reversed range",
+			anchor:   "R5-R3",
+			maxLines: 10,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			lines, isTruncated := getDiffHunkLines(testPatch, tc.anchor, 2, tc.maxLines)
+			assert.Equal(t, tc.expected, lines)
+			assert.Equal(t, tc.isTruncated, isTruncated)
+		})
+	}
+}
+
+func TestMakeDiffReplacements(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{EnableCodePreview: "public"})
+	p.SetAPI(&plugintest.API{})
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/api-v3/repos/mattermost/mattermost":
+			_ = json.NewEncoder(w).Encode(&github.Repository{Private: github.Bool(false)})
+		case "/api-v3/repos/mattermost/mattermost/pulls/12/files":
+			_ = json.NewEncoder(w).Encode([]*github.CommitFile{
+				{Filename: github.String("README.md"), Patch: github.String("@@ -1 +1 @@\n-a\n+b")},
+				{Filename: github.String("app/file.go"), Patch: github.String(testPatch)},
+			})
+		}
+	}))
+	defer server.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + baseURLPath + "/")
+
+	link := "https://github.com/mattermost/mattermost/pull/12/files#diff-" + getDiffAnchor("app/file.go", 64) + "R3-R5"
+	msg := p.makeReplacements(context.Background(), "look "+link, p.getReplacements("look "+link), client)
+
+	assert.Equal(t, "look \n[mattermost/mattermost/app/file.go]("+link+")\n```diff\n+import (\n+\t\"fmt\"\n+)\n```\n", msg)
+}
+
+func TestDiffCacheKey(t *testing.T) {
+	sha := "cbb25838a61872b624ac512556d7bc932486a64c"
+	hash := getDiffAnchor("app/file.go", 64)
+
+	// A file named like a diff anchor must not share the cache entry of the diff patch.
+	file := permalinkFile{user: "mattermost", repo: "mattermost", commit: sha, path: "diff-" + hash}
+	diff := &diffInfo{commit: sha, hash: hash}
+	assert.NotEqual(t, file.cacheKey(), diff.cacheKey("mattermost", "mattermost"))
+
+	assert.Equal(t, diff.cacheKey("mattermost", "mattermost"), diff.cacheKey("Mattermost", "Mattermost"))
+	assert.NotEqual(t, diff.cacheKey("mattermost", "mattermost"), (&diffInfo{pull: 12, hash: hash}).cacheKey("mattermost", "mattermost"))
+}
@@ -32,7 +32,9 @@ type FlowManager struct {
 	router           *mux.Router
 	getConfiguration func() *Configuration
 	getGitHubClient  func(ctx context.Context, userID string) (*github.Client, error)
-	useGitHubClient  func(info *GitHubUserInfo, toRun func(info *GitHubUserInfo, token *oauth2.Token) error) error
+	// getInstallationClient returns a client authenticated as the GitHub App installation on an owner.
+	getInstallationClient func(ctx context.Context, owner string) (*github.Client, error)
+	useGitHubClient       func(info *GitHubUserInfo, toRun func(info *GitHubUserInfo, token *oauth2.Token) error) error
 
 	pingBroker PingBroker
 
@@ -44,13 +46,14 @@ type FlowManager struct {
 
 func (p *Plugin) NewFlowManager() (*FlowManager, error) {
 	fm := &FlowManager{
-		client:           p.client,
-		pluginID:         Manifest.Id,
-		botUserID:        p.BotUserID,
-		router:           p.router,
-		getConfiguration: p.getConfiguration,
-		getGitHubClient:  p.GetGitHubClient,
-		useGitHubClient:  p.useGitHubClient,
+		client:                p.client,
+		pluginID:              Manifest.Id,
+		botUserID:             p.BotUserID,
+		router:                p.router,
+		getConfiguration:      p.getConfiguration,
+		getGitHubClient:       p.GetGitHubClient,
+		getInstallationClient: p.getInstallationClient,
+		useGitHubClient:       p.useGitHubClient,
 
 		pingBroker: p.webhookBroker,
 	}
@@ -665,7 +668,7 @@ func (fm *FlowManager) submitWebhook(f *flow.Flow, submitted map[string]any) (fl
 	ctx, cancel := context.WithTimeout(context.Background(), 28*time.Second) // HTTP request times out after 30 seconds
 	defer cancel()
 
-	ghClient, err := fm.getGitHubClient(ctx, f.UserID)
+	ghClient, err := fm.getWebhookClient(ctx, f.UserID, org)
 	if err != nil {
 		return "", nil, nil, err
 	}
@@ -729,6 +732,20 @@ func (fm *FlowManager) submitWebhook(f *flow.Flow, submitted map[string]any) (fl
 	return stepWebhookConfirmation, nil, nil, nil
 }
 
+// getWebhookClient returns the client that creates the webhook on the owner: the GitHub App
+// installation on the owner when the app is configured and installed there, else the user's.
+func (fm *FlowManager) getWebhookClient(ctx context.Context, userID, owner string) (*github.Client, error) {
+	if fm.getConfiguration().IsGitHubAppConfigured() {
+		ghClient, err := fm.getInstallationClient(ctx, owner)
+		if err == nil {
+			return ghClient, nil
+		}
+		fm.client.Log.Debug("Failed to get GitHub App installation client", "owner", owner, "error", err.Error())
+	}
+
+	return fm.getGitHubClient(ctx, userID)
+}
+
 func (fm *FlowManager) stepWebhookWarning() flow.Step {
 	warnText := "The GitHub plugin uses a webhook to connect a GitHub account to Mattermost to listen for incoming GitHub events. " +
 		"You can't subscribe a channel to a repository for notifications until webhooks are configured.\n" +
@@ -0,0 +1,227 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v54/github"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-github/server/plugin/graphql"
+)
+
+const (
+	// githubAppJWTLifetime stays below the ten minutes GitHub accepts for app JWTs.
+	githubAppJWTLifetime = 9 * time.Minute
+	// githubAppJWTClockSkew backdates the JWT issue time to tolerate clock drift.
+	githubAppJWTClockSkew = 60 * time.Second
+	// installationTokenRefreshMargin is how long before its expiry an installation token is replaced.
+	installationTokenRefreshMargin = 5 * time.Minute
+)
+
+var errGitHubAppNotConfigured = errors.New("GitHub App is not configured")
+
+// installationToken is a cached installation access token of the GitHub App.
+type installationToken struct {
+	InstallationID int64
+	Token          string
+	ExpiresAt      time.Time
+}
+
+func (t *installationToken) isValid(now time.Time) bool {
+	return t.Token != "" && now.Add(installationTokenRefreshMargin).Before(t.ExpiresAt)
+}
+
+// parseGitHubAppPrivateKey parses the PEM encoded private key GitHub generates for an app.
+func parseGitHubAppPrivateKey(privateKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(privateKey)))
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse private key")
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+
+	return key, nil
+}
+
+// makeGitHubAppJWT returns the RS256 signed JWT that authenticates requests as the app itself.
+func makeGitHubAppJWT(appID string, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-githubAppJWTClockSkew).Unix(),
+		"exp": now.Add(githubAppJWTLifetime).Unix(),
+		"iss": appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// getGitHubAppClient returns a client authenticated as the GitHub App itself, which can only
+// manage the app's installations.
+func (p *Plugin) getGitHubAppClient() (*github.Client, error) {
+	config := p.getConfiguration()
+	if !config.IsGitHubAppConfigured() {
+		return nil, errGitHubAppNotConfigured
+	}
+
+	key, err := parseGitHubAppPrivateKey(config.GitHubAppPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	jwt, err := makeGitHubAppJWT(config.GitHubAppID, key, time.Now())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign GitHub App JWT")
+	}
+
+	return getGitHubClient(p.newGitHubHTTPClient(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: jwt})), config)
+}
+
+// findInstallationID returns the ID of the app installation on the organization or user account.
+func findInstallationID(ctx context.Context, appClient *github.Client, owner string) (int64, error) {
+	installation, resp, err := appClient.Apps.FindOrganizationInstallation(ctx, owner)
+	if err != nil && resp != nil && resp.StatusCode == http.StatusNotFound {
+		installation, _, err = appClient.Apps.FindUserInstallation(ctx, owner)
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to find the GitHub App installation on %s", owner)
+	}
+
+	return installation.GetID(), nil
+}
+
+// getInstallationToken returns an access token of the app installation on the owner, creating
+// a new one when the cached token is missing or about to expire.
+func (p *Plugin) getInstallationToken(ctx context.Context, owner string) (string, error) {
+	key := strings.ToLower(owner)
+
+	p.installationTokensLock.Lock()
+	defer p.installationTokensLock.Unlock()
+
+	cached := p.installationTokens[key]
+	if cached != nil && cached.isValid(time.Now()) {
+		return cached.Token, nil
+	}
+
+	appClient, err := p.getGitHubAppClient()
+	if err != nil {
+		return "", err
+	}
+
+	var installationID int64
+	if cached != nil {
+		installationID = cached.InstallationID
+	} else if installationID, err = findInstallationID(ctx, appClient, owner); err != nil {
+		return "", err
+	}
+
+	token, _, err := appClient.Apps.CreateInstallationToken(ctx, installationID, nil)
+	if err != nil {
+		// The installation may have been removed and the app installed again.
+		delete(p.installationTokens, key)
+		return "", errors.Wrapf(err, "failed to create an installation token for %s", owner)
+	}
+
+	if p.installationTokens == nil {
+		p.installationTokens = map[string]*installationToken{}
+	}
+	p.installationTokens[key] = &installationToken{
+		InstallationID: installationID,
+		Token:          token.GetToken(),
+		ExpiresAt:      token.GetExpiresAt().Time,
+	}
+
+	return token.GetToken(), nil
+}
+
+// resetInstallationTokens drops the cached installation tokens, e.g. after the app credentials changed.
+func (p *Plugin) resetInstallationTokens() {
+	p.installationTokensLock.Lock()
+	defer p.installationTokensLock.Unlock()
+
+	p.installationTokens = nil
+}
+
+// getInstallationClient returns a client authenticated as the app installation on the owner.
+func (p *Plugin) getInstallationClient(ctx context.Context, owner string) (*github.Client, error) {
+	token, err := p.getInstallationToken(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return getGitHubClient(p.newGitHubHTTPClient(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})), p.getConfiguration())
+}
+
+// getInstallationGraphQLClient returns a GraphQL client authenticated as the app installation on the owner.
+func (p *Plugin) getInstallationGraphQLClient(ctx context.Context, owner string) (*graphql.Client, error) {
+	token, err := p.getInstallationToken(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	config := p.getConfiguration()
+	return graphql.NewClient(p.client.Log, config.getOrganizations, p.newGitHubHTTPClient(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})), "", config.GitHubOrg, config.EnterpriseBaseURL), nil
+}
+
+// isInstallationOrganizationMember checks the organization membership of user as the app
+// installation on the organization. ok is false when the app isn't configured or installed
+// there, in which case callers fall back to a connected user's token.
+func (p *Plugin) isInstallationOrganizationMember(user *github.User, organization string) (isMember, ok bool) {
+	if organization == "" || !p.getConfiguration().IsGitHubAppConfigured() {
+		return false, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	githubClient, err := p.getInstallationClient(ctx, organization)
+	if err != nil {
+		p.client.Log.Debug("Failed to get GitHub App installation client", "org", organization, "error", err.Error())
+		return false, false
+	}
+
+	isMember, _, err = githubClient.Organizations.IsMember(ctx, organization, user.GetLogin())
+	if err != nil {
+		p.client.Log.Warn("Failed to check if user is an org member", "GitHub username", user.GetLogin(), "error", err.Error())
+		return false, true
+	}
+
+	return isMember, true
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateGitHubAppKey(t *testing.T) (*rsa.PrivateKey, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+func TestParseGitHubAppPrivateKey(t *testing.T) {
+	key, pkcs1 := generateGitHubAppKey(t)
+	pkcs8Bytes, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	pkcs8 := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Bytes}))
+
+	for name, tc := range map[string]struct {
+		privateKey  string
+		expectError bool
+	}{
+		"PKCS1 key":        {privateKey: pkcs1},
+		"PKCS8 key":        {privateKey: pkcs8},
+		"surrounding text": {privateKey: "\n  " + pkcs1 + "\n"},
+		"not PEM":          {privateKey: "not a key", expectError: true},
+		"invalid key":      {privateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("garbage")})), expectError: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			parsed, err := parseGitHubAppPrivateKey(tc.privateKey)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, key.Equal(parsed))
+		})
+	}
+}
+
+func TestMakeGitHubAppJWT(t *testing.T) {
+	key, _ := generateGitHubAppKey(t)
+	now := time.Unix(1700000000, 0)
+
+	jwt, err := makeGitHubAppJWT("12345", key, now)
+	require.NoError(t, err)
+
+	parts := strings.Split(jwt, ".")
+	require.Len(t, parts, 3)
+
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"alg":"RS256","typ":"JWT"}`, string(header))
+
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"iat":1699999940,"exp":1700000540,"iss":"12345"}`, string(claims))
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+}
+
+func TestGetInstallationToken(t *testing.T) {
+	_, privateKey := generateGitHubAppKey(t)
+
+	var findRequests, tokenRequests int
+	var expiresAt time.Time
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/orgs/mockOrg/installation", func(w http.ResponseWriter, r *http.Request) {
+		findRequests++
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "))
+		fmt.Fprint(w, `{"id": 42}`)
+	})
+	mux.HandleFunc("/api/v3/app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		assert.Equal(t, http.MethodPost, r.Method)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"token":      fmt.Sprintf("token%d", tokenRequests),
+			"expires_at": expiresAt,
+		})
+	})
+	mux.HandleFunc("/api/v3/users/unknown/installation", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+	})
+	mux.HandleFunc("/api/v3/orgs/unknown/installation", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{
+		EnterpriseBaseURL:   server.URL,
+		EnterpriseUploadURL: server.URL,
+		GitHubAppID:         "12345",
+		GitHubAppPrivateKey: privateKey,
+	})
+
+	expiresAt = time.Now().Add(time.Hour)
+	token, err := p.getInstallationToken(context.Background(), "mockOrg")
+	require.NoError(t, err)
+	assert.Equal(t, "token1", token)
+
+	token, err = p.getInstallationToken(context.Background(), "MockOrg")
+	require.NoError(t, err)
+	assert.Equal(t, "token1", token)
+	assert.Equal(t, 1, findRequests)
+	assert.Equal(t, 1, tokenRequests)
+
+	p.installationTokens["mockorg"].ExpiresAt = time.Now().Add(installationTokenRefreshMargin - time.Minute)
+	token, err = p.getInstallationToken(context.Background(), "mockOrg")
+	require.NoError(t, err)
+	assert.Equal(t, "token2", token)
+	assert.Equal(t, 1, findRequests)
+	assert.Equal(t, 2, tokenRequests)
+
+	_, err = p.getInstallationToken(context.Background(), "unknown")
+	assert.Error(t, err)
+
+	p.resetInstallationTokens()
+	p.setConfiguration(&Configuration{})
+	_, err = p.getInstallationToken(context.Background(), "mockOrg")
+	assert.ErrorIs(t, err, errGitHubAppNotConfigured)
+}
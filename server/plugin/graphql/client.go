@@ -5,11 +5,11 @@ package graphql
 
 import (
 	"context"
+	"net/http"
 	"net/url"
 
 	"github.com/pkg/errors"
 	"github.com/shurcooL/githubv4"
-	"golang.org/x/oauth2"
 
 	"github.com/mattermost/mattermost/server/public/pluginapi"
 )
@@ -24,9 +24,7 @@ type Client struct {
 }
 
 // NewClient creates and returns Client. The third party package that queries GraphQL is initialized here.
-func NewClient(logger pluginapi.LogService, getOrganizations func() []string, token oauth2.Token, username, orgName, enterpriseBaseURL string) *Client {
-	ts := oauth2.StaticTokenSource(&token)
-	httpClient := oauth2.NewClient(context.Background(), ts)
+func NewClient(logger pluginapi.LogService, getOrganizations func() []string, httpClient *http.Client, username, orgName, enterpriseBaseURL string) *Client {
 	var client Client
 
 	if enterpriseBaseURL == "" {
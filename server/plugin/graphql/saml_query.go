@@ -0,0 +1,61 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package graphql
+
+import (
+	"context"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// samlIdentityQuery is the response shape for GetSAMLIdentityEmails.
+type samlIdentityQuery struct {
+	Organization struct {
+		// SamlIdentityProvider is nil for organizations without SAML single sign-on.
+		SamlIdentityProvider *struct {
+			ExternalIdentities struct {
+				Nodes []struct {
+					SamlIdentity struct {
+						NameID githubv4.String `graphql:"nameId"`
+						Emails []struct {
+							Value githubv4.String
+						}
+					}
+				}
+			} `graphql:"externalIdentities(first:1, login:$login)"`
+		}
+	} `graphql:"organization(login:$org)"`
+}
+
+// GetSAMLIdentityEmails returns the name ID and email addresses of the SAML identity linked to
+// the GitHub login in the organization, if the organization uses SAML single sign-on. Reading
+// external identities requires the organization's owner permissions.
+func (c *Client) GetSAMLIdentityEmails(ctx context.Context, org, login string) ([]string, error) {
+	var query samlIdentityQuery
+	params := map[string]any{
+		"org":   githubv4.String(org),
+		"login": githubv4.String(login),
+	}
+
+	if err := c.executeQuery(ctx, &query, params); err != nil {
+		return nil, err
+	}
+
+	provider := query.Organization.SamlIdentityProvider
+	if provider == nil {
+		return nil, nil
+	}
+
+	var emails []string
+	for _, node := range provider.ExternalIdentities.Nodes {
+		if node.SamlIdentity.NameID != "" {
+			emails = append(emails, string(node.SamlIdentity.NameID))
+		}
+		for _, email := range node.SamlIdentity.Emails {
+			emails = append(emails, string(email.Value))
+		}
+	}
+
+	return emails, nil
+}
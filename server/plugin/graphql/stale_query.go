@@ -0,0 +1,130 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shurcooL/githubv4"
+)
+
+// AuthoredPR is a single open PR authored by the client's user, flattened from the GraphQL
+// search response with the review and commit details needed to tell whether it is stuck.
+type AuthoredPR struct {
+	RepoFullName   string
+	Number         int
+	Title          string
+	URL            string
+	UpdatedAt      time.Time
+	ReviewDecision string // APPROVED, CHANGES_REQUESTED, REVIEW_REQUIRED or empty
+	// LastCommitAt is when the most recent commit on the PR was committed.
+	LastCommitAt time.Time
+	// ChangesRequestedAt is when the most recent "changes requested" review was submitted.
+	ChangesRequestedAt time.Time
+}
+
+type authoredPRSearchNode struct {
+	PullRequest struct {
+		Number         githubv4.Int
+		Title          githubv4.String
+		URL            githubv4.URI
+		UpdatedAt      githubv4.DateTime
+		ReviewDecision githubv4.String
+		Repository     struct {
+			NameWithOwner githubv4.String
+		}
+		Commits struct {
+			Nodes []struct {
+				Commit struct {
+					CommittedDate githubv4.DateTime
+				}
+			}
+		} `graphql:"commits(last:1)"`
+		Reviews struct {
+			Nodes []struct {
+				SubmittedAt githubv4.DateTime
+			}
+		} `graphql:"reviews(last:1, states:[CHANGES_REQUESTED])"`
+	} `graphql:"... on PullRequest"`
+}
+
+// authoredPRsSearchQuery is the response shape for GetAuthoredOpenPRs. Instantiated per call
+// so concurrent callers (e.g. the weekly reminder running alongside an LHS fetch) don't share state.
+type authoredPRsSearchQuery struct {
+	Search struct {
+		Nodes    []authoredPRSearchNode
+		PageInfo struct {
+			EndCursor   githubv4.String
+			HasNextPage bool
+		}
+	} `graphql:"search(first:100, after:$cursor, query:$query, type:ISSUE)"`
+}
+
+// GetAuthoredOpenPRs returns every open non-draft PR authored by the client's user, limited to
+// the configured organizations when there are any.
+func (c *Client) GetAuthoredOpenPRs(ctx context.Context) ([]AuthoredPR, error) {
+	orgsList := c.getOrganizations()
+	if len(orgsList) == 0 {
+		return c.fetchAuthoredOpenPRs(ctx, "")
+	}
+
+	var out []AuthoredPR
+	for _, org := range orgsList {
+		prs, err := c.fetchAuthoredOpenPRs(ctx, org)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, prs...)
+	}
+
+	return out, nil
+}
+
+func (c *Client) fetchAuthoredOpenPRs(ctx context.Context, org string) ([]AuthoredPR, error) {
+	query := fmt.Sprintf("author:%s is:pr is:%s archived:false draft:false", c.username, githubv4.PullRequestStateOpen)
+	if org != "" {
+		query = fmt.Sprintf("org:%s %s", org, query)
+	}
+
+	params := map[string]any{
+		"query":  githubv4.String(query),
+		"cursor": (*githubv4.String)(nil),
+	}
+
+	var authoredQuery authoredPRsSearchQuery
+	var out []AuthoredPR
+	for {
+		if err := c.executeQuery(ctx, &authoredQuery, params); err != nil {
+			return nil, errors.Wrapf(err, "authored PR search failed for org %q", org)
+		}
+
+		for _, node := range authoredQuery.Search.Nodes {
+			pr := AuthoredPR{
+				RepoFullName:   string(node.PullRequest.Repository.NameWithOwner),
+				Number:         int(node.PullRequest.Number),
+				Title:          string(node.PullRequest.Title),
+				URL:            node.PullRequest.URL.String(),
+				UpdatedAt:      node.PullRequest.UpdatedAt.Time,
+				ReviewDecision: string(node.PullRequest.ReviewDecision),
+			}
+			if commits := node.PullRequest.Commits.Nodes; len(commits) > 0 {
+				pr.LastCommitAt = commits[len(commits)-1].Commit.CommittedDate.Time
+			}
+			if reviews := node.PullRequest.Reviews.Nodes; len(reviews) > 0 {
+				pr.ChangesRequestedAt = reviews[len(reviews)-1].SubmittedAt.Time
+			}
+			out = append(out, pr)
+		}
+
+		if !authoredQuery.Search.PageInfo.HasNextPage {
+			break
+		}
+		params["cursor"] = githubv4.NewString(authoredQuery.Search.PageInfo.EndCursor)
+	}
+
+	return out, nil
+}
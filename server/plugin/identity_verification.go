@@ -0,0 +1,152 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v54/github"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/pkg/errors"
+)
+
+const (
+	identityMatchVerifiedEmail = "verified_email"
+	identityMatchSAML          = "saml_identity"
+)
+
+// errIdentityMismatch is returned when a GitHub account can't be matched to the Mattermost user.
+var errIdentityMismatch = errors.New("GitHub account does not match the Mattermost user")
+
+// verifyGitHubIdentity checks that the GitHub account authenticated by githubClient belongs to
+// the Mattermost user with the given email, through a verified email of the account or its SAML
+// identity in one of the configured organizations. It returns how the identity was matched.
+func (p *Plugin) verifyGitHubIdentity(ctx context.Context, githubClient *github.Client, login, email string) (string, error) {
+	if email == "" {
+		return "", errIdentityMismatch
+	}
+
+	emails, err := listVerifiedEmails(ctx, githubClient)
+	if err != nil {
+		// The SAML identity may still match, so the error only matters if it doesn't.
+		p.client.Log.Warn("Failed to list the verified emails of the GitHub account", "login", login, "error", err.Error())
+	}
+	if containsEmail(emails, email) {
+		return identityMatchVerifiedEmail, nil
+	}
+
+	if matched := p.matchSAMLIdentity(ctx, login, email); matched {
+		return identityMatchSAML, nil
+	}
+
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list the verified emails of the GitHub account")
+	}
+
+	return "", errIdentityMismatch
+}
+
+func listVerifiedEmails(ctx context.Context, githubClient *github.Client) ([]string, error) {
+	var emails []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := githubClient.Users.ListEmails(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, email := range page {
+			if email.GetVerified() {
+				emails = append(emails, email.GetEmail())
+			}
+		}
+		if resp.NextPage == 0 {
+			return emails, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// matchSAMLIdentity returns if the SAML identity of login in one of the configured organizations
+// has the email. External identities can only be read by organization owners, so they are read
+// through the GitHub App installations.
+func (p *Plugin) matchSAMLIdentity(ctx context.Context, login, email string) bool {
+	config := p.getConfiguration()
+	if !config.IsGitHubAppConfigured() {
+		return false
+	}
+
+	for _, org := range config.getOrganizations() {
+		graphQLClient, err := p.getInstallationGraphQLClient(ctx, org)
+		if err != nil {
+			p.client.Log.Debug("Failed to get GitHub App installation GraphQL client", "org", org, "error", err.Error())
+			continue
+		}
+
+		emails, err := graphQLClient.GetSAMLIdentityEmails(ctx, org, login)
+		if err != nil {
+			p.client.Log.Warn("Failed to get the SAML identity of the GitHub account", "org", org, "login", login, "error", err.Error())
+			continue
+		}
+		if containsEmail(emails, email) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsEmail(emails []string, email string) bool {
+	for _, e := range emails {
+		if strings.EqualFold(strings.TrimSpace(e), email) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkGitHubIdentity enforces RequireMatchingIdentity for the user connecting the GitHub login,
+// writing the API error and returning it when the connection must be rejected.
+func (p *Plugin) checkGitHubIdentity(ctx context.Context, c *Context, w http.ResponseWriter, githubClient *github.Client, login, userID string) error {
+	auditRec := plugin.MakeAuditRecord("verifyGitHubIdentity", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = userID
+	model.AddEventParameterAuditableToAuditRec(auditRec, "identity", VerifyGitHubIdentityAuditParams{
+		UserID:         userID,
+		GitHubUsername: login,
+	})
+
+	user, err := p.client.User.Get(userID)
+	if err != nil {
+		c.Log.WithError(err).Errorf("Failed to get the Mattermost user")
+		auditRec.AddErrorDesc(err.Error())
+		p.writeAPIError(w, &APIErrorResponse{Message: "failed to verify the GitHub account", StatusCode: http.StatusInternalServerError})
+		return err
+	}
+
+	method, err := p.verifyGitHubIdentity(ctx, githubClient, login, user.Email)
+	if errors.Is(err, errIdentityMismatch) {
+		c.Log.Warnf("Rejecting GitHub account %s: no verified email or SAML identity matches the Mattermost user", login)
+		auditRec.AddErrorDesc(err.Error())
+		message := fmt.Sprintf("The GitHub account %s doesn't have a verified email address matching your Mattermost email address %s. "+
+			"Verify that address on GitHub, or connect the GitHub account that uses it.", login, user.Email)
+		p.writeAPIError(w, &APIErrorResponse{Message: message, StatusCode: http.StatusForbidden})
+		return err
+	}
+	if err != nil {
+		c.Log.WithError(err).Errorf("Failed to verify the GitHub account")
+		auditRec.AddErrorDesc(err.Error())
+		p.writeAPIError(w, &APIErrorResponse{Message: "failed to verify the GitHub account", StatusCode: http.StatusInternalServerError})
+		return err
+	}
+
+	auditRec.Success()
+	auditRec.AddEventResultState(VerifyGitHubIdentityAuditResult{MatchedBy: method})
+
+	return nil
+}
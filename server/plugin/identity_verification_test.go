@@ -0,0 +1,100 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v54/github"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyGitHubIdentity(t *testing.T) {
+	for name, tc := range map[string]struct {
+		email          string
+		status         int
+		response       string
+		expectedMethod string
+		expectedErr    error
+	}{
+		"verified email matches": {
+			email:          "Alice@Example.com",
+			status:         http.StatusOK,
+			response:       `[{"email":"alice@personal.com","verified":true},{"email":"alice@example.com","verified":true}]`,
+			expectedMethod: identityMatchVerifiedEmail,
+		},
+		"unverified email doesn't match": {
+			email:       "alice@example.com",
+			status:      http.StatusOK,
+			response:    `[{"email":"alice@example.com","verified":false}]`,
+			expectedErr: errIdentityMismatch,
+		},
+		"no matching email": {
+			email:       "alice@example.com",
+			status:      http.StatusOK,
+			response:    `[{"email":"mallory@example.com","verified":true}]`,
+			expectedErr: errIdentityMismatch,
+		},
+		"user without an email": {
+			email:       "",
+			status:      http.StatusOK,
+			response:    `[{"email":"alice@example.com","verified":true}]`,
+			expectedErr: errIdentityMismatch,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+			p := getPluginTest(mockAPI, mockKvStore)
+
+			githubClient := newTestEmailsClient(t, tc.status, tc.response)
+
+			method, err := p.verifyGitHubIdentity(context.Background(), githubClient, "alice", tc.email)
+			if tc.expectedErr != nil {
+				assert.True(t, errors.Is(err, tc.expectedErr))
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedMethod, method)
+		})
+	}
+
+	t.Run("failing to list emails isn't a mismatch", func(t *testing.T) {
+		mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKvStore)
+		mockAPI.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+		githubClient := newTestEmailsClient(t, http.StatusInternalServerError, `{"message":"Server Error"}`)
+
+		_, err := p.verifyGitHubIdentity(context.Background(), githubClient, "alice", "alice@example.com")
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, errIdentityMismatch))
+	})
+}
+
+func newTestEmailsClient(t *testing.T, status int, response string) *github.Client {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	base, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = base
+
+	return client
+}
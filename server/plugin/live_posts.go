@@ -0,0 +1,209 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v54/github"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+)
+
+const (
+	// renderStyleLive keeps a single post per pull request up to date and threads its events as replies.
+	renderStyleLive = "live"
+
+	livePRPostKeyPrefix = "live_pr_post_v1_"
+	// livePRPostTTL is how long after its last event a pull request keeps its live post.
+	livePRPostTTL = 90 * 24 * time.Hour
+)
+
+// livePRPost is the root post a channel with a live subscription keeps up to date for a pull request.
+type livePRPost struct {
+	PostID  string         `json:"post_id"`
+	Reviews []livePRReview `json:"reviews,omitempty"`
+}
+
+// livePRReview is the state of the latest review of a reviewer.
+type livePRReview struct {
+	Login   string `json:"login"`
+	HTMLURL string `json:"html_url"`
+	State   string `json:"state"`
+}
+
+// LivePullRequest is rendered by the livePR template.
+type LivePullRequest struct {
+	Repo        *github.Repository
+	PullRequest *github.PullRequest
+	Status      string
+	Reviewers   []LivePullRequestReviewer
+}
+
+// LivePullRequestReviewer is a reviewer listed on a live pull request post.
+type LivePullRequestReviewer struct {
+	User  *github.User
+	State string
+}
+
+func livePRPostKey(channelID, repo string, number int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%s#%d", channelID, strings.ToLower(repo), number)))
+	return livePRPostKeyPrefix + hex.EncodeToString(sum[:16])
+}
+
+func getLivePRStatus(pr *github.PullRequest) string {
+	switch {
+	case pr.GetMerged():
+		return "Merged"
+	case pr.GetState() == "closed":
+		return "Closed"
+	case pr.GetDraft():
+		return "Draft"
+	default:
+		return "Open"
+	}
+}
+
+// getLivePRReviewers lists the reviewers who reviewed the pull request, followed by the ones whose
+// review is still requested.
+func getLivePRReviewers(pr *github.PullRequest, reviews []livePRReview) []LivePullRequestReviewer {
+	states := map[string]string{
+		"approved":          "approved",
+		"changes_requested": "requested changes",
+		"commented":         "commented",
+	}
+
+	reviewers := []LivePullRequestReviewer{}
+	reviewed := map[string]bool{}
+	for _, review := range reviews {
+		reviewers = append(reviewers, LivePullRequestReviewer{
+			User:  &github.User{Login: github.String(review.Login), HTMLURL: github.String(review.HTMLURL)},
+			State: states[review.State],
+		})
+		reviewed[strings.ToLower(review.Login)] = true
+	}
+	for _, user := range pr.RequestedReviewers {
+		if !reviewed[strings.ToLower(user.GetLogin())] {
+			reviewers = append(reviewers, LivePullRequestReviewer{User: user, State: "review requested"})
+		}
+	}
+
+	return reviewers
+}
+
+// recordReview replaces the previous review of the reviewer with the given one.
+func (l *livePRPost) recordReview(review *github.PullRequestReview) {
+	login := review.GetUser().GetLogin()
+	reviews := []livePRReview{}
+	for _, r := range l.Reviews {
+		if !strings.EqualFold(r.Login, login) {
+			reviews = append(reviews, r)
+		}
+	}
+	reviews = append(reviews, livePRReview{Login: login, HTMLURL: review.GetUser().GetHTMLURL(), State: review.GetState()})
+	sort.Slice(reviews, func(i, j int) bool {
+		return strings.ToLower(reviews[i].Login) < strings.ToLower(reviews[j].Login)
+	})
+	l.Reviews = reviews
+}
+
+// getLivePRPostID returns the live post of the pull request in the channel, or an empty string
+// when there is none.
+func (p *Plugin) getLivePRPostID(channelID, repo string, number int) string {
+	var live livePRPost
+	if err := p.store.Get(livePRPostKey(channelID, repo, number), &live); err != nil {
+		p.client.Log.Warn("Failed to get live pull request post", "repo", repo, "number", number, "error", err.Error())
+		return ""
+	}
+	return live.PostID
+}
+
+// postLivePREvent refreshes the live post of the pull request in the channel, creating it when
+// needed, and threads reply under it. reply may be nil for events that only change the post.
+func (p *Plugin) postLivePREvent(channelID string, repo *github.Repository, pr *github.PullRequest, review *github.PullRequestReview, reply *model.Post) error {
+	repoName := strings.ToLower(repo.GetFullName())
+	key := livePRPostKey(channelID, repoName, pr.GetNumber())
+
+	// Events of the same pull request can be delivered concurrently to different nodes.
+	m, err := cluster.NewMutex(p.API, key)
+	if err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+
+	var live livePRPost
+	if err = p.store.Get(key, &live); err != nil {
+		return err
+	}
+	if review != nil {
+		live.recordReview(review)
+	}
+
+	message, err := renderTemplate("livePR", &LivePullRequest{
+		Repo:        repo,
+		PullRequest: pr,
+		Status:      getLivePRStatus(pr),
+		Reviewers:   getLivePRReviewers(pr, live.Reviews),
+	})
+	if err != nil {
+		return err
+	}
+
+	var root *model.Post
+	if live.PostID != "" {
+		if root, err = p.client.Post.GetPost(live.PostID); err != nil {
+			// The post was deleted, so a new one is started.
+			p.client.Log.Debug("Failed to get live pull request post", "post_id", live.PostID, "error", err.Error())
+			root = nil
+		}
+	}
+
+	if root != nil {
+		root.Message = message
+		p.setLivePRActions(root, repo, pr)
+		if err = p.client.Post.UpdatePost(root); err != nil {
+			return err
+		}
+	} else {
+		root = p.makeBotPost(message, "custom_git_pr")
+		root.ChannelId = channelID
+		root.AddProp(postPropGithubRepo, repoName)
+		root.AddProp(postPropGithubObjectID, pr.GetNumber())
+		root.AddProp(postPropGithubObjectType, githubObjectTypeIssue)
+		p.setLivePRActions(root, repo, pr)
+		if err = p.client.Post.CreatePost(root); err != nil {
+			return err
+		}
+		p.trackReactionSyncPost(repoName, githubObjectTypeIssue, int64(pr.GetNumber()), root.Id)
+		live.PostID = root.Id
+	}
+
+	if _, err = p.store.Set(key, live, pluginapi.SetExpiry(livePRPostTTL)); err != nil {
+		return err
+	}
+
+	if reply == nil {
+		return nil
+	}
+	reply.ChannelId = channelID
+	reply.RootId = root.Id
+	return p.client.Post.CreatePost(reply)
+}
+
+// setLivePRActions shows the pull request action buttons on the live post while it is open.
+func (p *Plugin) setLivePRActions(post *model.Post, repo *github.Repository, pr *github.PullRequest) {
+	post.DelProp(model.PostPropsAttachments)
+	if p.getConfiguration().EnablePullRequestActions && pr.GetState() == "open" {
+		model.ParseSlackAttachment(post, []*model.MessageAttachment{
+			p.makePRActionsAttachment(repo.GetOwner().GetLogin(), repo.GetName(), pr.GetNumber(), pr.GetDraft()),
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-github/v54/github"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLivePRPostKey(t *testing.T) {
+	key := livePRPostKey("channelID", "Mattermost/Server", 12)
+
+	assert.True(t, strings.HasPrefix(key, livePRPostKeyPrefix))
+	assert.Equal(t, key, livePRPostKey("channelID", "mattermost/server", 12))
+	assert.NotEqual(t, key, livePRPostKey("channelID", "mattermost/server", 13))
+	assert.NotEqual(t, key, livePRPostKey("otherChannelID", "mattermost/server", 12))
+}
+
+func TestGetLivePRStatus(t *testing.T) {
+	assert.Equal(t, "Open", getLivePRStatus(&github.PullRequest{State: github.String("open")}))
+	assert.Equal(t, "Draft", getLivePRStatus(&github.PullRequest{State: github.String("open"), Draft: github.Bool(true)}))
+	assert.Equal(t, "Closed", getLivePRStatus(&github.PullRequest{State: github.String("closed")}))
+	assert.Equal(t, "Merged", getLivePRStatus(&github.PullRequest{State: github.String("closed"), Merged: github.Bool(true)}))
+}
+
+func TestGetLivePRReviewers(t *testing.T) {
+	var live livePRPost
+	live.recordReview(&github.PullRequestReview{User: &github.User{Login: github.String("bob")}, State: github.String("commented")})
+	live.recordReview(&github.PullRequestReview{User: &github.User{Login: github.String("alice")}, State: github.String("changes_requested")})
+	live.recordReview(&github.PullRequestReview{User: &github.User{Login: github.String("Bob")}, State: github.String("approved")})
+
+	pr := &github.PullRequest{RequestedReviewers: []*github.User{
+		{Login: github.String("alice")},
+		{Login: github.String("carol")},
+	}}
+
+	var got []string
+	for _, reviewer := range getLivePRReviewers(pr, live.Reviews) {
+		got = append(got, reviewer.User.GetLogin()+" "+reviewer.State)
+	}
+	assert.Equal(t, []string{"alice requested changes", "Bob approved", "carol review requested"}, got)
+}
+
+func TestRenderLivePR(t *testing.T) {
+	pr := &github.PullRequest{
+		Number:  github.Int(42),
+		Title:   github.String("Fix the build"),
+		HTMLURL: github.String("https://github.com/mattermost/mattermost/pull/42"),
+		User:    &github.User{Login: github.String("alice"), HTMLURL: github.String("https://github.com/alice")},
+		Labels:  []*github.Label{{Name: github.String("bug")}},
+	}
+	repo := &github.Repository{FullName: github.String("mattermost/mattermost"), HTMLURL: github.String("https://github.com/mattermost/mattermost")}
+
+	expected := `
+#### Fix the build
+##### [mattermost/mattermost#42](https://github.com/mattermost/mattermost/pull/42)
+**Merged** pull request by [alice](https://github.com/alice)
+Labels: [` + "`bug`" + `](https://github.com/mattermost/mattermost/labels/bug)
+Reviewers: [bob](https://github.com/bob) (approved)
+`
+
+	actual, err := renderTemplate("livePR", &LivePullRequest{
+		Repo:        repo,
+		PullRequest: pr,
+		Status:      "Merged",
+		Reviewers: []LivePullRequestReviewer{
+			{User: &github.User{Login: github.String("bob"), HTMLURL: github.String("https://github.com/bob")}, State: "approved"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestPostLivePREvent(t *testing.T) {
+	repo := &github.Repository{
+		Name:     github.String(MockRepo),
+		FullName: github.String(MockOrgRepo),
+		Owner:    &github.User{Login: github.String(MockOrg)},
+	}
+	pr := &github.PullRequest{Number: github.Int(12), State: github.String("open"), Title: github.String("Fix the build")}
+	key := livePRPostKey("channelID", MockOrgRepo, 12)
+
+	t.Run("creates the live post", func(t *testing.T) {
+		mockKVStore, mockAPI, _, _, _ := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKVStore)
+
+		mockAPI.On("KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+		mockKVStore.EXPECT().Get(key, gomock.Any()).Return(nil).Times(1)
+		mockAPI.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.RootId == "" && strings.Contains(post.Message, "Fix the build")
+		})).Return(&model.Post{Id: "rootID"}, nil).Once()
+		mockKVStore.EXPECT().Set(key, livePRPost{PostID: "rootID"}, gomock.Any()).Return(true, nil).Times(1)
+
+		require.NoError(t, p.postLivePREvent("channelID", repo, pr, nil, nil))
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("updates the live post and threads the event", func(t *testing.T) {
+		mockKVStore, mockAPI, _, _, _ := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKVStore)
+		review := &github.PullRequestReview{User: &github.User{Login: github.String("bob")}, State: github.String("approved")}
+
+		mockAPI.On("KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+		mockKVStore.EXPECT().Get(key, gomock.Any()).DoAndReturn(func(key string, value any) error {
+			*value.(*livePRPost) = livePRPost{PostID: "rootID"}
+			return nil
+		}).Times(1)
+		mockAPI.On("GetPost", "rootID").Return(&model.Post{Id: "rootID", Message: "outdated"}, nil).Once()
+		mockAPI.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.Id == "rootID" && strings.Contains(post.Message, "(approved)")
+		})).Return(&model.Post{Id: "rootID"}, nil).Once()
+		mockKVStore.EXPECT().Set(key, livePRPost{PostID: "rootID", Reviews: []livePRReview{{Login: "bob", State: "approved"}}}, gomock.Any()).Return(true, nil).Times(1)
+		mockAPI.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.RootId == "rootID" && post.ChannelId == "channelID" && post.Message == "review submitted"
+		})).Return(&model.Post{Id: "replyID"}, nil).Once()
+
+		require.NoError(t, p.postLivePREvent("channelID", repo, pr, review, p.makeBotPost("review submitted", "custom_git_pull_review")))
+		mockAPI.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/pkg/errors"
+)
+
+const (
+	muteRulesKey = "-mute-rules"
+
+	muteScopeRepo    = "repo"
+	muteScopeOrg     = "org"
+	muteScopeKeyword = "keyword"
+
+	flagMuteFor = "for"
+)
+
+// MuteRule mutes DM notifications for a repository, an organization or any
+// issue and pull request whose title contains a keyword.
+type MuteRule struct {
+	Scope string `json:"scope"`
+	Value string `json:"value"`
+	// ExpiresAt is the Unix time in milliseconds at which the rule stops applying. Zero means never.
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+}
+
+func (r MuteRule) isExpired(now time.Time) bool {
+	return r.ExpiresAt != 0 && now.UnixMilli() >= r.ExpiresAt
+}
+
+func (r MuteRule) matches(repoFullName, title string) bool {
+	switch r.Scope {
+	case muteScopeRepo:
+		return repoFullName != "" && strings.EqualFold(r.Value, repoFullName)
+	case muteScopeOrg:
+		owner, _, _ := strings.Cut(repoFullName, "/")
+		return owner != "" && strings.EqualFold(r.Value, owner)
+	case muteScopeKeyword:
+		return title != "" && strings.Contains(strings.ToLower(title), strings.ToLower(r.Value))
+	default:
+		return false
+	}
+}
+
+func (r MuteRule) String() string {
+	text := fmt.Sprintf("%s `%s`", r.Scope, r.Value)
+	if r.ExpiresAt != 0 {
+		text += fmt.Sprintf(" (until %s)", time.UnixMilli(r.ExpiresAt).UTC().Format(time.RFC1123))
+	}
+	return text
+}
+
+func isValidMuteScope(scope string) bool {
+	return scope == muteScopeRepo || scope == muteScopeOrg || scope == muteScopeKeyword
+}
+
+// parseMuteDuration parses durations such as "30m", "12h", "7d" or "2w".
+func parseMuteDuration(s string) (time.Duration, error) {
+	if len(s) < 2 {
+		return 0, errors.Errorf("invalid duration %q", s)
+	}
+
+	unit := s[len(s)-1]
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, errors.Errorf("invalid duration %q", s)
+	}
+
+	switch unit {
+	case 'm':
+		return time.Duration(n) * time.Minute, nil
+	case 'h':
+		return time.Duration(n) * time.Hour, nil
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, nil
+	case 'w':
+		return time.Duration(n) * 7 * 24 * time.Hour, nil
+	default:
+		return 0, errors.Errorf("invalid duration %q", s)
+	}
+}
+
+func (p *Plugin) getMuteRules(userID string) ([]MuteRule, error) {
+	var rules []MuteRule
+	if err := p.store.Get(userID+muteRulesKey, &rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// storeMuteRules persists the given rules, dropping the ones that have already expired.
+func (p *Plugin) storeMuteRules(userID string, rules []MuteRule) error {
+	now := time.Now()
+	active := []MuteRule{}
+	for _, rule := range rules {
+		if !rule.isExpired(now) {
+			active = append(active, rule)
+		}
+	}
+
+	if len(active) == 0 {
+		return p.store.Delete(userID + muteRulesKey)
+	}
+
+	_, err := p.store.Set(userID+muteRulesKey, active)
+	return err
+}
+
+// notificationMutedByReceiver reports whether the receiver muted the sender, or the
+// repository, organization or title the notification is about.
+func (p *Plugin) notificationMutedByReceiver(userID, sender, repoFullName, title string) bool {
+	if p.senderMutedByReceiver(userID, sender) {
+		return true
+	}
+
+	rules, err := p.getMuteRules(userID)
+	if err != nil {
+		p.client.Log.Warn("Failed to get mute rules", "userID", userID, "error", err.Error())
+		return false
+	}
+
+	now := time.Now()
+	for _, rule := range rules {
+		if rule.isExpired(now) {
+			continue
+		}
+		if rule.matches(repoFullName, title) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Plugin) handleMuteRuleAdd(scope string, parameters []string, userInfo *GitHubUserInfo) string {
+	if len(parameters) == 0 {
+		return fmt.Sprintf("Please specify the %s to mute.", scope)
+	}
+
+	value := strings.Trim(parameters[0], `"`)
+	parameters = parameters[1:]
+
+	var expiresAt int64
+	if len(parameters) > 0 {
+		if len(parameters) != 2 || parseFlag(parameters[0]) != flagMuteFor {
+			return "Please use the correct format for flags: --for <duration>"
+		}
+		d, err := parseMuteDuration(parameters[1])
+		if err != nil {
+			return "Invalid duration. Use a number followed by `m`, `h`, `d` or `w`, for example `--for 7d`."
+		}
+		expiresAt = time.Now().Add(d).UnixMilli()
+	}
+
+	switch scope {
+	case muteScopeRepo:
+		owner, repo := parseOwnerAndRepo(value, p.getConfiguration().getBaseURL())
+		if owner == "" || repo == "" {
+			return "Please provide a valid repository"
+		}
+		value = fullNameFromOwnerAndRepo(owner, repo)
+	case muteScopeOrg:
+		owner, repo := parseOwnerAndRepo(value, p.getConfiguration().getBaseURL())
+		if owner == "" || repo != "" {
+			return "Please provide a valid organization"
+		}
+		value = owner
+	}
+
+	if value == "" {
+		return fmt.Sprintf("Please specify the %s to mute.", scope)
+	}
+
+	rules, err := p.getMuteRules(userInfo.UserID)
+	if err != nil {
+		p.client.Log.Error("error occurred getting mute rules.", "UserID", userInfo.UserID, "Error", err)
+		return "An error occurred getting your mute rules. Please try again later"
+	}
+
+	rule := MuteRule{Scope: scope, Value: value, ExpiresAt: expiresAt}
+	updated := false
+	for i, existing := range rules {
+		if existing.Scope == scope && strings.EqualFold(existing.Value, value) {
+			rules[i] = rule
+			updated = true
+			break
+		}
+	}
+	if !updated {
+		rules = append(rules, rule)
+	}
+
+	auditRec := plugin.MakeAuditRecord("updateMutes", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = userInfo.UserID
+	model.AddEventParameterAuditableToAuditRec(auditRec, "mute", UpdateMutesAuditParams{
+		Action: "mute",
+		Scope:  scope,
+		Value:  value,
+	})
+
+	if err := p.storeMuteRules(userInfo.UserID, rules); err != nil {
+		auditRec.AddErrorDesc(err.Error())
+		return "Error occurred saving your mute rules"
+	}
+	auditRec.Success()
+
+	return fmt.Sprintf("Muted %s. You'll no longer receive notifications about it.", rule.String())
+}
+
+func (p *Plugin) handleMuteRuleDelete(scope, value string, userInfo *GitHubUserInfo) string {
+	rules, err := p.getMuteRules(userInfo.UserID)
+	if err != nil {
+		p.client.Log.Error("error occurred getting mute rules.", "UserID", userInfo.UserID, "Error", err)
+		return "An error occurred getting your mute rules. Please try again later"
+	}
+
+	value = strings.Trim(value, `"`)
+	if scope == muteScopeRepo || scope == muteScopeOrg {
+		value = strings.TrimSuffix(strings.Replace(value, p.getConfiguration().getBaseURL(), "", 1), "/")
+	}
+
+	remaining := []MuteRule{}
+	removed := false
+	for _, rule := range rules {
+		if rule.Scope == scope && strings.EqualFold(rule.Value, value) {
+			removed = true
+			continue
+		}
+		remaining = append(remaining, rule)
+	}
+
+	if !removed {
+		return fmt.Sprintf("%s `%s` is not muted", scope, value)
+	}
+
+	auditRec := plugin.MakeAuditRecord("updateMutes", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = userInfo.UserID
+	model.AddEventParameterAuditableToAuditRec(auditRec, "mute", UpdateMutesAuditParams{
+		Action: "unmute",
+		Scope:  scope,
+		Value:  value,
+	})
+
+	if err := p.storeMuteRules(userInfo.UserID, remaining); err != nil {
+		auditRec.AddErrorDesc(err.Error())
+		return "Error occurred saving your mute rules"
+	}
+	auditRec.Success()
+
+	return fmt.Sprintf("%s `%s` is no longer muted", scope, value)
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMuteDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{input: "30m", expected: 30 * time.Minute},
+		{input: "12h", expected: 12 * time.Hour},
+		{input: "7d", expected: 7 * 24 * time.Hour},
+		{input: "2w", expected: 14 * 24 * time.Hour},
+		{input: "d", wantErr: true},
+		{input: "0d", wantErr: true},
+		{input: "-1d", wantErr: true},
+		{input: "7y", wantErr: true},
+		{input: "soon", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			d, err := parseMuteDuration(tc.input)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, d)
+		})
+	}
+}
+
+func TestMuteRuleMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     MuteRule
+		repo     string
+		title    string
+		expected bool
+	}{
+		{name: "repo matches case-insensitively", rule: MuteRule{Scope: muteScopeRepo, Value: "mattermost/docs"}, repo: "Mattermost/Docs", expected: true},
+		{name: "repo does not match another repo", rule: MuteRule{Scope: muteScopeRepo, Value: "mattermost/docs"}, repo: "mattermost/server", expected: false},
+		{name: "org matches any repo of the org", rule: MuteRule{Scope: muteScopeOrg, Value: "mattermost"}, repo: "mattermost/server", expected: true},
+		{name: "org does not match repo prefix", rule: MuteRule{Scope: muteScopeOrg, Value: "matter"}, repo: "mattermost/server", expected: false},
+		{name: "keyword matches title", rule: MuteRule{Scope: muteScopeKeyword, Value: "flaky"}, title: "Fix FLAKY test", expected: true},
+		{name: "keyword does not match empty title", rule: MuteRule{Scope: muteScopeKeyword, Value: "flaky"}, expected: false},
+		{name: "unknown scope never matches", rule: MuteRule{Scope: "label", Value: "bug"}, repo: "mattermost/server", title: "bug", expected: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.rule.matches(tc.repo, tc.title))
+		})
+	}
+}
+
+func TestNotificationMutedByReceiver(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []MuteRule
+		expected bool
+	}{
+		{
+			name:     "No mute rules",
+			expected: false,
+		},
+		{
+			name:     "Repository is muted",
+			rules:    []MuteRule{{Scope: muteScopeRepo, Value: MockOrgRepo}},
+			expected: true,
+		},
+		{
+			name:     "Expired rule is ignored",
+			rules:    []MuteRule{{Scope: muteScopeOrg, Value: MockOrg, ExpiresAt: time.Now().Add(-time.Hour).UnixMilli()}},
+			expected: false,
+		},
+		{
+			name:     "Rule that has not expired yet applies",
+			rules:    []MuteRule{{Scope: muteScopeKeyword, Value: "release", ExpiresAt: time.Now().Add(time.Hour).UnixMilli()}},
+			expected: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockKVStore, mockAPI, _, _, _ := GetTestSetup(t)
+			p := getPluginTest(mockAPI, mockKVStore)
+
+			mockKVStore.EXPECT().Get("user1-muted-users", mock.MatchedBy(func(val any) bool {
+				_, ok := val.(*[]uint8)
+				return ok
+			})).Return(nil).Times(1)
+			mockKVStore.EXPECT().Get("user1-mute-rules", mock.MatchedBy(func(val any) bool {
+				_, ok := val.(*[]MuteRule)
+				return ok
+			})).DoAndReturn(func(key string, value any) error {
+				*value.(*[]MuteRule) = tc.rules
+				return nil
+			}).Times(1)
+
+			muted := p.notificationMutedByReceiver("user1", MockSender, MockOrgRepo, "Prepare release 1.2")
+
+			assert.Equal(t, tc.expected, muted)
+		})
+	}
+}
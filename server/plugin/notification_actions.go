@@ -0,0 +1,578 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v54/github"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"golang.org/x/oauth2"
+)
+
+const (
+	notificationActionApprove        = "approve"
+	notificationActionRequestChanges = "request_changes"
+	notificationActionMerge          = "merge"
+	notificationActionClose          = "close"
+	notificationActionReopen         = "reopen"
+	notificationActionAddLabel       = "add_label"
+	notificationActionAssignMe       = "assign_me"
+	notificationActionAssign         = "assign"
+	notificationActionSetMilestone   = "set_milestone"
+
+	notificationActionDialogFieldBody      = "body"
+	notificationActionDialogFieldLabel     = "label"
+	notificationActionDialogFieldAssignee  = "assignee"
+	notificationActionDialogFieldMilestone = "milestone"
+	notificationActionDialogFieldMethod    = "merge_method"
+)
+
+// errNotificationActionNotAllowed is returned when the user lacks the repository permission an action needs.
+var errNotificationActionNotAllowed = errors.New("not allowed")
+
+// notificationActionState identifies the issue or pull request a button or dialog acts on. It is
+// stored in the button context and in the dialog state.
+type notificationActionState struct {
+	Action string `json:"action"`
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Number int    `json:"number"`
+	// IsPR is true for the buttons of pull request notifications.
+	IsPR bool `json:"is_pr"`
+}
+
+func (s notificationActionState) isValid() bool {
+	return s.Owner != "" && s.Repo != "" && s.Number > 0
+}
+
+func (s notificationActionState) link() string {
+	return fmt.Sprintf("%s/%s#%d", s.Owner, s.Repo, s.Number)
+}
+
+// hasDialog reports whether the action asks for input before running.
+func (s notificationActionState) hasDialog() bool {
+	switch s.Action {
+	case notificationActionRequestChanges, notificationActionMerge, notificationActionAddLabel, notificationActionAssign, notificationActionSetMilestone:
+		return true
+	}
+	return false
+}
+
+func (p *Plugin) getNotificationActionURL() string {
+	return fmt.Sprintf("/plugins/%s/api/v1/notification-action", Manifest.Id)
+}
+
+func (p *Plugin) getNotificationActionDialogURL() string {
+	return fmt.Sprintf("/plugins/%s/api/v1/notification-action/dialog", Manifest.Id)
+}
+
+// isNotificationActionEnabled reports whether the buttons of the notification kind are enabled.
+func (p *Plugin) isNotificationActionEnabled(isPR bool) bool {
+	config := p.getConfiguration()
+	if isPR {
+		return config.EnablePullRequestActions
+	}
+	return config.EnableIssueActions
+}
+
+func (p *Plugin) makeNotificationAction(state notificationActionState, id, name, style string) *model.PostAction {
+	return &model.PostAction{
+		Id:    id,
+		Name:  name,
+		Type:  model.PostActionTypeButton,
+		Style: style,
+		Integration: &model.PostActionIntegration{
+			URL: p.getNotificationActionURL(),
+			Context: map[string]any{
+				"action": state.Action,
+				"owner":  state.Owner,
+				"repo":   state.Repo,
+				"number": state.Number,
+				"is_pr":  state.IsPR,
+			},
+		},
+	}
+}
+
+// makePRActionsAttachment returns the buttons shown under a pull request notification. Reviewing
+// and merging aren't offered for draft pull requests.
+func (p *Plugin) makePRActionsAttachment(owner, repo string, number int, isDraft bool) *model.MessageAttachment {
+	makeAction := func(id, name, action, style string) *model.PostAction {
+		return p.makeNotificationAction(notificationActionState{Action: action, Owner: owner, Repo: repo, Number: number, IsPR: true}, id, name, style)
+	}
+
+	var actions []*model.PostAction
+	if !isDraft {
+		actions = append(actions,
+			makeAction("approve", "Approve", notificationActionApprove, "good"),
+			makeAction("requestchanges", "Request changes", notificationActionRequestChanges, "default"),
+		)
+	}
+	actions = append(actions,
+		makeAction("addlabel", "Add label", notificationActionAddLabel, "default"),
+		makeAction("assignme", "Assign me", notificationActionAssignMe, "default"),
+	)
+	if !isDraft {
+		actions = append(actions, makeAction("merge", "Merge", notificationActionMerge, "primary"))
+	}
+
+	return &model.MessageAttachment{Actions: actions}
+}
+
+// makeIssueActionsAttachment returns the triage buttons shown under an issue notification.
+func (p *Plugin) makeIssueActionsAttachment(owner, repo string, number int, isClosed bool) *model.MessageAttachment {
+	makeAction := func(id, name, action, style string) *model.PostAction {
+		return p.makeNotificationAction(notificationActionState{Action: action, Owner: owner, Repo: repo, Number: number}, id, name, style)
+	}
+
+	stateAction := makeAction("close", "Close", notificationActionClose, "danger")
+	if isClosed {
+		stateAction = makeAction("reopen", "Reopen", notificationActionReopen, "default")
+	}
+
+	return &model.MessageAttachment{
+		Actions: []*model.PostAction{
+			stateAction,
+			makeAction("assignme", "Assign me", notificationActionAssignMe, "default"),
+			makeAction("assign", "Assign", notificationActionAssign, "default"),
+			makeAction("addlabel", "Add label", notificationActionAddLabel, "default"),
+			makeAction("setmilestone", "Set milestone", notificationActionSetMilestone, "default"),
+		},
+	}
+}
+
+func (p *Plugin) handleNotificationAction(c *Context, w http.ResponseWriter, r *http.Request) {
+	p.serveNotificationAction(c, w, r, false)
+}
+
+// handleLegacyPRAction serves the buttons of pull request notifications posted before issue
+// notifications got buttons too. They were sent to the /pr-action route and don't carry is_pr.
+func (p *Plugin) handleLegacyPRAction(c *Context, w http.ResponseWriter, r *http.Request) {
+	p.serveNotificationAction(c, w, r, true)
+}
+
+func (p *Plugin) serveNotificationAction(c *Context, w http.ResponseWriter, r *http.Request, isLegacyPR bool) {
+	var request model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		c.Log.WithError(err).Warnf("Error decoding PostActionIntegrationRequest from JSON body")
+		p.writeAPIError(w, &APIErrorResponse{Message: "invalid request body", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	var state notificationActionState
+	state.Action, _ = request.Context["action"].(string)
+	state.Owner, _ = request.Context["owner"].(string)
+	state.Repo, _ = request.Context["repo"].(string)
+	number, _ := request.Context["number"].(float64)
+	state.Number = int(number)
+	state.IsPR, _ = request.Context["is_pr"].(bool)
+	state.IsPR = state.IsPR || isLegacyPR
+	if !state.isValid() {
+		p.writeAPIError(w, &APIErrorResponse{Message: "invalid request context", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	respond := func(text string) {
+		p.writeJSON(w, &model.PostActionIntegrationResponse{EphemeralText: text})
+	}
+
+	if !p.isNotificationActionEnabled(state.IsPR) {
+		respond("These actions are disabled.")
+		return
+	}
+
+	info, apiErr := p.getGitHubUserInfo(c.UserID)
+	if apiErr != nil {
+		if apiErr.ID == apiErrorIDNotConnected {
+			respond("You must connect your GitHub account to use this action. Run `/github connect` to connect it.")
+			return
+		}
+		c.Log.WithError(apiErr).Warnf("Failed to get GitHub user info")
+		respond("Something went wrong. Please try again later.")
+		return
+	}
+
+	switch {
+	case state.hasDialog():
+		dialog, err := p.makeNotificationActionDialog(c.Ctx, info, state)
+		if err != nil {
+			c.Log.WithError(err).Warnf("Failed to prepare notification action dialog")
+			respond(getNotificationActionErrorText(err))
+			return
+		}
+		if err = p.client.Frontend.OpenInteractiveDialog(model.OpenDialogRequest{
+			TriggerId: request.TriggerId,
+			URL:       p.getNotificationActionDialogURL(),
+			Dialog:    *dialog,
+		}); err != nil {
+			c.Log.WithError(err).Warnf("Failed to open notification action dialog")
+			respond("Failed to open the dialog.")
+			return
+		}
+		p.writeJSON(w, &model.PostActionIntegrationResponse{})
+	default:
+		text, err := p.runNotificationAction(c.Ctx, info, state, nil)
+		if err != nil {
+			c.Log.WithError(err).Warnf("Failed to run notification action %q", state.Action)
+			respond(getNotificationActionErrorText(err))
+			return
+		}
+		respond(text)
+	}
+}
+
+// makeNotificationActionDialog returns the dialog asking for the input of actions that need one.
+// The pickers list the same labels, assignees and milestones as the issue creation modal.
+func (p *Plugin) makeNotificationActionDialog(ctx context.Context, info *GitHubUserInfo, state notificationActionState) (*model.Dialog, error) {
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	dialog := &model.Dialog{
+		CallbackId: state.Action,
+		State:      string(stateJSON),
+	}
+
+	var options []*model.PostActionOptions
+	switch state.Action {
+	case notificationActionRequestChanges:
+		dialog.Title = fmt.Sprintf("Request changes on #%d", state.Number)
+		dialog.SubmitLabel = "Request changes"
+		dialog.Elements = []model.DialogElement{{
+			DisplayName: "Comment",
+			Name:        notificationActionDialogFieldBody,
+			Type:        "textarea",
+			Placeholder: "Describe the changes needed",
+			MaxLength:   65536,
+		}}
+		return dialog, nil
+	case notificationActionMerge:
+		githubClient := p.githubConnectUser(ctx, info)
+		var repo *github.Repository
+		if err = p.useGitHubClient(info, func(info *GitHubUserInfo, token *oauth2.Token) error {
+			repo, _, err = githubClient.Repositories.Get(ctx, state.Owner, state.Repo)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		options = getMergeMethodOptions(repo)
+		if len(options) == 0 {
+			return nil, errors.New("the repository allows no merge method")
+		}
+		dialog.Title = fmt.Sprintf("Merge #%d", state.Number)
+		dialog.IntroductionText = fmt.Sprintf("Merge %s into its base branch?", state.link())
+		dialog.SubmitLabel = "Merge"
+		// The methods keep the order GitHub shows them in, and the first one is preselected.
+		dialog.Elements = []model.DialogElement{{
+			DisplayName: "Merge method",
+			Name:        notificationActionDialogFieldMethod,
+			Type:        "select",
+			Options:     options,
+			Default:     options[0].Value,
+		}}
+		return dialog, nil
+	case notificationActionAddLabel:
+		labels, err := p.listRepoLabels(ctx, info, state.Owner, state.Repo)
+		if err != nil {
+			return nil, err
+		}
+		for _, label := range labels {
+			options = append(options, &model.PostActionOptions{Text: label.GetName(), Value: label.GetName()})
+		}
+		dialog.Title = fmt.Sprintf("Add label to #%d", state.Number)
+		dialog.SubmitLabel = "Add"
+		dialog.Elements = []model.DialogElement{{DisplayName: "Label", Name: notificationActionDialogFieldLabel, Type: "select"}}
+	case notificationActionAssign:
+		assignees, err := p.listRepoAssignees(ctx, info, state.Owner, state.Repo)
+		if err != nil {
+			return nil, err
+		}
+		for _, assignee := range assignees {
+			options = append(options, &model.PostActionOptions{Text: assignee.GetLogin(), Value: assignee.GetLogin()})
+		}
+		dialog.Title = fmt.Sprintf("Assign #%d", state.Number)
+		dialog.SubmitLabel = "Assign"
+		dialog.Elements = []model.DialogElement{{DisplayName: "Assignee", Name: notificationActionDialogFieldAssignee, Type: "select"}}
+	case notificationActionSetMilestone:
+		milestones, err := p.listRepoMilestones(ctx, info, state.Owner, state.Repo)
+		if err != nil {
+			return nil, err
+		}
+		for _, milestone := range milestones {
+			options = append(options, &model.PostActionOptions{Text: milestone.GetTitle(), Value: strconv.Itoa(milestone.GetNumber())})
+		}
+		dialog.Title = fmt.Sprintf("Set milestone of #%d", state.Number)
+		dialog.SubmitLabel = "Set"
+		dialog.Elements = []model.DialogElement{{DisplayName: "Milestone", Name: notificationActionDialogFieldMilestone, Type: "select"}}
+	default:
+		return nil, fmt.Errorf("action %q has no dialog", state.Action)
+	}
+
+	if len(options) == 0 {
+		return nil, fmt.Errorf("the repository has no %ss to choose from", dialog.Elements[0].Name)
+	}
+	sort.Slice(options, func(i, j int) bool {
+		return strings.ToLower(options[i].Text) < strings.ToLower(options[j].Text)
+	})
+	dialog.Elements[0].Options = options
+
+	return dialog, nil
+}
+
+func (p *Plugin) handleNotificationActionDialog(c *Context, w http.ResponseWriter, r *http.Request) {
+	p.serveNotificationActionDialog(c, w, r, false)
+}
+
+// handleLegacyPRActionDialog serves the dialogs opened from the buttons of handleLegacyPRAction.
+func (p *Plugin) handleLegacyPRActionDialog(c *Context, w http.ResponseWriter, r *http.Request) {
+	p.serveNotificationActionDialog(c, w, r, true)
+}
+
+func (p *Plugin) serveNotificationActionDialog(c *Context, w http.ResponseWriter, r *http.Request, isLegacyPR bool) {
+	var request model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		c.Log.WithError(err).Warnf("Error decoding SubmitDialogRequest from JSON body")
+		p.writeAPIError(w, &APIErrorResponse{Message: "invalid request body", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if request.UserId != c.UserID {
+		p.writeAPIError(w, &APIErrorResponse{Message: "Not authorized.", StatusCode: http.StatusForbidden})
+		return
+	}
+	if request.Cancelled {
+		p.writeJSON(w, &model.SubmitDialogResponse{})
+		return
+	}
+
+	var state notificationActionState
+	if err := json.Unmarshal([]byte(request.State), &state); err != nil || !state.isValid() {
+		p.writeAPIError(w, &APIErrorResponse{Message: "invalid dialog state", StatusCode: http.StatusBadRequest})
+		return
+	}
+	state.IsPR = state.IsPR || isLegacyPR
+	if !state.hasDialog() {
+		p.writeAPIError(w, &APIErrorResponse{Message: "unknown action", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if !p.isNotificationActionEnabled(state.IsPR) {
+		p.writeJSON(w, &model.SubmitDialogResponse{Error: "These actions are disabled."})
+		return
+	}
+
+	info, apiErr := p.getGitHubUserInfo(c.UserID)
+	if apiErr != nil {
+		p.writeJSON(w, &model.SubmitDialogResponse{Error: apiErr.Message})
+		return
+	}
+
+	text, err := p.runNotificationAction(c.Ctx, info, state, request.Submission)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to run notification action %q", state.Action)
+		p.writeJSON(w, &model.SubmitDialogResponse{Error: getNotificationActionErrorText(err)})
+		return
+	}
+
+	p.client.Post.SendEphemeralPost(c.UserID, &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: request.ChannelId,
+		Message:   text,
+	})
+	p.writeJSON(w, &model.SubmitDialogResponse{})
+}
+
+// runNotificationAction runs the action on GitHub as the clicking user and returns the
+// confirmation shown to them. GitHub enforces the user's permissions on the repository.
+func (p *Plugin) runNotificationAction(ctx context.Context, info *GitHubUserInfo, state notificationActionState, submission map[string]any) (string, error) {
+	auditRec := plugin.MakeAuditRecord("notificationAction", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = info.UserID
+	params := NotificationActionAuditParams{
+		Action:     state.Action,
+		Repository: fullNameFromOwnerAndRepo(state.Owner, state.Repo),
+		Number:     state.Number,
+	}
+
+	githubClient := p.githubConnectUser(ctx, info)
+
+	getSubmitted := func(field string) (string, error) {
+		value, _ := submission[field].(string)
+		if value == "" {
+			return "", fmt.Errorf("no %s selected", field)
+		}
+		params.Value = value
+		return value, nil
+	}
+
+	var text string
+	err := p.useGitHubClient(info, func(info *GitHubUserInfo, token *oauth2.Token) error {
+		switch state.Action {
+		case notificationActionApprove, notificationActionRequestChanges:
+			review := &github.PullRequestReviewRequest{Event: github.String("APPROVE")}
+			text = fmt.Sprintf("Approved %s.", state.link())
+			if state.Action == notificationActionRequestChanges {
+				body, _ := submission[notificationActionDialogFieldBody].(string)
+				review = &github.PullRequestReviewRequest{Event: github.String("REQUEST_CHANGES"), Body: github.String(body)}
+				text = fmt.Sprintf("Requested changes on %s.", state.link())
+			}
+			_, _, err := githubClient.PullRequests.CreateReview(ctx, state.Owner, state.Repo, state.Number, review)
+			return err
+		case notificationActionMerge:
+			method, err := getSubmitted(notificationActionDialogFieldMethod)
+			if err != nil {
+				return err
+			}
+			merged, err := p.mergePR(ctx, githubClient, state, method)
+			if err != nil {
+				return err
+			}
+			text = fmt.Sprintf("Merged %s as %s.", state.link(), merged.GetSHA())
+			return nil
+		case notificationActionClose, notificationActionReopen:
+			issueState, verb := "closed", "Closed"
+			if state.Action == notificationActionReopen {
+				issueState, verb = "open", "Reopened"
+			}
+			text = fmt.Sprintf("%s %s.", verb, state.link())
+			_, _, err := githubClient.Issues.Edit(ctx, state.Owner, state.Repo, state.Number, &github.IssueRequest{State: &issueState})
+			return err
+		case notificationActionAddLabel:
+			label, err := getSubmitted(notificationActionDialogFieldLabel)
+			if err != nil {
+				return err
+			}
+			text = fmt.Sprintf("Added label `%s` to %s.", label, state.link())
+			_, _, err = githubClient.Issues.AddLabelsToIssue(ctx, state.Owner, state.Repo, state.Number, []string{label})
+			return err
+		case notificationActionAssignMe, notificationActionAssign:
+			assignee := info.GitHubUsername
+			text = fmt.Sprintf("Assigned you to %s.", state.link())
+			if state.Action == notificationActionAssign {
+				var err error
+				if assignee, err = getSubmitted(notificationActionDialogFieldAssignee); err != nil {
+					return err
+				}
+				text = fmt.Sprintf("Assigned %s to %s.", assignee, state.link())
+			}
+			_, _, err := githubClient.Issues.AddAssignees(ctx, state.Owner, state.Repo, state.Number, []string{assignee})
+			return err
+		case notificationActionSetMilestone:
+			value, err := getSubmitted(notificationActionDialogFieldMilestone)
+			if err != nil {
+				return err
+			}
+			milestone, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid milestone %q", value)
+			}
+			text = fmt.Sprintf("Set the milestone of %s.", state.link())
+			_, _, err = githubClient.Issues.Edit(ctx, state.Owner, state.Repo, state.Number, &github.IssueRequest{Milestone: &milestone})
+			return err
+		default:
+			return fmt.Errorf("unknown action %q", state.Action)
+		}
+	})
+
+	model.AddEventParameterAuditableToAuditRec(auditRec, "action", params)
+	if err != nil {
+		auditRec.AddErrorDesc(err.Error())
+		return "", err
+	}
+
+	auditRec.Success()
+	return text, nil
+}
+
+// getMergeMethodOptions returns the merge methods the repository allows. Settings GitHub doesn't
+// return to the user are offered, and GitHub rejects the merge if the method isn't allowed.
+func getMergeMethodOptions(repo *github.Repository) []*model.PostActionOptions {
+	var options []*model.PostActionOptions
+	for _, method := range []struct {
+		text, value string
+		allowed     *bool
+	}{
+		{"Create a merge commit", "merge", repo.AllowMergeCommit},
+		{"Squash and merge", "squash", repo.AllowSquashMerge},
+		{"Rebase and merge", "rebase", repo.AllowRebaseMerge},
+	} {
+		if method.allowed == nil || *method.allowed {
+			options = append(options, &model.PostActionOptions{Text: method.text, Value: method.value})
+		}
+	}
+	return options
+}
+
+// mergePR merges the pull request with the merge method when the user can push to the repository
+// and the pull request is open, ready for review and mergeable.
+func (p *Plugin) mergePR(ctx context.Context, githubClient *github.Client, state notificationActionState, method string) (*github.PullRequestMergeResult, error) {
+	repo, _, err := githubClient.Repositories.Get(ctx, state.Owner, state.Repo)
+	if err != nil {
+		return nil, err
+	}
+	if !repo.GetPermissions()["push"] {
+		return nil, errNotificationActionNotAllowed
+	}
+
+	pr, _, err := githubClient.PullRequests.Get(ctx, state.Owner, state.Repo, state.Number)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case pr.GetState() != "open":
+		return nil, errors.New("the pull request is not open")
+	case pr.GetDraft():
+		return nil, errors.New("the pull request is a draft")
+	case pr.Mergeable != nil && !pr.GetMergeable():
+		return nil, errors.New("the pull request can't be merged")
+	}
+
+	// Pinning the head commit keeps the merge from including commits pushed after the check.
+	result, _, err := githubClient.PullRequests.Merge(ctx, state.Owner, state.Repo, state.Number, "", &github.PullRequestOptions{
+		SHA:         pr.GetHead().GetSHA(),
+		MergeMethod: method,
+	})
+	return result, err
+}
+
+// getNotificationActionErrorText returns the message shown to the user when an action fails.
+func getNotificationActionErrorText(err error) string {
+	if errors.Is(err, errNotificationActionNotAllowed) {
+		return "You don't have permission to do this on GitHub."
+	}
+
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) {
+		if errResp.Response != nil {
+			switch errResp.Response.StatusCode {
+			case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+				return "You don't have permission to do this on GitHub."
+			}
+		}
+		if errResp.Message != "" {
+			return fmt.Sprintf("GitHub rejected the action: %s.", strings.TrimSuffix(errResp.Message, "."))
+		}
+		if errResp.Response == nil {
+			// ErrorResponse.Error needs the response.
+			return "The action failed."
+		}
+	}
+
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return "GitHub rate limit exceeded. Please try again later."
+	}
+
+	return fmt.Sprintf("The action failed: %s.", err.Error())
+}
@@ -0,0 +1,336 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-github/v54/github"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestMakeNotificationActionsAttachment(t *testing.T) {
+	p := NewPlugin()
+
+	getNames := func(attachment *model.MessageAttachment) []string {
+		var names []string
+		for _, action := range attachment.Actions {
+			names = append(names, action.Name)
+			assert.Equal(t, p.getNotificationActionURL(), action.Integration.URL)
+			assert.Equal(t, 12, action.Integration.Context["number"])
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"Approve", "Request changes", "Add label", "Assign me", "Merge"}, getNames(p.makePRActionsAttachment(MockOrg, MockRepo, 12, false)))
+	assert.Equal(t, []string{"Add label", "Assign me"}, getNames(p.makePRActionsAttachment(MockOrg, MockRepo, 12, true)))
+	assert.Equal(t, []string{"Close", "Assign me", "Assign", "Add label", "Set milestone"}, getNames(p.makeIssueActionsAttachment(MockOrg, MockRepo, 12, false)))
+	assert.Equal(t, []string{"Reopen", "Assign me", "Assign", "Add label", "Set milestone"}, getNames(p.makeIssueActionsAttachment(MockOrg, MockRepo, 12, true)))
+}
+
+func TestHandleNotificationAction(t *testing.T) {
+	mockKvStore, mockAPI, _, _, mockContext := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKvStore)
+
+	makeBody := func(action string, number int) string {
+		b, err := json.Marshal(model.PostActionIntegrationRequest{
+			PostId: "postID",
+			Context: map[string]any{
+				"action": action,
+				"owner":  MockOrg,
+				"repo":   MockRepo,
+				"number": number,
+				"is_pr":  true,
+			},
+		})
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	tests := []struct {
+		name               string
+		requestBody        string
+		enabled            bool
+		setup              func()
+		expectedStatusCode int
+		expectedText       string
+	}{
+		{
+			name:               "Invalid context",
+			requestBody:        makeBody(notificationActionApprove, 0),
+			setup:              func() {},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Actions disabled",
+			requestBody:        makeBody(notificationActionApprove, 12),
+			setup:              func() {},
+			expectedStatusCode: http.StatusOK,
+			expectedText:       "These actions are disabled.",
+		},
+		{
+			name:        "User not connected",
+			requestBody: makeBody(notificationActionApprove, 12),
+			enabled:     true,
+			setup: func() {
+				mockKvStore.EXPECT().Get(MockUserID+githubTokenKey, mock.MatchedBy(func(val any) bool {
+					_, ok := val.(**GitHubUserInfo)
+					return ok
+				})).Return(nil).Times(1)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedText:       "You must connect your GitHub account",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config := p.getConfiguration().Clone()
+			config.EnablePullRequestActions = tc.enabled
+			p.setConfiguration(config)
+			tc.setup()
+
+			req := httptest.NewRequest(http.MethodPost, "/notification-action", strings.NewReader(tc.requestBody))
+			rec := httptest.NewRecorder()
+
+			p.handleNotificationAction(mockContext, rec, req)
+
+			assert.Equal(t, tc.expectedStatusCode, rec.Result().StatusCode)
+			if tc.expectedText == "" {
+				return
+			}
+
+			var resp model.PostActionIntegrationResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Contains(t, resp.EphemeralText, tc.expectedText)
+		})
+	}
+}
+
+func TestHandleLegacyPRAction(t *testing.T) {
+	mockKvStore, mockAPI, _, _, mockContext := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKvStore)
+	p.setConfiguration(&Configuration{EnablePullRequestActions: true})
+
+	// Buttons posted before the routes were renamed don't carry is_pr.
+	b, err := json.Marshal(model.PostActionIntegrationRequest{
+		PostId: "postID",
+		Context: map[string]any{
+			"action": notificationActionApprove,
+			"owner":  MockOrg,
+			"repo":   MockRepo,
+			"number": 12,
+		},
+	})
+	require.NoError(t, err)
+	mockKvStore.EXPECT().Get(MockUserID+githubTokenKey, gomock.Any()).Return(nil).Times(1)
+
+	req := httptest.NewRequest(http.MethodPost, "/pr-action", strings.NewReader(string(b)))
+	rec := httptest.NewRecorder()
+
+	p.handleLegacyPRAction(mockContext, rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Result().StatusCode)
+	var resp model.PostActionIntegrationResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Contains(t, resp.EphemeralText, "You must connect your GitHub account")
+}
+
+func TestHandleNotificationActionDialog(t *testing.T) {
+	mockKvStore, mockAPI, _, _, mockContext := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKvStore)
+
+	makeBody := func(userID, action string, cancelled bool) string {
+		state, err := json.Marshal(notificationActionState{Action: action, Owner: MockOrg, Repo: MockRepo, Number: 12})
+		require.NoError(t, err)
+		b, err := json.Marshal(model.SubmitDialogRequest{
+			UserId:    userID,
+			ChannelId: "channelID",
+			State:     string(state),
+			Cancelled: cancelled,
+		})
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	tests := []struct {
+		name               string
+		requestBody        string
+		expectedStatusCode int
+	}{
+		{name: "Submitted by another user", requestBody: makeBody("otherUserID", notificationActionSetMilestone, false), expectedStatusCode: http.StatusForbidden},
+		{name: "Cancelled", requestBody: makeBody(MockUserID, notificationActionSetMilestone, true), expectedStatusCode: http.StatusOK},
+		{name: "Action without dialog", requestBody: makeBody(MockUserID, notificationActionClose, false), expectedStatusCode: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/notification-action/dialog", strings.NewReader(tc.requestBody))
+			rec := httptest.NewRecorder()
+
+			p.handleNotificationActionDialog(mockContext, rec, req)
+
+			assert.Equal(t, tc.expectedStatusCode, rec.Result().StatusCode)
+		})
+	}
+}
+
+func TestRunNotificationActionAudit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/api/v3/repos/mockOrg/mockRepo/issues/12/labels":
+			_ = json.NewEncoder(w).Encode([]*github.Label{{Name: github.String("bug")}})
+		default:
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKvStore)
+	p.setConfiguration(&Configuration{EnterpriseBaseURL: server.URL, EnterpriseUploadURL: server.URL})
+	info := &GitHubUserInfo{UserID: MockUserID, GitHubUsername: MockUsername, Token: &oauth2.Token{AccessToken: "token"}}
+	mockKvStore.EXPECT().Set(MockUserID+githubLastAPICallKey, gomock.Any()).Return(true, nil).AnyTimes()
+
+	mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+		params, _ := rec.EventData.Parameters["action"].(map[string]any)
+		return rec.EventName == "notificationAction" && rec.Status == model.AuditStatusSuccess &&
+			rec.Actor.UserId == MockUserID && params["value"] == "bug"
+	})).Return().Once()
+	text, err := p.runNotificationAction(context.Background(), info,
+		notificationActionState{Action: notificationActionAddLabel, Owner: MockOrg, Repo: MockRepo, Number: 12},
+		map[string]any{notificationActionDialogFieldLabel: "bug"})
+	require.NoError(t, err)
+	assert.Equal(t, "Added label `bug` to mockOrg/mockRepo#12.", text)
+
+	mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+		return rec.EventName == "notificationAction" && rec.Status == model.AuditStatusFail && rec.Actor.UserId == MockUserID
+	})).Return().Once()
+	mockAPI.On("LogWarn", mock.Anything, mock.Anything, mock.Anything).Maybe()
+	_, err = p.runNotificationAction(context.Background(), info,
+		notificationActionState{Action: notificationActionClose, Owner: MockOrg, Repo: MockRepo, Number: 12}, nil)
+	require.Error(t, err)
+
+	mockAPI.AssertExpectations(t)
+}
+
+func TestGetMergeMethodOptions(t *testing.T) {
+	getValues := func(repo *github.Repository) []string {
+		var values []string
+		for _, option := range getMergeMethodOptions(repo) {
+			values = append(values, option.Value)
+		}
+		return values
+	}
+
+	assert.Equal(t, []string{"merge", "squash", "rebase"}, getValues(&github.Repository{}))
+	assert.Equal(t, []string{"squash"}, getValues(&github.Repository{
+		AllowMergeCommit: github.Bool(false),
+		AllowSquashMerge: github.Bool(true),
+		AllowRebaseMerge: github.Bool(false),
+	}))
+}
+
+func TestMergePR(t *testing.T) {
+	tests := []struct {
+		name          string
+		canPush       bool
+		pr            *github.PullRequest
+		expectedError string
+	}{
+		{
+			name:          "No push permission",
+			pr:            &github.PullRequest{State: github.String("open")},
+			expectedError: errNotificationActionNotAllowed.Error(),
+		},
+		{
+			name:          "Closed pull request",
+			canPush:       true,
+			pr:            &github.PullRequest{State: github.String("closed")},
+			expectedError: "the pull request is not open",
+		},
+		{
+			name:          "Conflicting pull request",
+			canPush:       true,
+			pr:            &github.PullRequest{State: github.String("open"), Mergeable: github.Bool(false)},
+			expectedError: "the pull request can't be merged",
+		},
+		{
+			name:    "Merged",
+			canPush: true,
+			pr:      &github.PullRequest{State: github.String("open"), Head: &github.PullRequestBranch{SHA: github.String("headsha")}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var mergedSHA, mergeMethod string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				switch req.URL.Path {
+				case "/api-v3/repos/mockOrg/mockRepo":
+					_ = json.NewEncoder(w).Encode(&github.Repository{Permissions: map[string]bool{"push": tc.canPush}})
+				case "/api-v3/repos/mockOrg/mockRepo/pulls/12":
+					_ = json.NewEncoder(w).Encode(tc.pr)
+				case "/api-v3/repos/mockOrg/mockRepo/pulls/12/merge":
+					var options struct {
+						SHA         string `json:"sha"`
+						MergeMethod string `json:"merge_method"`
+					}
+					_ = json.NewDecoder(req.Body).Decode(&options)
+					mergedSHA = options.SHA
+					mergeMethod = options.MergeMethod
+					_ = json.NewEncoder(w).Encode(&github.PullRequestMergeResult{Merged: github.Bool(true), SHA: github.String("mergesha")})
+				}
+			}))
+			defer server.Close()
+
+			client := github.NewClient(nil)
+			client.BaseURL, _ = url.Parse(server.URL + baseURLPath + "/")
+
+			result, err := NewPlugin().mergePR(context.Background(), client, notificationActionState{Action: notificationActionMerge, Owner: MockOrg, Repo: MockRepo, Number: 12}, "squash")
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				assert.Empty(t, mergedSHA)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "mergesha", result.GetSHA())
+			assert.Equal(t, "headsha", mergedSHA)
+			assert.Equal(t, "squash", mergeMethod)
+		})
+	}
+}
+
+func TestGetNotificationActionErrorText(t *testing.T) {
+	makeErrorResponse := func(statusCode int, message string) error {
+		return &github.ErrorResponse{Response: &http.Response{StatusCode: statusCode}, Message: message}
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{name: "Not allowed", err: errNotificationActionNotAllowed, expected: "You don't have permission to do this on GitHub."},
+		{name: "Forbidden", err: makeErrorResponse(http.StatusForbidden, "Resource not accessible"), expected: "You don't have permission to do this on GitHub."},
+		{name: "Validation failed", err: makeErrorResponse(http.StatusUnprocessableEntity, "Can not approve your own pull request"), expected: "GitHub rejected the action: Can not approve your own pull request."},
+		{name: "Other error", err: errors.New("the pull request is a draft"), expected: "The action failed: the pull request is a draft."},
+		{name: "Error response without response", err: &github.ErrorResponse{Message: "Base branch was modified"}, expected: "GitHub rejected the action: Base branch was modified."},
+		{name: "Empty error response without response", err: &github.ErrorResponse{}, expected: "The action failed."},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, getNotificationActionErrorText(tc.err))
+		})
+	}
+}
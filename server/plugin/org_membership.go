@@ -0,0 +1,371 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v54/github"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const (
+	membershipEnforcementDisconnect = "disconnect"
+	membershipEnforcementDowngrade  = "downgrade"
+
+	membershipCheckMutexKey   = "org_membership_check_mutex"
+	membershipCheckLastRunKey = "org_membership_check_last_run"
+	membershipCheckInterval   = 24 * time.Hour
+	// membershipCheckPollInterval is how often the scheduler looks whether the daily check is due.
+	membershipCheckPollInterval = time.Hour
+	membershipCheckUserTimeout  = 30 * time.Second
+	membershipCheckAdminsLimit  = 100
+	// A check that would remove more than membershipCheckMaxRemovedShare of at least
+	// membershipCheckGuardMinUsers checked users is aborted.
+	membershipCheckMaxRemovedShare = 0.2
+	membershipCheckGuardMinUsers   = 10
+
+	membershipDowngradedSettingsMessage = "Your GitHub account isn't a member of the GitHub organization used with Mattermost anymore, so your notifications and reminders can't be turned on."
+)
+
+// isConfiguredOrganizationMember returns if login is a member of one of the configured
+// organizations. userClient has to authenticate login: a member can always see their own
+// membership, even a concealed one, while other clients may only see public memberships.
+func (p *Plugin) isConfiguredOrganizationMember(ctx context.Context, userClient *github.Client, login string) (bool, error) {
+	for _, org := range p.getConfiguration().getOrganizations() {
+		isMember, _, err := userClient.Organizations.IsMember(ctx, org, login)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to check the membership of %s in %s", login, org)
+		}
+		if isMember {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// checkOrganizationMembership enforces OrganizationMembershipEnforcement for the user connecting
+// the GitHub login, writing the API error and returning it when the connection must be rejected.
+func (p *Plugin) checkOrganizationMembership(ctx context.Context, c *Context, w http.ResponseWriter, githubClient *github.Client, login string) error {
+	isMember, err := p.isConfiguredOrganizationMember(ctx, githubClient, login)
+	if err != nil {
+		c.Log.WithError(err).Errorf("Failed to check the organization membership of the GitHub account")
+		p.writeAPIError(w, &APIErrorResponse{Message: "failed to check the organization membership of the GitHub account", StatusCode: http.StatusInternalServerError})
+		return err
+	}
+	if !isMember {
+		c.Log.Warnf("Rejecting GitHub account %s: not a member of the configured organizations", login)
+		orgs := strings.Join(p.getConfiguration().getOrganizations(), ", ")
+		p.writeAPIError(w, &APIErrorResponse{
+			Message:    fmt.Sprintf("The GitHub account %s isn't a member of the %s GitHub organization. Only members can connect their GitHub account.", login, orgs),
+			StatusCode: http.StatusForbidden,
+		})
+		return errors.New("GitHub account is not a member of the configured organizations")
+	}
+
+	return nil
+}
+
+// runMembershipCheckScheduler loops until ctx is cancelled, checking daily that the connected
+// users are still members of the configured organizations.
+func (p *Plugin) runMembershipCheckScheduler(ctx context.Context) {
+	for {
+		if p.getConfiguration().isOrganizationMembershipEnforced() {
+			p.maybeEnforceOrganizationMembership(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(membershipCheckPollInterval):
+		}
+	}
+}
+
+// maybeEnforceOrganizationMembership runs the membership check when the last one is at least a
+// day old. A cluster mutex ensures only one node runs it in HA setups.
+func (p *Plugin) maybeEnforceOrganizationMembership(ctx context.Context) {
+	m, err := cluster.NewMutex(p.API, membershipCheckMutexKey)
+	if err != nil {
+		p.client.Log.Warn("Failed to create cluster mutex for the organization membership check", "error", err.Error())
+		return
+	}
+	if err = m.LockWithContext(ctx); err != nil {
+		return
+	}
+	defer m.Unlock()
+
+	var lastRun int64
+	if err = p.store.Get(membershipCheckLastRunKey, &lastRun); err != nil {
+		p.client.Log.Warn("Failed to get the last organization membership check", "error", err.Error())
+		return
+	}
+	if time.Since(time.UnixMilli(lastRun)) < membershipCheckInterval {
+		return
+	}
+
+	summary, err := p.enforceOrganizationMembership(ctx)
+	if err != nil {
+		p.client.Log.Warn("Failed to check the organization membership of connected users", "error", err.Error())
+		return
+	}
+
+	if _, err = p.store.Set(membershipCheckLastRunKey, model.GetMillis()); err != nil {
+		p.client.Log.Warn("Failed to store the last organization membership check", "error", err.Error())
+	}
+
+	if summary.Aborted || len(summary.Removed) > 0 {
+		p.postMembershipSummaryToAdmins(summary)
+	}
+}
+
+// removedMember is a connected user who isn't a member of the configured organizations anymore.
+type removedMember struct {
+	UserID         string
+	GitHubUsername string
+}
+
+// membershipCheckSummary is the outcome of checking the membership of all connected users.
+type membershipCheckSummary struct {
+	Action  string
+	Checked int
+	// Removed are the users who were disconnected or downgraded, or would have been when Aborted.
+	Removed []removedMember
+	// Skipped counts the users whose membership couldn't be checked. They are checked again the next day.
+	Skipped int
+	// Aborted is set when nobody was removed because an unusually large share of users would have been.
+	Aborted bool
+}
+
+// enforceOrganizationMembership checks the membership of every connected user, disconnecting or
+// downgrading those who aren't members of the configured organizations anymore. Nobody is
+// removed when an unusually large share of the users would be.
+func (p *Plugin) enforceOrganizationMembership(ctx context.Context) (*membershipCheckSummary, error) {
+	action := p.getConfiguration().OrganizationMembershipEnforcement
+
+	keys, _, err := p.listUserTokenKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	auditRec := plugin.MakeAuditRecord("enforceOrganizationMembership", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	model.AddEventParameterAuditableToAuditRec(auditRec, "enforcement", EnforceOrganizationMembershipAuditParams{
+		Action:         action,
+		ConnectedUsers: len(keys),
+	})
+
+	summary := &membershipCheckSummary{Action: action}
+	var formerMembers []*GitHubUserInfo
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			break
+		}
+
+		info, apiErr := p.getGitHubUserInfo(strings.TrimSuffix(key, githubTokenKey))
+		if apiErr != nil {
+			summary.Skipped++
+			continue
+		}
+		if p.shouldDeferForRateLimit(info.Token.AccessToken, "organization membership check") {
+			summary.Skipped++
+			continue
+		}
+
+		var isMember bool
+		userCtx, cancel := context.WithTimeout(ctx, membershipCheckUserTimeout)
+		err = p.useGitHubClient(info, func(info *GitHubUserInfo, token *oauth2.Token) error {
+			var cErr error
+			isMember, cErr = p.isConfiguredOrganizationMember(userCtx, p.githubConnectUser(userCtx, info), info.GitHubUsername)
+			return cErr
+		})
+		cancel()
+		if err != nil {
+			summary.Skipped++
+			continue
+		}
+
+		summary.Checked++
+		if isMember {
+			if info.MembershipDowngradedAt != 0 {
+				if rErr := p.restoreFormerMember(info); rErr != nil {
+					p.client.Log.Warn("Failed to restore a former organization member", "user_id", info.UserID, "error", rErr.Error())
+				}
+			}
+			continue
+		}
+		// Users downgraded by an earlier check aren't reported again.
+		if action == membershipEnforcementDowngrade && info.MembershipDowngradedAt != 0 {
+			continue
+		}
+		formerMembers = append(formerMembers, info)
+	}
+
+	if isUnusualMembershipLoss(summary.Checked, len(formerMembers)) {
+		p.client.Log.Warn("Aborting the organization membership check, as it would remove an unusually large share of the connected users",
+			"checked", summary.Checked, "former_members", len(formerMembers))
+		summary.Aborted = true
+		for _, info := range formerMembers {
+			summary.Removed = append(summary.Removed, removedMember{UserID: info.UserID, GitHubUsername: info.GitHubUsername})
+		}
+		auditRec.AddErrorDesc("aborted: an unusually large share of the connected users would have been removed")
+		return summary, nil
+	}
+
+	for _, info := range formerMembers {
+		p.client.Log.Info("Connected user isn't a member of the configured organizations anymore", "user_id", info.UserID, "action", action)
+		if action == membershipEnforcementDowngrade {
+			downgraded, dErr := p.downgradeFormerMember(info)
+			if dErr != nil {
+				p.client.Log.Warn("Failed to downgrade a former organization member", "user_id", info.UserID, "error", dErr.Error())
+				summary.Skipped++
+				continue
+			}
+			if !downgraded {
+				continue
+			}
+		} else {
+			p.forceDisconnectUser("", info.UserID, info.GitHubUsername,
+				"Your GitHub account was disconnected because it isn't a member of the GitHub organization used with Mattermost anymore.")
+		}
+		summary.Removed = append(summary.Removed, removedMember{UserID: info.UserID, GitHubUsername: info.GitHubUsername})
+	}
+
+	auditRec.Success()
+	auditRec.AddEventResultState(EnforceOrganizationMembershipAuditResult{
+		Checked: summary.Checked,
+		Removed: len(summary.Removed),
+		Skipped: summary.Skipped,
+	})
+
+	return summary, nil
+}
+
+// isUnusualMembershipLoss returns if removing formerMembers of the checked users is more likely
+// to come from a misconfiguration or a GitHub outage than from people leaving the organization.
+func isUnusualMembershipLoss(checked, formerMembers int) bool {
+	return checked >= membershipCheckGuardMinUsers &&
+		float64(formerMembers) > membershipCheckMaxRemovedShare*float64(checked)
+}
+
+// isMembershipDowngraded returns if the user's notifications and reminders are kept off because
+// they aren't a member of the configured organizations anymore.
+func (p *Plugin) isMembershipDowngraded(info *GitHubUserInfo) bool {
+	return info.MembershipDowngradedAt != 0 &&
+		p.getConfiguration().OrganizationMembershipEnforcement == membershipEnforcementDowngrade
+}
+
+// downgradeFormerMember turns off the notifications and reminders of a user who isn't a member
+// of the configured organizations anymore, keeping their connection. They stay off until the user
+// is a member again. It returns false when the user was already downgraded.
+func (p *Plugin) downgradeFormerMember(info *GitHubUserInfo) (bool, error) {
+	if info.MembershipDowngradedAt != 0 {
+		return false, nil
+	}
+
+	downgradedAt := model.GetMillis()
+	if err := p.updateGitHubUserInfo(info.UserID, func(stored *GitHubUserInfo) {
+		if stored.Settings == nil {
+			stored.Settings = &UserSettings{}
+		}
+		stored.Settings.Notifications = false
+		stored.Settings.DailyReminder = false
+		stored.Settings.DailyReminderOnChange = false
+		stored.Settings.StalePRReminder = false
+		stored.Settings.ThreadReplySync = false
+		stored.MembershipDowngradedAt = downgradedAt
+	}); err != nil {
+		return false, err
+	}
+
+	// Notifications are only sent to the users mapped to a GitHub login.
+	if err := p.deleteGitHubToUserIDMapping(info.GitHubUsername, info.UserID); err != nil {
+		p.client.Log.Warn("Failed to delete the GitHub username mapping of a former organization member", "user_id", info.UserID, "error", err.Error())
+	}
+
+	p.CreateBotDMPost(info.UserID,
+		"Your GitHub notifications and reminders were turned off because your GitHub account isn't a member of the GitHub organization used with Mattermost anymore.",
+		"custom_git_membership")
+	return true, nil
+}
+
+// restoreFormerMember lets a downgraded user who is a member of the configured organizations again
+// turn their notifications and reminders back on.
+func (p *Plugin) restoreFormerMember(info *GitHubUserInfo) error {
+	if err := p.updateGitHubUserInfo(info.UserID, func(stored *GitHubUserInfo) {
+		stored.MembershipDowngradedAt = 0
+	}); err != nil {
+		return err
+	}
+
+	p.CreateBotDMPost(info.UserID,
+		"Your GitHub account is a member of the GitHub organization used with Mattermost again. You can turn your notifications and reminders back on using `/github settings`.",
+		"custom_git_membership")
+	return nil
+}
+
+func buildMembershipSummaryMessage(summary *membershipCheckSummary, usernames map[string]string) string {
+	var text strings.Builder
+	text.WriteString("#### GitHub organization membership check\n")
+
+	outcome := "were disconnected"
+	if summary.Action == membershipEnforcementDowngrade {
+		outcome = "had their notifications and reminders turned off"
+	}
+	if summary.Aborted {
+		fmt.Fprintf(&text, "Checked %d connected users. **Nobody was removed**, because an unusually large share of them aren't members of the GitHub organization. "+
+			"Check the organization and the plugin configuration. The check runs again tomorrow. These users aren't members:\n", summary.Checked)
+	} else {
+		fmt.Fprintf(&text, "Checked %d connected users. These users aren't members of the GitHub organization anymore and %s:\n", summary.Checked, outcome)
+	}
+	for _, removed := range summary.Removed {
+		username := usernames[removed.UserID]
+		if username == "" {
+			username = removed.UserID
+		}
+		fmt.Fprintf(&text, "* @%s (GitHub account %s)\n", username, removed.GitHubUsername)
+	}
+	if summary.Skipped > 0 {
+		fmt.Fprintf(&text, "\nThe membership of %d users couldn't be checked. They will be checked again tomorrow.\n", summary.Skipped)
+	}
+
+	return text.String()
+}
+
+// postMembershipSummaryToAdmins DMs the summary of the membership check to the System Admins.
+func (p *Plugin) postMembershipSummaryToAdmins(summary *membershipCheckSummary) {
+	usernames := map[string]string{}
+	for _, removed := range summary.Removed {
+		if user, err := p.client.User.Get(removed.UserID); err == nil {
+			usernames[removed.UserID] = user.Username
+		}
+	}
+	message := buildMembershipSummaryMessage(summary, usernames)
+
+	admins, err := p.client.User.List(&model.UserGetOptions{
+		Role:    model.SystemAdminRoleId,
+		Active:  true,
+		PerPage: membershipCheckAdminsLimit,
+	})
+	if err != nil {
+		p.client.Log.Warn("Failed to list the System Admins", "error", err.Error())
+		return
+	}
+
+	for _, admin := range admins {
+		if admin.IsBot {
+			continue
+		}
+		p.CreateBotDMPost(admin.Id, message, "custom_git_membership_summary")
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-github/v54/github"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsConfiguredOrganizationMember(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/org1/members/alice", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/orgs/org2/members/alice", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/orgs/org1/members/bob", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/orgs/org2/members/bob", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/orgs/org1/members/carol", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	githubClient := github.NewClient(nil)
+	base, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+	githubClient.BaseURL = base
+
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{GitHubOrg: "org1, org2"})
+
+	isMember, err := p.isConfiguredOrganizationMember(context.Background(), githubClient, "alice")
+	require.NoError(t, err)
+	assert.True(t, isMember)
+
+	isMember, err = p.isConfiguredOrganizationMember(context.Background(), githubClient, "bob")
+	require.NoError(t, err)
+	assert.False(t, isMember)
+
+	_, err = p.isConfiguredOrganizationMember(context.Background(), githubClient, "carol")
+	assert.Error(t, err)
+}
+
+func TestBuildMembershipSummaryMessage(t *testing.T) {
+	summary := &membershipCheckSummary{
+		Action:  membershipEnforcementDowngrade,
+		Checked: 3,
+		Removed: []removedMember{
+			{UserID: "user1", GitHubUsername: "ghuser1"},
+			{UserID: "user2", GitHubUsername: "ghuser2"},
+		},
+		Skipped: 1,
+	}
+
+	message := buildMembershipSummaryMessage(summary, map[string]string{"user1": "alice"})
+
+	assert.Contains(t, message, "Checked 3 connected users.")
+	assert.Contains(t, message, "had their notifications and reminders turned off")
+	assert.Contains(t, message, "* @alice (GitHub account ghuser1)\n")
+	assert.Contains(t, message, "* @user2 (GitHub account ghuser2)\n")
+	assert.Contains(t, message, "The membership of 1 users couldn't be checked.")
+}
+
+func TestIsUnusualMembershipLoss(t *testing.T) {
+	for name, tc := range map[string]struct {
+		checked       int
+		formerMembers int
+		expected      bool
+	}{
+		"nobody left":              {checked: 100, formerMembers: 0, expected: false},
+		"a few users left":         {checked: 100, formerMembers: 20, expected: false},
+		"too many users left":      {checked: 100, formerMembers: 21, expected: true},
+		"everybody left":           {checked: 50, formerMembers: 50, expected: true},
+		"too few users to compare": {checked: 9, formerMembers: 9, expected: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, isUnusualMembershipLoss(tc.checked, tc.formerMembers))
+		})
+	}
+}
+
+func TestBuildAbortedMembershipSummaryMessage(t *testing.T) {
+	summary := &membershipCheckSummary{
+		Action:  membershipEnforcementDisconnect,
+		Checked: 10,
+		Removed: []removedMember{{UserID: "user1", GitHubUsername: "ghuser1"}},
+		Aborted: true,
+	}
+
+	message := buildMembershipSummaryMessage(summary, map[string]string{"user1": "alice"})
+
+	assert.Contains(t, message, "**Nobody was removed**")
+	assert.NotContains(t, message, "were disconnected")
+	assert.Contains(t, message, "* @alice (GitHub account ghuser1)\n")
+}
+
+func TestDowngradeFormerMember(t *testing.T) {
+	t.Run("already downgraded", func(t *testing.T) {
+		mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKvStore)
+
+		downgraded, err := p.downgradeFormerMember(&GitHubUserInfo{UserID: MockUserID, MembershipDowngradedAt: 1})
+		require.NoError(t, err)
+		assert.False(t, downgraded)
+	})
+
+	t.Run("downgrades the user", func(t *testing.T) {
+		mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKvStore)
+		info, err := GetMockGHUserInfo(p)
+		require.NoError(t, err)
+		info.Settings = &UserSettings{Notifications: true, DailyReminder: true, StalePRReminder: true}
+
+		mockAPI.On("KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+		ExpectStoredGitHubUserInfo(mockKvStore, info).Times(1)
+		mockKvStore.EXPECT().Set(MockUserID+githubTokenKey, gomock.Any()).DoAndReturn(
+			func(key string, value any, opts ...pluginapi.KVSetOption) (bool, error) {
+				stored := value.(*GitHubUserInfo)
+				assert.NotZero(t, stored.MembershipDowngradedAt)
+				assert.Equal(t, &UserSettings{}, stored.Settings)
+				return true, nil
+			}).Times(1)
+		expectUserIDMapping(mockKvStore, MockUsername, MockUserID)
+		mockKvStore.EXPECT().Delete(MockUsername + githubUsernameKey).Return(nil).Times(1)
+		mockAPI.On("GetDirectChannel", MockUserID, MockBotID).Return(&model.Channel{Id: "dmchannel"}, nil)
+		mockAPI.On("CreatePost", mock.Anything).Return(&model.Post{}, nil).Once()
+
+		downgraded, err := p.downgradeFormerMember(info)
+		require.NoError(t, err)
+		assert.True(t, downgraded)
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("keeps the mapping of the user the login is mapped to", func(t *testing.T) {
+		mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKvStore)
+		info, err := GetMockGHUserInfo(p)
+		require.NoError(t, err)
+
+		mockAPI.On("KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+		ExpectStoredGitHubUserInfo(mockKvStore, info).Times(1)
+		mockKvStore.EXPECT().Set(MockUserID+githubTokenKey, gomock.Any()).Return(true, nil).Times(1)
+		// The user never took the login over from the member it is mapped to.
+		expectUserIDMapping(mockKvStore, MockUsername, "memberUserID")
+		mockAPI.On("GetDirectChannel", MockUserID, MockBotID).Return(&model.Channel{Id: "dmchannel"}, nil)
+		mockAPI.On("CreatePost", mock.Anything).Return(&model.Post{}, nil).Once()
+
+		downgraded, err := p.downgradeFormerMember(info)
+		require.NoError(t, err)
+		assert.True(t, downgraded)
+		mockAPI.AssertExpectations(t)
+	})
+}
+
+func TestSettingsOfDowngradedMember(t *testing.T) {
+	mockKvStore, mockAPI, mockLogger, _, _ := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKvStore)
+	c, err := GetMockUserContext(p, mockLogger)
+	require.NoError(t, err)
+	p.setConfiguration(&Configuration{OrganizationMembershipEnforcement: membershipEnforcementDowngrade})
+	c.GHInfo.MembershipDowngradedAt = 1
+
+	assert.Equal(t, membershipDowngradedSettingsMessage, p.handleSettings(nil, nil, []string{settingNotifications, settingOn}, c.GHInfo))
+	assert.Equal(t, membershipDowngradedSettingsMessage, p.handleSettings(nil, nil, []string{settingReminders, settingOnChange}, c.GHInfo))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/settings", strings.NewReader(`{"notifications": true}`))
+	p.updateSettings(c, rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	sidebar, err := p.getSidebarData(c)
+	require.NoError(t, err)
+	assert.Empty(t, sidebar.PRs)
+	assert.Empty(t, sidebar.Unreads)
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lruCache is a size bounded in-memory cache that evicts the least recently used entry when full.
+// It is bounded by the number of entries, or by their total size in bytes for caches created with
+// newByteBoundedLRUCache. Entries expire after ttl, or never when ttl is zero. It is safe for
+// concurrent use.
+type lruCache struct {
+	mu       sync.Mutex
+	size     int
+	maxBytes int
+	bytes    int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+type lruEntry struct {
+	key       string
+	value     any
+	bytes     int
+	expiresAt time.Time
+}
+
+func newLRUCache(size int, ttl time.Duration) *lruCache {
+	return &lruCache{
+		size:    size,
+		ttl:     ttl,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// newByteBoundedLRUCache returns a cache holding entries up to a total of maxBytes, as reported
+// to addWithSize.
+func newByteBoundedLRUCache(maxBytes int, ttl time.Duration) *lruCache {
+	return &lruCache{
+		maxBytes: maxBytes,
+		ttl:      ttl,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+func (c *lruCache) isFull() bool {
+	if c.maxBytes > 0 {
+		return c.bytes > c.maxBytes
+	}
+	return c.order.Len() > c.size
+}
+
+func (c *lruCache) remove(elem *list.Element) {
+	entry := elem.Value.(*lruEntry)
+	c.order.Remove(elem)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.bytes
+}
+
+func (c *lruCache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (c *lruCache) add(key string, value any) {
+	c.addWithSize(key, value, 0)
+}
+
+// addWithSize adds the value taking up size bytes. Values larger than the byte bound of the
+// cache aren't added.
+func (c *lruCache) addWithSize(key string, value any, size int) {
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = time.Now().Add(c.ttl)
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		c.bytes += size - entry.bytes
+		entry.value = value
+		entry.bytes = size
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+	} else {
+		c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, bytes: size, expiresAt: expiresAt})
+		c.bytes += size
+	}
+
+	for c.isFull() {
+		c.remove(c.order.Back())
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	t.Run("evicts the least recently used entry", func(t *testing.T) {
+		c := newLRUCache(2, 0)
+		c.add("a", 1)
+		c.add("b", 2)
+		_, _ = c.get("a")
+		c.add("c", 3)
+
+		_, ok := c.get("b")
+		assert.False(t, ok)
+		value, ok := c.get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 1, value)
+		value, ok = c.get("c")
+		assert.True(t, ok)
+		assert.Equal(t, 3, value)
+	})
+
+	t.Run("evicts entries beyond the byte bound", func(t *testing.T) {
+		c := newByteBoundedLRUCache(10, 0)
+		c.addWithSize("a", 1, 4)
+		c.addWithSize("b", 2, 4)
+		c.addWithSize("c", 3, 4)
+
+		_, ok := c.get("a")
+		assert.False(t, ok)
+		_, ok = c.get("b")
+		assert.True(t, ok)
+
+		// Growing an entry evicts the others as needed.
+		c.addWithSize("b", 2, 8)
+		_, ok = c.get("c")
+		assert.False(t, ok)
+		assert.Equal(t, 8, c.bytes)
+
+		c.addWithSize("d", 4, 11)
+		_, ok = c.get("d")
+		assert.False(t, ok)
+		assert.Equal(t, 8, c.bytes)
+	})
+
+	t.Run("expires entries after the ttl", func(t *testing.T) {
+		c := newLRUCache(2, time.Millisecond)
+		c.add("a", 1)
+		time.Sleep(5 * time.Millisecond)
+
+		_, ok := c.get("a")
+		assert.False(t, ok)
+	})
+}
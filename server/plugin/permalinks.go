@@ -5,8 +5,12 @@ package plugin
 
 import (
 	"context"
+	"net/url"
 	"path"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/v54/github"
@@ -18,13 +22,19 @@ const maxPermalinkReplacements = 10
 
 const permalinkReqTimeout = 5 * time.Second
 
-// maxPreviewLines sets the maximum number of preview lines that will be shown
-// while replacing a permalink.
-const maxPreviewLines = 10
+const (
+	// permalinkContentCacheSize is the number of files kept in the permalink content cache.
+	permalinkContentCacheSize = 200
+	// maxCachedPermalinkContentSize is the size in bytes above which files aren't cached.
+	maxCachedPermalinkContentSize = 256 * 1024
 
-// permalinkLineContext shows the number of lines before and after to show
-// if the link points to a single line.
-const permalinkLineContext = 3
+	repoVisibilityCacheSize = 500
+	repoVisibilityCacheTTL  = 10 * time.Minute
+)
+
+// maxPreviewLineLength is the number of characters after which a line of a
+// preview is cut off, so minified or generated files don't swamp the channel.
+const maxPreviewLineLength = 200
 
 // replacement holds necessary info to replace github permalinks
 // in messages with a code preview block.
@@ -39,14 +49,33 @@ type replacement struct {
 		path   string
 		line   string
 	}
+	diff *diffInfo // set for links to a file in a pull request or commit diff
+}
+
+// newGitHubPermalinkRegex returns the regex matching permalinks to file lines on the GitHub host of baseURL.
+// The commit group is a commit SHA, branch or tag.
+func newGitHubPermalinkRegex(baseURL string) *regexp.Regexp {
+	return regexp.MustCompile(`https?://(?P<haswww>www\.)?` + githubHostPattern(baseURL) + `/(?P<user>[\w-]+)/(?P<repo>[\w-.]+)/blob/(?P<commit>[\w-.]+)/(?P<path>[\w-/.]+)#(?P<line>[\w-]+)?`)
+}
+
+// githubHostPattern returns the quoted host and path prefix of baseURL, e.g. github.com or
+// github.example.com/prefix, falling back to github.com when baseURL can't be parsed.
+func githubHostPattern(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return regexp.QuoteMeta("github.com")
+	}
+
+	return regexp.QuoteMeta(strings.TrimSuffix(u.Host+u.Path, "/"))
 }
 
 // getReplacements returns the permalink replacements that needs to be performed
 // on a message. The returned slice is sorted by the index in ascending order.
 func (p *Plugin) getReplacements(msg string) []replacement {
 	// find the permalinks from the msg using a regex
-	matches := p.githubPermalinkRegex.FindAllStringSubmatch(msg, -1)
-	indices := p.githubPermalinkRegex.FindAllStringIndex(msg, -1)
+	permalinkRegex := p.getGitHubPermalinkRegex()
+	matches := permalinkRegex.FindAllStringSubmatch(msg, -1)
+	indices := permalinkRegex.FindAllStringIndex(msg, -1)
 	var replacements []replacement
 	for i, m := range matches {
 		// have a limit on the number of replacements to do
@@ -64,7 +93,7 @@ func (p *Plugin) getReplacements(msg string) []replacement {
 			continue
 		}
 		// populate the permalinkInfo with the extracted groups of the regex
-		for j, name := range p.githubPermalinkRegex.SubexpNames() {
+		for j, name := range permalinkRegex.SubexpNames() {
 			if j == 0 {
 				continue
 			}
@@ -85,82 +114,274 @@ func (p *Plugin) getReplacements(msg string) []replacement {
 		}
 		replacements = append(replacements, r)
 	}
+
+	replacements = append(replacements, p.getDiffReplacements(msg)...)
+	sort.SliceStable(replacements, func(i, j int) bool {
+		return replacements[i].index < replacements[j].index
+	})
+	if len(replacements) > maxPermalinkReplacements {
+		replacements = replacements[:maxPermalinkReplacements]
+	}
 	return replacements
 }
 
+// permalinkFile identifies the file a permalink points to.
+type permalinkFile struct {
+	user   string
+	repo   string
+	commit string
+	path   string
+}
+
+func (r replacement) file() permalinkFile {
+	return permalinkFile{
+		user:   r.permalinkInfo.user,
+		repo:   r.permalinkInfo.repo,
+		commit: r.permalinkInfo.commit,
+		path:   r.permalinkInfo.path,
+	}
+}
+
+// fetchKey identifies what needs to be fetched to preview the replacement, so links
+// to the same file are fetched once.
+func (r replacement) fetchKey() string {
+	if r.diff != nil {
+		return r.diff.cacheKey(r.permalinkInfo.user, r.permalinkInfo.repo)
+	}
+	return r.file().cacheKey()
+}
+
+// cacheKey identifies the file in permalinkContentCache. The "file:" namespace keeps it apart
+// from the keys of diff patches, whatever the path.
+func (f permalinkFile) cacheKey() string {
+	return "file:" + strings.ToLower(f.user+"/"+f.repo+"@"+f.commit) + ":" + f.path
+}
+
+// isCommitSHA reports whether ref is a full commit SHA rather than a branch or tag name.
+func isCommitSHA(ref string) bool {
+	if len(ref) != 40 {
+		return false
+	}
+	for _, c := range ref {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
 // makeReplacements perform the given replacements on the msg and returns
 // the new msg. The replacements slice needs to be sorted by the index in ascending order.
-func (p *Plugin) makeReplacements(msg string, replacements []replacement, ghClient *github.Client) string {
+// All files are fetched concurrently under the deadline of ctx.
+func (p *Plugin) makeReplacements(ctx context.Context, msg string, replacements []replacement, ghClient *github.Client) string {
+	contents := p.getPermalinkContents(ctx, replacements, ghClient)
+
 	config := p.getConfiguration()
+	maxLines := config.getCodePreviewMaxLines()
+	contextLines := config.CodePreviewContextLines
 
 	// iterating the slice in reverse to preserve the replacement indices.
 	for i := len(replacements) - 1; i >= 0; i-- {
 		r := replacements[i]
 
-		ctx, cancel := context.WithTimeout(context.Background(), permalinkReqTimeout)
-		defer cancel()
-
-		// Check if repo is public
-		if config.EnableCodePreview != "privateAndPublic" {
-			repo, _, err := ghClient.Repositories.Get(ctx, r.permalinkInfo.user, r.permalinkInfo.repo)
-			if err != nil {
-				p.client.Log.Warn("Error while fetching repository information",
-					"error", err.Error(),
-					"repo", r.permalinkInfo.repo,
-					"user", r.permalinkInfo.user)
-				continue
-			}
-
-			if repo.GetPrivate() {
-				continue
-			}
+		content, ok := contents[r.fetchKey()]
+		if !ok {
+			continue
 		}
 
-		// get the file contents
-		opts := github.RepositoryContentGetOptions{
-			Ref: r.permalinkInfo.commit,
-		}
-		// TODO: make all of these requests concurrently.
-		fileContent, _, _, err := ghClient.Repositories.GetContents(ctx,
-			r.permalinkInfo.user, r.permalinkInfo.repo, r.permalinkInfo.path, &opts)
-		if err != nil {
-			p.client.Log.Warn("Error while fetching file contents", "error", err.Error(), "path", r.permalinkInfo.path)
-			continue
+		var final string
+		if r.diff != nil {
+			final, ok = p.getDiffPreviewMarkdown(r, content, maxLines, contextLines)
+		} else {
+			final, ok = p.getFilePreviewMarkdown(r, content, maxLines, contextLines)
 		}
-		// this is not a file, ignore.
-		if fileContent == nil {
-			p.client.Log.Warn("Permalink is not a file", "file", r.permalinkInfo.path)
+		if !ok {
 			continue
 		}
-		decoded, err := fileContent.GetContent()
-		if err != nil {
-			p.client.Log.Warn("Error while decoding file contents", "error", err.Error(), "path", r.permalinkInfo.path)
+
+		// replace word in msg starting from r.index only once.
+		msg = msg[:r.index] + strings.Replace(msg[r.index:], r.word, final, 1)
+	}
+	return msg
+}
+
+// getFilePreviewMarkdown returns the code preview of the lines of the file the permalink points to.
+func (p *Plugin) getFilePreviewMarkdown(r replacement, content permalinkContent, maxLines, contextLines int) (string, bool) {
+	// get the required lines.
+	start, end := getLineNumbers(r.permalinkInfo.line, contextLines)
+	// bad anchor tag, ignore.
+	if start == -1 || end == -1 {
+		return "", false
+	}
+	isTruncated := false
+	if end-start > maxLines {
+		end = start + maxLines
+		isTruncated = true
+	}
+	lines, err := filterLines(content.decoded, start, end, maxPreviewLineLength)
+	if err != nil {
+		p.client.Log.Warn("Error while filtering lines", "error", err.Error(), "path", r.permalinkInfo.path)
+	}
+	if lines == "" {
+		p.client.Log.Warn("Line numbers out of range. Skipping.", "file", r.permalinkInfo.path, "start", start, "end", end)
+		return "", false
+	}
+
+	// Label previews of branch and tag links with the commit they were resolved to, since
+	// the ref may point elsewhere by the time someone reads the post.
+	pinnedURL := ""
+	if content.commit != r.permalinkInfo.commit {
+		pinnedURL = strings.Replace(r.word, "/blob/"+r.permalinkInfo.commit+"/", "/blob/"+content.commit+"/", 1)
+	}
+
+	language := getCodeLanguage(r.permalinkInfo.path)
+	return getCodeMarkdown(r.permalinkInfo.user, r.permalinkInfo.repo, r.permalinkInfo.path, r.word, lines, language, isTruncated, content.commit, pinnedURL), true
+}
+
+// permalinkContent is the decoded contents of a file linked by a permalink, or the patch of
+// a file linked from a diff.
+type permalinkContent struct {
+	decoded string
+	commit  string // the commit SHA the contents were fetched at
+	path    string // the path of a file linked from a diff
+}
+
+// getPermalinkContents concurrently fetches the decoded contents of the files the replacements
+// point to, keyed by replacement.fetchKey. Files that can't be previewed are missing from the
+// returned map.
+func (p *Plugin) getPermalinkContents(ctx context.Context, replacements []replacement, ghClient *github.Client) map[string]permalinkContent {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		contents = map[string]permalinkContent{}
+		fetching = map[string]bool{}
+	)
+
+	for _, r := range replacements {
+		key := r.fetchKey()
+		if fetching[key] {
 			continue
 		}
+		fetching[key] = true
 
-		// get the required lines.
-		start, end := getLineNumbers(r.permalinkInfo.line)
-		// bad anchor tag, ignore.
-		if start == -1 || end == -1 {
-			continue
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var content permalinkContent
+			var ok bool
+			if r.diff != nil {
+				content, ok = p.getDiffPatch(ctx, r, ghClient)
+			} else {
+				content, ok = p.getPermalinkContent(ctx, r.file(), ghClient)
+			}
+			if !ok {
+				return
+			}
+
+			mu.Lock()
+			contents[key] = content
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return contents
+}
+
+// checkPermalinkRepo reports whether files of the repository may be previewed, and whether the
+// repository is known to be public.
+func (p *Plugin) checkPermalinkRepo(ctx context.Context, owner, repo string, ghClient *github.Client) (allowed, public bool) {
+	if p.getConfiguration().EnableCodePreview == "privateAndPublic" {
+		return true, false
+	}
+
+	private, err := p.isPrivateRepo(ctx, owner, repo, ghClient)
+	if err != nil {
+		p.client.Log.Warn("Error while fetching repository information",
+			"error", err.Error(),
+			"repo", repo,
+			"user", owner)
+		return false, false
+	}
+
+	return !private, !private
+}
+
+// getPermalinkContent returns the decoded contents of the file, or false when it can't be previewed.
+// Branches and tags are resolved to a commit SHA first. Contents at a commit SHA never change,
+// so files of public repositories are served from permalinkContentCache.
+func (p *Plugin) getPermalinkContent(ctx context.Context, file permalinkFile, ghClient *github.Client) (permalinkContent, bool) {
+	// Check if repo is public
+	allowed, checkedPublic := p.checkPermalinkRepo(ctx, file.user, file.repo, ghClient)
+	if !allowed {
+		return permalinkContent{}, false
+	}
+
+	if !isCommitSHA(file.commit) {
+		sha, _, err := ghClient.Repositories.GetCommitSHA1(ctx, file.user, file.repo, file.commit, "")
+		if err != nil {
+			p.client.Log.Warn("Error while resolving permalink ref", "error", err.Error(), "ref", file.commit, "path", file.path)
+			return permalinkContent{}, false
 		}
-		isTruncated := false
-		if end-start > maxPreviewLines {
-			end = start + maxPreviewLines
-			isTruncated = true
+		if !isCommitSHA(sha) {
+			p.client.Log.Warn("Permalink ref did not resolve to a commit", "ref", file.commit, "path", file.path)
+			return permalinkContent{}, false
 		}
-		lines, err := filterLines(decoded, start, end)
-		if err != nil {
-			p.client.Log.Warn("Error while filtering lines", "error", err.Error(), "path", r.permalinkInfo.path)
+		file.commit = sha
+	}
+
+	// Only public files are ever cached, so a cache hit can't show private code to someone who
+	// couldn't fetch it with their own token.
+	if cached, ok := p.permalinkContentCache.get(file.cacheKey()); ok {
+		if content, ok := cached.(permalinkContent); ok {
+			return content, true
 		}
-		if lines == "" {
-			p.client.Log.Warn("Line numbers out of range. Skipping.", "file", r.permalinkInfo.path, "start", start, "end", end)
-			continue
+	}
+
+	// get the file contents
+	opts := github.RepositoryContentGetOptions{
+		Ref: file.commit,
+	}
+	fileContent, _, _, err := ghClient.Repositories.GetContents(ctx, file.user, file.repo, file.path, &opts)
+	if err != nil {
+		p.client.Log.Warn("Error while fetching file contents", "error", err.Error(), "path", file.path)
+		return permalinkContent{}, false
+	}
+	// this is not a file, ignore.
+	if fileContent == nil {
+		p.client.Log.Warn("Permalink is not a file", "file", file.path)
+		return permalinkContent{}, false
+	}
+	decoded, err := fileContent.GetContent()
+	if err != nil {
+		p.client.Log.Warn("Error while decoding file contents", "error", err.Error(), "path", file.path)
+		return permalinkContent{}, false
+	}
+
+	content := permalinkContent{decoded: decoded, commit: file.commit}
+	if checkedPublic && len(decoded) <= maxCachedPermalinkContentSize {
+		p.permalinkContentCache.add(file.cacheKey(), content)
+	}
+
+	return content, true
+}
+
+// isPrivateRepo reports whether the repository is private. Results are cached for
+// repoVisibilityCacheTTL, so a repository made private stops being previewed soon after.
+func (p *Plugin) isPrivateRepo(ctx context.Context, owner, repo string, ghClient *github.Client) (bool, error) {
+	key := strings.ToLower(owner + "/" + repo)
+	if cached, ok := p.repoVisibilityCache.get(key); ok {
+		if private, ok := cached.(bool); ok {
+			return private, nil
 		}
-		final := getCodeMarkdown(r.permalinkInfo.user, r.permalinkInfo.repo, r.permalinkInfo.path, r.word, lines, isTruncated)
+	}
 
-		// replace word in msg starting from r.index only once.
-		msg = msg[:r.index] + strings.Replace(msg[r.index:], r.word, final, 1)
+	repository, _, err := ghClient.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return false, err
 	}
-	return msg
+
+	p.repoVisibilityCache.add(key, repository.GetPrivate())
+	return repository.GetPrivate(), nil
 }
@@ -4,10 +4,12 @@
 package plugin
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync"
 	"testing"
 
 	"github.com/google/go-github/v54/github"
@@ -365,7 +367,7 @@ func TestMakeReplacements(t *testing.T) {
 		{
 			name:   "link with branch name",
 			input:  "start https://github.com/mattermost/mattermost-server/blob/TEST-branch_1/app/authentication.go#L15-L22 lorem ipsum",
-			output: "start \n[mattermost/mattermost-server/app/authentication.go](https://github.com/mattermost/mattermost-server/blob/TEST-branch_1/app/authentication.go#L15-L22)\n```go\ntype TokenLocation int\n\nconst (\n\tTokenLocationNotFound TokenLocation = iota\n\tTokenLocationHeader\n\tTokenLocationCookie\n\tTokenLocationQueryString\n)\n```\n lorem ipsum",
+			output: "start \n[mattermost/mattermost-server/app/authentication.go](https://github.com/mattermost/mattermost-server/blob/TEST-branch_1/app/authentication.go#L15-L22) at [`cbb2583`](https://github.com/mattermost/mattermost-server/blob/cbb25838a61872b624ac512556d7bc932486a64c/app/authentication.go#L15-L22)\n```go\ntype TokenLocation int\n\nconst (\n\tTokenLocationNotFound TokenLocation = iota\n\tTokenLocationHeader\n\tTokenLocationCookie\n\tTokenLocationQueryString\n)\n```\n lorem ipsum",
 			replacements: []replacement{
 				{
 					index: 6,
@@ -379,7 +381,7 @@ func TestMakeReplacements(t *testing.T) {
 						line   string
 					}{
 						haswww: "",
-						commit: "master",
+						commit: "TEST-branch_1",
 						line:   "L15-L22",
 						path:   "app/authentication.go",
 						user:   "mattermost",
@@ -446,7 +448,7 @@ func TestMakeReplacements(t *testing.T) {
 
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
-			msg := p.makeReplacements(tc.input, tc.replacements, client)
+			msg := p.makeReplacements(context.Background(), tc.input, tc.replacements, client)
 			assert.Equalf(t, tc.output, msg, "mismatched output")
 		})
 	}
@@ -454,6 +456,83 @@ func TestMakeReplacements(t *testing.T) {
 	mockPluginAPI.AssertCalled(t, "LogWarn", "Error while fetching file contents", "error", "unmarshalling failed for both file and directory content: unexpected end of JSON input and unexpected end of JSON input", "path", "path/file.go")
 }
 
+func TestMakeReplacementsCache(t *testing.T) {
+	const sha = "cbb25838a61872b624ac512556d7bc932486a64c"
+
+	tcs := []struct {
+		name             string
+		commit           string
+		private          bool
+		codePreview      string
+		expectedContents int
+		expectedRepos    int
+		expectedCommits  int
+		expectPreview    bool
+	}{
+		{name: "public file at a commit SHA is fetched once", commit: sha, codePreview: "public", expectedContents: 1, expectedRepos: 1, expectPreview: true},
+		{name: "branch is resolved every time", commit: "master", codePreview: "public", expectedContents: 1, expectedRepos: 1, expectedCommits: 3, expectPreview: true},
+		{name: "private repo is not previewed", commit: sha, private: true, codePreview: "public", expectedContents: 0, expectedRepos: 1},
+		{name: "visibility unknown when previewing private repos", commit: sha, codePreview: "privateAndPublic", expectedContents: 3, expectedRepos: 0, expectPreview: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewPlugin()
+			p.setConfiguration(&Configuration{EnableCodePreview: tc.codePreview})
+			mockPluginAPI := &plugintest.API{}
+			p.SetAPI(mockPluginAPI)
+			p.client = pluginapi.NewClient(p.API, p.Driver)
+
+			var mu sync.Mutex
+			contentRequests, repoRequests, commitRequests := 0, 0, 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				switch req.URL.Path {
+				case "/api-v3/repos/mattermost/mattermost-server":
+					repoRequests++
+					_, _ = fmt.Fprintf(w, `{"private": %t}`, tc.private)
+				case "/api-v3/repos/mattermost/mattermost-server/contents/main.go":
+					contentRequests++
+					_, _ = fmt.Fprintln(w, `{"type": "file", "encoding": "base64", "content": "cGFja2FnZSBtYWluCg=="}`)
+				case "/api-v3/repos/mattermost/mattermost-server/commits/master":
+					commitRequests++
+					_, _ = fmt.Fprint(w, sha)
+				}
+			}))
+			defer server.Close()
+
+			client := github.NewClient(nil)
+			client.BaseURL, _ = url.Parse(server.URL + baseURLPath + "/")
+
+			word := "https://github.com/mattermost/mattermost-server/blob/" + tc.commit + "/main.go#L1"
+			r := replacement{index: 0, word: word}
+			r.permalinkInfo.user = "mattermost"
+			r.permalinkInfo.repo = "mattermost-server"
+			r.permalinkInfo.commit = tc.commit
+			r.permalinkInfo.path = "main.go"
+			r.permalinkInfo.line = "L1"
+
+			// The same link posted twice in a message is fetched once.
+			msg := word + " " + word
+			r2 := r
+			r2.index = len(word) + 1
+			out := p.makeReplacements(context.Background(), msg, []replacement{r, r2}, client)
+			for range 2 {
+				out = p.makeReplacements(context.Background(), word, []replacement{r}, client)
+			}
+
+			assert.Equal(t, tc.expectedContents, contentRequests)
+			assert.Equal(t, tc.expectedRepos, repoRequests)
+			assert.Equal(t, tc.expectedCommits, commitRequests)
+			if tc.expectPreview {
+				assert.Contains(t, out, "package main")
+			} else {
+				assert.Equal(t, word, out)
+			}
+		})
+	}
+}
+
 const (
 	baseURLPath = "/api-v3"
 )
@@ -481,6 +560,8 @@ func getClient() (*github.Client, func()) {
     "html": "https://github.com/mattermost/mattermost-server/blob/cbb25838a61872b624ac512556d7bc932486a64c/app/authentication.go"
   }
 }`)
+		case "/api-v3/repos/mattermost/mattermost-server/commits/TEST-branch_1":
+			_, _ = fmt.Fprint(w, "cbb25838a61872b624ac512556d7bc932486a64c")
 		case "/api-v3/repos/badorg/badrepo/path/file.go":
 			_, _ = fmt.Fprintln(w, `{
   "sha": "c5c4ebf9077d04306ce8eca1e451421e4df7ca3c",
@@ -507,3 +588,20 @@ func getClient() (*github.Client, func()) {
 	client.UploadURL = url
 	return client, server.Close
 }
+
+func TestGetReplacementsEnterprise(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{EnterpriseBaseURL: "https://git.example.com/github"})
+
+	msg := "see https://git.example.com/github/mattermost/mattermost/blob/v1.2.0/app/file.go#L3 and https://github.com/mattermost/mattermost/blob/master/app/file.go#L3"
+	replacements := p.getReplacements(msg)
+
+	require.Len(t, replacements, 1)
+	assert.Equal(t, "https://git.example.com/github/mattermost/mattermost/blob/v1.2.0/app/file.go#L3", replacements[0].word)
+	assert.Equal(t, "v1.2.0", replacements[0].permalinkInfo.commit)
+	assert.Equal(t, "app/file.go", replacements[0].permalinkInfo.path)
+
+	previews := p.getLinkPreviews("https://git.example.com/github/mattermost/mattermost/pull/12 https://github.com/mattermost/mattermost/pull/13")
+	require.Len(t, previews, 1)
+	assert.Equal(t, "12", previews[0].id)
+}
@@ -13,6 +13,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/go-github/v54/github"
@@ -34,10 +35,13 @@ const (
 	githubOauthKey       = "githuboauthkey_"
 	githubUsernameKey    = "_githubusername"
 	githubPrivateRepoKey = "_githubprivate"
+	githubLastAPICallKey = "_githublastapicall"
 
 	mm34646MutexKey   = "mm34646_token_reset_mutex"
 	mm34646DoneKey    = "mm34646_token_reset_done"
 	reEncryptMutexKey = "reencrypt_user_data_mutex"
+	// tokenEncryptionMigratedKey records that no stored token uses the legacy AES-CFB format anymore.
+	tokenEncryptionMigratedKey = "token_encryption_migration_done"
 
 	wsEventConnect    = "connect"
 	wsEventDisconnect = "disconnect"
@@ -61,6 +65,8 @@ const (
 	chimeraGitHubAppIdentifier = "plugin-github"
 
 	invalidTokenError = "401 Bad credentials" //#nosec G101 -- False positive
+
+	configurationResetDisconnectMessage = "Your GitHub connection has been reset due to a change in the plugin configuration. Please reconnect your account using `/github connect`."
 )
 
 // testOAuthServerURL is the URL for the oauthServer used for testing purposes
@@ -99,12 +105,48 @@ type Plugin struct {
 	CommandHandlers map[string]CommandHandleFunc
 
 	// githubPermalinkRegex is used to parse github permalinks in post messages.
+	// It is built from the configured base URL and guarded by configurationLock.
 	githubPermalinkRegex *regexp.Regexp
 
+	// githubLinkRegex is used to parse links to github issues, pull requests and commits in post messages.
+	// It is built from the configured base URL and guarded by configurationLock.
+	githubLinkRegex *regexp.Regexp
+
+	// githubDiffLinkRegex is used to parse links to files in pull request and commit diffs in post messages.
+	// It is built from the configured base URL and guarded by configurationLock.
+	githubDiffLinkRegex *regexp.Regexp
+
+	// permalinkContentCache holds the permalinkContent of files linked by permalinks and of
+	// patches linked from diffs, keyed by permalinkFile.cacheKey and diffInfo.cacheKey.
+	permalinkContentCache *lruCache
+	// repoVisibilityCache holds whether repositories linked by permalinks are private.
+	repoVisibilityCache *lruCache
+
+	// installationTokensLock guards installationTokens.
+	installationTokensLock sync.Mutex
+	// installationTokens holds the access tokens of the GitHub App installations, keyed by lowercase owner.
+	installationTokens map[string]*installationToken
+
+	// rateLimits tracks the GitHub rate limit budgets of the tokens in use.
+	rateLimits *rateLimitTracker
+	// conditionalRequestCache holds the GitHub responses revalidated with conditional requests,
+	// keyed by token, media type and URL.
+	conditionalRequestCache *lruCache
+	// lastAPICallCache holds the users whose last successful GitHub API call was recently recorded.
+	lastAPICallCache *lruCache
+
+	// legacyTokensMigrated is set once all stored tokens were migrated to AES-GCM, after which
+	// legacy AES-CFB ciphertexts are rejected.
+	legacyTokensMigrated atomic.Bool
+
 	webhookBroker *WebhookBroker
 	oauthBroker   *OAuthBroker
 
-	slaDigestCancel context.CancelFunc
+	slaDigestCancel       context.CancelFunc
+	reviewReminderCancel  context.CancelFunc
+	stalePRReminderCancel context.CancelFunc
+	reactionSyncCancel    context.CancelFunc
+	membershipCheckCancel context.CancelFunc
 
 	emojiMap map[string]string
 }
@@ -112,7 +154,14 @@ type Plugin struct {
 // NewPlugin returns an instance of a Plugin.
 func NewPlugin() *Plugin {
 	p := &Plugin{
-		githubPermalinkRegex: regexp.MustCompile(`https?://(?P<haswww>www\.)?github\.com/(?P<user>[\w-]+)/(?P<repo>[\w-.]+)/blob/(?P<commit>[\w-]+)/(?P<path>[\w-/.]+)#(?P<line>[\w-]+)?`),
+		githubPermalinkRegex:    newGitHubPermalinkRegex(defaultGitHubBaseURL),
+		githubLinkRegex:         newGitHubLinkRegex(defaultGitHubBaseURL),
+		githubDiffLinkRegex:     newGitHubDiffLinkRegex(defaultGitHubBaseURL),
+		permalinkContentCache:   newLRUCache(permalinkContentCacheSize, 0),
+		repoVisibilityCache:     newLRUCache(repoVisibilityCacheSize, repoVisibilityCacheTTL),
+		rateLimits:              newRateLimitTracker(),
+		conditionalRequestCache: newByteBoundedLRUCache(conditionalRequestCacheMaxBytes, 0),
+		lastAPICallCache:        newLRUCache(lastAPICallCacheSize, lastAPICallRecordInterval),
 	}
 
 	p.CommandHandlers = map[string]CommandHandleFunc{
@@ -175,19 +224,24 @@ func (p *Plugin) GetGitHubClient(ctx context.Context, userID string) (*github.Cl
 }
 
 func (p *Plugin) githubConnectUser(_ context.Context, info *GitHubUserInfo) *github.Client {
-	tok := *info.Token
-	return p.githubConnectToken(tok)
+	client, err := getGitHubClient(p.newUserGitHubHTTPClient(info), p.getConfiguration())
+	if err != nil {
+		p.client.Log.Warn("Failed to create GitHub client", "error", err.Error())
+		return nil
+	}
+
+	return client
 }
 
 func (p *Plugin) graphQLConnect(info *GitHubUserInfo) *graphql.Client {
 	conf := p.getConfiguration()
-	return graphql.NewClient(p.client.Log, p.configuration.getOrganizations, *info.Token, info.GitHubUsername, conf.GitHubOrg, conf.EnterpriseBaseURL)
+	return graphql.NewClient(p.client.Log, p.configuration.getOrganizations, p.newUserGitHubHTTPClient(info), info.GitHubUsername, conf.GitHubOrg, conf.EnterpriseBaseURL)
 }
 
 func (p *Plugin) githubConnectToken(token oauth2.Token) *github.Client {
 	config := p.getConfiguration()
 
-	client, err := GetGitHubClient(token, config)
+	client, err := getGitHubClient(p.newGitHubHTTPClient(oauth2.StaticTokenSource(&token)), config)
 	if err != nil {
 		p.client.Log.Warn("Failed to create GitHub client", "error", err.Error())
 		return nil
@@ -293,6 +347,8 @@ func (p *Plugin) OnActivate() error {
 
 	registerGitHubToUsernameMappingCallback(p.getGitHubToUsernameMapping)
 
+	go p.migrateTokenEncryption()
+
 	go func() {
 		resetErr := p.forceResetAllMM34646()
 		if resetErr != nil {
@@ -304,6 +360,22 @@ func (p *Plugin) OnActivate() error {
 	p.slaDigestCancel = cancel
 	go p.runSLADigestScheduler(ctx)
 
+	reminderCtx, reminderCancel := context.WithCancel(context.Background())
+	p.reviewReminderCancel = reminderCancel
+	go p.runReviewReminderScheduler(reminderCtx)
+
+	stalePRCtx, stalePRCancel := context.WithCancel(context.Background())
+	p.stalePRReminderCancel = stalePRCancel
+	go p.runStalePRReminderScheduler(stalePRCtx)
+
+	reactionSyncCtx, reactionSyncCancel := context.WithCancel(context.Background())
+	p.reactionSyncCancel = reactionSyncCancel
+	go p.runReactionSyncScheduler(reactionSyncCtx)
+
+	membershipCheckCtx, membershipCheckCancel := context.WithCancel(context.Background())
+	p.membershipCheckCancel = membershipCheckCancel
+	go p.runMembershipCheckScheduler(membershipCheckCtx)
+
 	return nil
 }
 
@@ -311,6 +383,18 @@ func (p *Plugin) OnDeactivate() error {
 	if p.slaDigestCancel != nil {
 		p.slaDigestCancel()
 	}
+	if p.reviewReminderCancel != nil {
+		p.reviewReminderCancel()
+	}
+	if p.stalePRReminderCancel != nil {
+		p.stalePRReminderCancel()
+	}
+	if p.reactionSyncCancel != nil {
+		p.reactionSyncCancel()
+	}
+	if p.membershipCheckCancel != nil {
+		p.membershipCheckCancel()
+	}
 	p.webhookBroker.Close()
 	p.oauthBroker.Close()
 	return nil
@@ -323,6 +407,11 @@ func (p *Plugin) getPostPropsForReaction(reaction *model.Reaction) (org, repo st
 		return org, repo, id, objectType, false
 	}
 
+	return p.getGitHubPostProps(post)
+}
+
+// getGitHubPostProps returns the GitHub object a notification post of the bot is about.
+func (p *Plugin) getGitHubPostProps(post *model.Post) (org, repo string, id float64, objectType string, ok bool) {
 	if post.UserId != p.BotUserID {
 		return org, repo, id, objectType, false
 	}
@@ -357,6 +446,11 @@ func (p *Plugin) getPostPropsForReaction(reaction *model.Reaction) (org, repo st
 }
 
 func (p *Plugin) ReactionHasBeenAdded(c *plugin.Context, reaction *model.Reaction) {
+	// The bot's reactions mirror reactions made on GitHub.
+	if reaction.UserId == p.BotUserID {
+		return
+	}
+
 	githubEmoji := p.emojiMap[reaction.EmojiName]
 	if githubEmoji == "" {
 		return
@@ -400,6 +494,11 @@ func (p *Plugin) ReactionHasBeenAdded(c *plugin.Context, reaction *model.Reactio
 }
 
 func (p *Plugin) ReactionHasBeenRemoved(c *plugin.Context, reaction *model.Reaction) {
+	// The bot's reactions mirror reactions made on GitHub.
+	if reaction.UserId == p.BotUserID {
+		return
+	}
+
 	githubEmoji := p.emojiMap[reaction.EmojiName]
 	if githubEmoji == "" {
 		return
@@ -531,8 +630,17 @@ func (p *Plugin) MessageWillBePosted(c *plugin.Context, post *model.Post) (*mode
 	// TODO: make this part of the Plugin struct and reuse it.
 	ghClient := p.githubConnectUser(context.Background(), info)
 
+	// Permalinks and link previews share one deadline, so a slow GitHub holds up the post for
+	// permalinkReqTimeout at most.
+	ctx, cancel := context.WithTimeout(context.Background(), permalinkReqTimeout)
+	defer cancel()
+
 	replacements := p.getReplacements(msg)
-	post.Message = p.makeReplacements(msg, replacements, ghClient)
+	post.Message = p.makeReplacements(ctx, msg, replacements, ghClient)
+
+	if attachments := p.makeLinkPreviewAttachments(ctx, p.getLinkPreviews(msg), ghClient); len(attachments) > 0 {
+		model.ParseSlackAttachment(post, append(post.Attachments(), attachments...))
+	}
 	return post, ""
 }
 
@@ -545,6 +653,10 @@ func (p *Plugin) getOAuthConfig(privateAllowed bool) (*oauth2.Config, error) {
 		repo = github.ScopeRepo
 	}
 	scopes := []string{string(repo), string(github.ScopeNotifications), string(github.ScopeReadOrg), string(github.ScopeAdminOrgHook)}
+	if config.RequireMatchingIdentity {
+		// Needed to read the verified email addresses of the account.
+		scopes = append(scopes, string(github.ScopeUserEmail))
+	}
 
 	if config.UsePreregisteredApplication {
 		p.client.Log.Debug("Using Chimera Proxy OAuth configuration")
@@ -639,12 +751,19 @@ func parseScopes(header string) []string {
 }
 
 type GitHubUserInfo struct {
-	UserID              string
-	Token               *oauth2.Token
-	GitHubUsername      string
-	LastToDoPostAt      int64
-	Settings            *UserSettings
-	AllowedPrivateRepos bool
+	UserID         string
+	Token          *oauth2.Token
+	GitHubUsername string
+	LastToDoPostAt int64
+	// LastStalePRReminderAt is when the weekly stale pull request reminder was last sent, in Unix milliseconds.
+	LastStalePRReminderAt int64
+	// ConnectedAt is when the user connected their GitHub account, in Unix milliseconds.
+	ConnectedAt int64
+	// MembershipDowngradedAt is when the user's notifications and reminders were turned off for not
+	// being a member of the configured organizations anymore, in Unix milliseconds.
+	MembershipDowngradedAt int64
+	Settings               *UserSettings
+	AllowedPrivateRepos    bool
 
 	// MM34646ResetTokenDone is set for a user whose token has been reset for MM-34646.
 	MM34646ResetTokenDone bool
@@ -655,6 +774,8 @@ type UserSettings struct {
 	DailyReminder         bool   `json:"daily_reminder"`
 	DailyReminderOnChange bool   `json:"daily_reminder_on_change"`
 	Notifications         bool   `json:"notifications"`
+	StalePRReminder       bool   `json:"stale_pr_reminder"`
+	ThreadReplySync       bool   `json:"thread_reply_sync"`
 }
 
 func (p *Plugin) storeGitHubUserInfo(info *GitHubUserInfo, encryptionKey string) error {
@@ -665,6 +786,14 @@ func (p *Plugin) storeGitHubUserInfo(info *GitHubUserInfo, encryptionKey string)
 
 	info.Token.AccessToken = encryptedToken
 
+	if info.Token.RefreshToken != "" {
+		encryptedRefreshToken, err := encrypt([]byte(encryptionKey), info.Token.RefreshToken)
+		if err != nil {
+			return errors.Wrap(err, "error occurred while encrypting refresh token")
+		}
+		info.Token.RefreshToken = encryptedRefreshToken
+	}
+
 	if _, err := p.store.Set(info.UserID+githubTokenKey, info); err != nil {
 		return errors.Wrap(err, "error occurred while trying to store user info into KV store")
 	}
@@ -684,7 +813,7 @@ func (p *Plugin) getGitHubUserInfo(userID string) (*GitHubUserInfo, *APIErrorRes
 		return nil, &APIErrorResponse{ID: apiErrorIDNotConnected, Message: "Must connect user account to GitHub first.", StatusCode: http.StatusBadRequest}
 	}
 
-	unencryptedToken, err := decrypt([]byte(config.EncryptionKey), userInfo.Token.AccessToken)
+	unencryptedToken, err := p.decryptToken([]byte(config.EncryptionKey), userInfo.Token.AccessToken)
 	if err != nil {
 		p.client.Log.Error("Failed to decrypt access token", "error", err.Error())
 		return nil, &APIErrorResponse{ID: "", Message: "Unable to decrypt access token.", StatusCode: http.StatusInternalServerError}
@@ -692,9 +821,37 @@ func (p *Plugin) getGitHubUserInfo(userID string) (*GitHubUserInfo, *APIErrorRes
 
 	userInfo.Token.AccessToken = unencryptedToken
 
+	unencryptedRefreshToken, err := p.decryptStoredRefreshToken([]byte(config.EncryptionKey), userInfo.Token.RefreshToken)
+	if err != nil {
+		p.client.Log.Error("Failed to decrypt refresh token", "error", err.Error())
+		return nil, &APIErrorResponse{ID: "", Message: "Unable to decrypt refresh token.", StatusCode: http.StatusInternalServerError}
+	}
+
+	userInfo.Token.RefreshToken = unencryptedRefreshToken
+
 	return userInfo, nil
 }
 
+// decryptToken decrypts a stored access token. Legacy AES-CFB ciphertexts aren't authenticated, so
+// they are rejected once migrateTokenEncryption recorded that no stored token uses them anymore.
+func (p *Plugin) decryptToken(key []byte, text string) (string, error) {
+	if p.legacyTokensMigrated.Load() && isLegacyCiphertext(text) {
+		return "", errLegacyCiphertext
+	}
+
+	return decrypt(key, text)
+}
+
+// decryptStoredRefreshToken decrypts a stored refresh token. Plain text ones are rejected once
+// migrateTokenEncryption recorded that all of them were encrypted.
+func (p *Plugin) decryptStoredRefreshToken(key []byte, text string) (string, error) {
+	if p.legacyTokensMigrated.Load() && text != "" && isLegacyCiphertext(text) {
+		return "", errLegacyCiphertext
+	}
+
+	return decryptRefreshToken(key, text)
+}
+
 func (p *Plugin) storeGitHubToUserIDMapping(githubUsername, userID string) error {
 	_, err := p.store.Set(githubUsername+githubUsernameKey, []byte(userID))
 	if err != nil {
@@ -704,6 +861,24 @@ func (p *Plugin) storeGitHubToUserIDMapping(githubUsername, userID string) error
 	return nil
 }
 
+// deleteGitHubToUserIDMapping deletes the mapping of githubUsername when it points to userID. It may
+// point to another user who connected the same GitHub account first, while userID hasn't taken it over.
+func (p *Plugin) deleteGitHubToUserIDMapping(githubUsername, userID string) error {
+	var data []byte
+	if err := p.store.Get(githubUsername+githubUsernameKey, &data); err != nil {
+		return errors.Wrap(err, "encountered error getting github username mapping")
+	}
+	if string(data) != userID {
+		return nil
+	}
+
+	if err := p.store.Delete(githubUsername + githubUsernameKey); err != nil {
+		return errors.Wrap(err, "encountered error deleting github username mapping")
+	}
+
+	return nil
+}
+
 func (p *Plugin) getGitHubToUserIDMapping(githubUsername string) string {
 	var data []byte
 	err := p.store.Get(githubUsername+githubUsernameKey, &data)
@@ -725,7 +900,9 @@ func (p *Plugin) getGitHubToUsernameMapping(githubUsername string) string {
 	return user.Username
 }
 
-func (p *Plugin) disconnectGitHubAccount(userID string) {
+// disconnectGitHubAccount disconnects the user from GitHub. actorID is the user who asked for it,
+// or empty when the plugin did.
+func (p *Plugin) disconnectGitHubAccount(actorID, userID string) {
 	userInfo, apiErr := p.getGitHubUserInfo(userID)
 	if apiErr != nil {
 		if apiErr.ID == apiErrorIDNotConnected {
@@ -743,7 +920,7 @@ func (p *Plugin) disconnectGitHubAccount(userID string) {
 		if rawInfo != nil {
 			githubUsername = rawInfo.GitHubUsername
 		}
-		p.forceDisconnectUser(userID, githubUsername)
+		p.forceDisconnectUser(actorID, userID, githubUsername, configurationResetDisconnectMessage)
 		return
 	}
 
@@ -751,7 +928,7 @@ func (p *Plugin) disconnectGitHubAccount(userID string) {
 		p.client.Log.Warn("Failed to delete github token from KV store", "userID", userID, "error", err.Error())
 	}
 
-	if err := p.store.Delete(userInfo.GitHubUsername + githubUsernameKey); err != nil {
+	if err := p.deleteGitHubToUserIDMapping(userInfo.GitHubUsername, userID); err != nil {
 		p.client.Log.Warn("Failed to delete github username mapping from KV store", "userID", userID, "error", err.Error())
 	}
 
@@ -793,22 +970,10 @@ func (p *Plugin) reEncryptUserData(newEncryptionKey, previousEncryptionKey strin
 	m.Lock()
 	defer m.Unlock()
 
-	checker := func(key string) (keep bool, err error) {
-		return strings.HasSuffix(key, githubTokenKey), nil
-	}
-
-	var allKeys []string
-	for page := 0; ; page++ {
-		keys, err := p.store.ListKeys(page, keysPerPage, pluginapi.WithChecker(checker))
-		if err != nil {
-			p.client.Log.Warn("Encryption key changed but failed to list user keys for re-encryption, proceeding with keys collected so far",
-				"page", fmt.Sprintf("%d", page), "keys_collected", fmt.Sprintf("%d", len(allKeys)), "error", err.Error())
-			break
-		}
-		allKeys = append(allKeys, keys...)
-		if len(keys) < keysPerPage {
-			break
-		}
+	allKeys, page, err := p.listUserTokenKeys()
+	if err != nil {
+		p.client.Log.Warn("Encryption key changed but failed to list user keys for re-encryption, proceeding with keys collected so far",
+			"page", fmt.Sprintf("%d", page), "keys_collected", fmt.Sprintf("%d", len(allKeys)), "error", err.Error())
 	}
 
 	if len(allKeys) == 0 {
@@ -833,7 +998,7 @@ func (p *Plugin) reEncryptUserData(newEncryptionKey, previousEncryptionKey strin
 			p.client.Log.Warn("Failed to re-encrypt user token during encryption key rotation",
 				"user_id", userID, "error", err.Error())
 			auditRec.AddErrorDesc(fmt.Sprintf("user %s: %s", userID, err.Error()))
-			p.forceDisconnectUser(userID, githubUsername)
+			p.forceDisconnectUser("", userID, githubUsername, configurationResetDisconnectMessage)
 			forceDisconnected++
 		} else {
 			migrated++
@@ -849,6 +1014,47 @@ func (p *Plugin) reEncryptUserData(newEncryptionKey, previousEncryptionKey strin
 	})
 }
 
+// listUserTokenKeys returns the KV keys of all connected users' tokens. When listing fails, the
+// keys collected so far are returned along with the page that failed.
+func (p *Plugin) listUserTokenKeys() ([]string, int, error) {
+	return p.listKeysWithSuffix(githubTokenKey)
+}
+
+// listKeysWithSuffix returns the KV keys ending with suffix. When listing fails, the keys
+// collected so far are returned along with the page that failed.
+func (p *Plugin) listKeysWithSuffix(suffix string) ([]string, int, error) {
+	return p.listKeysMatching(func(key string) bool {
+		return strings.HasSuffix(key, suffix)
+	})
+}
+
+// listKeysWithPrefix returns the KV keys starting with prefix. When listing fails, the keys
+// collected so far are returned along with the page that failed.
+func (p *Plugin) listKeysWithPrefix(prefix string) ([]string, int, error) {
+	return p.listKeysMatching(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// listKeysMatching pages through the KV keys, returning those match accepts.
+func (p *Plugin) listKeysMatching(match func(key string) bool) ([]string, int, error) {
+	checker := func(key string) (keep bool, err error) {
+		return match(key), nil
+	}
+
+	var allKeys []string
+	for page := 0; ; page++ {
+		keys, err := p.store.ListKeys(page, keysPerPage, pluginapi.WithChecker(checker))
+		if err != nil {
+			return allKeys, page, err
+		}
+		allKeys = append(allKeys, keys...)
+		if len(keys) < keysPerPage {
+			return allKeys, page, nil
+		}
+	}
+}
+
 // reEncryptUserToken decrypts a single user's token with the old key and
 // re-encrypts it with the new key. Returns the GitHub username (best-effort,
 // may be empty) and any error encountered.
@@ -865,16 +1071,30 @@ func (p *Plugin) reEncryptUserToken(kvKey, newEncryptionKey, previousEncryptionK
 		return userInfo.GitHubUsername, errors.New("user has no token to re-encrypt")
 	}
 
-	if _, err := decrypt([]byte(newEncryptionKey), userInfo.Token.AccessToken); err == nil {
-		return userInfo.GitHubUsername, nil
+	// AES-GCM ciphertexts are authenticated, so decrypting one proves it already uses the new key.
+	// Legacy ones can decrypt with the wrong key, so they are always re-encrypted.
+	if !isLegacyCiphertext(userInfo.Token.AccessToken) {
+		if _, err := decrypt([]byte(newEncryptionKey), userInfo.Token.AccessToken); err == nil {
+			return userInfo.GitHubUsername, nil
+		}
 	}
 
-	plainToken, err := decrypt([]byte(previousEncryptionKey), userInfo.Token.AccessToken)
+	plainToken, err := p.decryptToken([]byte(previousEncryptionKey), userInfo.Token.AccessToken)
+	if err != nil && !errors.Is(err, errLegacyCiphertext) && isLegacyCiphertext(userInfo.Token.AccessToken) {
+		// Stored with the new key by a node running a version without AES-GCM.
+		plainToken, err = p.decryptToken([]byte(newEncryptionKey), userInfo.Token.AccessToken)
+	}
 	if err != nil {
 		return userInfo.GitHubUsername, errors.Wrap(err, "could not decrypt token with previous key")
 	}
 
+	plainRefreshToken, err := p.decryptStoredRefreshToken([]byte(previousEncryptionKey), userInfo.Token.RefreshToken)
+	if err != nil {
+		return userInfo.GitHubUsername, errors.Wrap(err, "could not decrypt refresh token with previous key")
+	}
+
 	userInfo.Token.AccessToken = plainToken
+	userInfo.Token.RefreshToken = plainRefreshToken
 	if err := p.storeGitHubUserInfo(userInfo, newEncryptionKey); err != nil {
 		return userInfo.GitHubUsername, errors.Wrap(err, "could not store re-encrypted token")
 	}
@@ -882,12 +1102,152 @@ func (p *Plugin) reEncryptUserToken(kvKey, newEncryptionKey, previousEncryptionK
 	return userInfo.GitHubUsername, nil
 }
 
+// migrateTokenEncryption re-encrypts the tokens still stored in the legacy AES-CFB format with
+// AES-GCM, along with refresh tokens stored in plain text. It shares the mutex of reEncryptUserData, so it doesn't race with a key rotation.
+// Once no legacy token is left, that is recorded so the legacy format is rejected from then on.
+func (p *Plugin) migrateTokenEncryption() {
+	m, err := cluster.NewMutex(p.API, reEncryptMutexKey)
+	if err != nil {
+		p.client.Log.Warn("Failed to create cluster mutex for token encryption migration", "error", err.Error())
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+
+	// Read after locking, as another node may have finished the migration in the meantime.
+	var done []byte
+	if err = p.store.Get(tokenEncryptionMigratedKey, &done); err != nil {
+		p.client.Log.Warn("Failed to check whether the token encryption migration is done", "error", err.Error())
+		return
+	}
+	if len(done) > 0 {
+		p.legacyTokensMigrated.Store(true)
+		return
+	}
+
+	// Read after locking, as a key rotation may have finished in the meantime.
+	encryptionKey := p.getConfiguration().EncryptionKey
+	if encryptionKey == "" {
+		return
+	}
+
+	allKeys, page, listErr := p.listUserTokenKeys()
+	if listErr != nil {
+		p.client.Log.Warn("Failed to list user keys for token encryption migration, proceeding with keys collected so far",
+			"page", fmt.Sprintf("%d", page), "keys_collected", fmt.Sprintf("%d", len(allKeys)), "error", listErr.Error())
+	}
+
+	// Only tokens that were all seen can be known to be migrated.
+	complete := listErr == nil
+	var legacyUsers []*GitHubUserInfo
+	for _, key := range allKeys {
+		var userInfo *GitHubUserInfo
+		if err := p.store.Get(key, &userInfo); err != nil {
+			p.client.Log.Warn("Failed to load user info for token encryption migration", "key", key, "error", err.Error())
+			complete = false
+			continue
+		}
+		if userInfo == nil || userInfo.Token == nil || userInfo.Token.AccessToken == "" {
+			continue
+		}
+		if isLegacyCiphertext(userInfo.Token.AccessToken) ||
+			(userInfo.Token.RefreshToken != "" && isLegacyCiphertext(userInfo.Token.RefreshToken)) {
+			legacyUsers = append(legacyUsers, userInfo)
+		}
+	}
+
+	if len(legacyUsers) == 0 {
+		if complete {
+			p.finishTokenEncryptionMigration()
+		}
+		return
+	}
+
+	auditRec := plugin.MakeAuditRecord("migrateTokenEncryption", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	model.AddEventParameterAuditableToAuditRec(auditRec, "migrate_token_encryption", MigrateTokenEncryptionAuditParams{
+		LegacyTokens: len(legacyUsers),
+	})
+
+	p.client.Log.Info("Migrating user tokens to AES-GCM encryption", "user_count", fmt.Sprintf("%d", len(legacyUsers)))
+
+	var migrated, failed int
+	for _, userInfo := range legacyUsers {
+		plainToken, err := decrypt([]byte(encryptionKey), userInfo.Token.AccessToken)
+		var plainRefreshToken string
+		if err == nil {
+			plainRefreshToken, err = decryptRefreshToken([]byte(encryptionKey), userInfo.Token.RefreshToken)
+		}
+		if err == nil {
+			userInfo.Token.AccessToken = plainToken
+			userInfo.Token.RefreshToken = plainRefreshToken
+			err = p.storeGitHubUserInfo(userInfo, encryptionKey)
+		}
+		if err != nil {
+			p.client.Log.Warn("Failed to migrate user token to AES-GCM encryption", "user_id", userInfo.UserID, "error", err.Error())
+			auditRec.AddErrorDesc(fmt.Sprintf("user %s: %s", userInfo.UserID, err.Error()))
+			failed++
+			continue
+		}
+		migrated++
+	}
+
+	if failed == 0 {
+		auditRec.Success()
+	}
+	auditRec.AddEventResultState(MigrateTokenEncryptionAuditResult{
+		Migrated: migrated,
+		Failed:   failed,
+	})
+
+	if complete && failed == 0 {
+		p.finishTokenEncryptionMigration()
+	}
+}
+
+// finishTokenEncryptionMigration records that no stored token uses the legacy AES-CFB format
+// anymore. Other nodes pick it up the next time they run migrateTokenEncryption.
+func (p *Plugin) finishTokenEncryptionMigration() {
+	if _, err := p.store.Set(tokenEncryptionMigratedKey, []byte("done")); err != nil {
+		p.client.Log.Warn("Failed to record that the token encryption migration is done", "error", err.Error())
+		return
+	}
+
+	p.legacyTokensMigrated.Store(true)
+	p.client.Log.Info("All user tokens use AES-GCM encryption, legacy ciphertexts are no longer accepted")
+}
+
 // forceDisconnectUser performs a best-effort cleanup of a user's encrypted
-// data and notifies them to reconnect
-func (p *Plugin) forceDisconnectUser(userID, githubUsername string) {
-	if err := p.store.Delete(userID + githubTokenKey); err != nil {
+// data, notifies them with message and records it in the audit log. actorID is
+// the user the disconnect was done for, or empty when the plugin did it on its own.
+func (p *Plugin) forceDisconnectUser(actorID, userID, githubUsername, message string) {
+	auditRec := plugin.MakeAuditRecord("forceDisconnectUser", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = actorID
+
+	githubUsername, err := p.removeGitHubConnection(userID, githubUsername, message)
+	if err != nil {
+		auditRec.AddErrorDesc(err.Error())
+	}
+
+	// The GitHub username may only be known from the user props, so the parameters are added last.
+	model.AddEventParameterAuditableToAuditRec(auditRec, "user", ForceDisconnectUserAuditParams{
+		UserID:         userID,
+		GitHubUsername: githubUsername,
+	})
+	if err == nil {
+		auditRec.Success()
+	}
+}
+
+// removeGitHubConnection does the cleanup of forceDisconnectUser without the audit record, for
+// callers recording the disconnect themselves. It returns the GitHub username of the connection,
+// and an error when the token couldn't be deleted.
+func (p *Plugin) removeGitHubConnection(userID, githubUsername, message string) (string, error) {
+	tokenErr := p.store.Delete(userID + githubTokenKey)
+	if tokenErr != nil {
 		p.client.Log.Warn("forceDisconnectUser: failed to delete github token",
-			"user_id", userID, "error", err.Error())
+			"user_id", userID, "error", tokenErr.Error())
 	}
 
 	if err := p.store.Delete(userID + githubPrivateRepoKey); err != nil {
@@ -915,7 +1275,7 @@ func (p *Plugin) forceDisconnectUser(userID, githubUsername string) {
 	}
 
 	if githubUsername != "" {
-		if err := p.store.Delete(githubUsername + githubUsernameKey); err != nil {
+		if err := p.deleteGitHubToUserIDMapping(githubUsername, userID); err != nil {
 			p.client.Log.Warn("forceDisconnectUser: failed to delete username mapping",
 				"user_id", userID, "error", err.Error())
 		}
@@ -927,9 +1287,9 @@ func (p *Plugin) forceDisconnectUser(userID, githubUsername string) {
 		&model.WebsocketBroadcast{UserId: userID},
 	)
 
-	p.CreateBotDMPost(userID,
-		"Your GitHub connection has been reset due to a change in the plugin configuration. Please reconnect your account using `/github connect`.",
-		"custom_git_disconnect")
+	p.CreateBotDMPost(userID, message, "custom_git_disconnect")
+
+	return githubUsername, tokenErr
 }
 
 func (p *Plugin) openIssueCreateModal(userID string, channelID string, title string) {
@@ -946,18 +1306,24 @@ func (p *Plugin) openIssueCreateModal(userID string, channelID string, title str
 // CreateBotDMPost posts a direct message using the bot account.
 // Any error are not returned and instead logged.
 func (p *Plugin) CreateBotDMPost(userID, message, postType string) {
+	p.createBotDMPost(userID, &model.Post{
+		Message: message,
+		Type:    postType,
+	})
+}
+
+// createBotDMPost posts the given post as a direct message from the bot account, filling in the
+// author and channel. Any error are not returned and instead logged.
+func (p *Plugin) createBotDMPost(userID string, post *model.Post) {
 	channel, err := p.client.Channel.GetDirect(userID, p.BotUserID)
 	if err != nil {
 		p.client.Log.Warn("Couldn't get bot's DM channel", "userID", userID, "error", err.Error())
 		return
 	}
 
-	post := &model.Post{
-		UserId:    p.BotUserID,
-		ChannelId: channel.Id,
-		Message:   truncatePostMessage(message),
-		Type:      postType,
-	}
+	post.UserId = p.BotUserID
+	post.ChannelId = channel.Id
+	post.Message = truncatePostMessage(post.Message)
 
 	if err = p.client.Post.CreatePost(post); err != nil {
 		p.client.Log.Warn("Failed to create DM post", "user_id", userID, "channel_id", post.ChannelId, "error", err.Error())
@@ -1345,11 +1711,16 @@ func (p *Plugin) getUsername(mmUserID string) (string, error) {
 
 func (p *Plugin) useGitHubClient(info *GitHubUserInfo, toRun func(info *GitHubUserInfo, token *oauth2.Token) error) error {
 	err := toRun(info, info.Token)
-	if err != nil {
+	if err == nil {
+		p.recordSuccessfulAPICall(info.UserID)
+	}
+	if err != nil && isRateLimitError(err) {
+		p.client.Log.Warn("GitHub rate limit exceeded", "user_id", info.UserID, "error", err.Error())
+	} else if err != nil {
 		p.client.Log.Warn("Error occurred while using the Github client", "error", err.Error())
 	}
 
-	if err != nil && strings.Contains(err.Error(), invalidTokenError) {
+	if err != nil && p.isTokenRevoked(info, err) {
 		p.handleRevokedToken(info)
 	}
 
@@ -1357,6 +1728,6 @@ func (p *Plugin) useGitHubClient(info *GitHubUserInfo, toRun func(info *GitHubUs
 }
 
 func (p *Plugin) handleRevokedToken(info *GitHubUserInfo) {
-	p.disconnectGitHubAccount(info.UserID)
+	p.disconnectGitHubAccount("", info.UserID)
 	p.CreateBotDMPost(info.UserID, "Your Github account was disconnected due to an invalid or revoked authorization token. Reconnect your account using the `/github connect` command.", "custom_git_revoked_token")
 }
@@ -11,6 +11,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/oauth2"
@@ -115,6 +116,7 @@ func TestReEncryptUserData_DecryptFailure(t *testing.T) {
 	// forceDisconnectUser expectations
 	mockKvStore.EXPECT().Delete("user1" + githubTokenKey).Return(nil)
 	mockKvStore.EXPECT().Delete("user1" + githubPrivateRepoKey).Return(nil)
+	expectUserIDMapping(mockKvStore, "ghuser1", "user1")
 	mockKvStore.EXPECT().Delete("ghuser1" + githubUsernameKey).Return(nil)
 
 	api.On("LogInfo", mock.Anything, mock.Anything, mock.Anything).Maybe()
@@ -163,6 +165,7 @@ func TestReEncryptUserData_StoreFailure(t *testing.T) {
 	// forceDisconnectUser expectations
 	mockKvStore.EXPECT().Delete("user1" + githubTokenKey).Return(nil)
 	mockKvStore.EXPECT().Delete("user1" + githubPrivateRepoKey).Return(nil)
+	expectUserIDMapping(mockKvStore, "ghuser1", "user1")
 	mockKvStore.EXPECT().Delete("ghuser1" + githubUsernameKey).Return(nil)
 
 	api.On("LogInfo", mock.Anything, mock.Anything, mock.Anything).Maybe()
@@ -281,12 +284,145 @@ func TestReEncryptUserData_AlreadyMigratedToken(t *testing.T) {
 	api.AssertExpectations(t)
 }
 
+func TestReEncryptUserData_LegacyToken(t *testing.T) {
+	p, api, mockKvStore, ctrl := setupRotationTest(t)
+	defer ctrl.Finish()
+
+	userInfo := &GitHubUserInfo{
+		UserID:         "user1",
+		GitHubUsername: "ghuser1",
+		Token:          &oauth2.Token{AccessToken: encryptLegacy(t, []byte(testOldKey), MockAccessToken)},
+		Settings:       &UserSettings{},
+	}
+	userInfoBytes, err := json.Marshal(userInfo)
+	require.NoError(t, err)
+
+	mockKvStore.EXPECT().ListKeys(0, keysPerPage, gomock.Any()).Return([]string{"user1" + githubTokenKey}, nil)
+	mockKvStore.EXPECT().Get("user1"+githubTokenKey, gomock.Any()).DoAndReturn(
+		func(key string, out any) error { return json.Unmarshal(userInfoBytes, out) },
+	)
+	mockKvStore.EXPECT().Set("user1"+githubTokenKey, gomock.Any()).DoAndReturn(
+		func(key string, value any, opts ...pluginapi.KVSetOption) (bool, error) {
+			storedInfo, ok := value.(*GitHubUserInfo)
+			require.True(t, ok, "expected *GitHubUserInfo")
+			require.False(t, isLegacyCiphertext(storedInfo.Token.AccessToken))
+			decrypted, decErr := decrypt([]byte(testNewKey), storedInfo.Token.AccessToken)
+			require.NoError(t, decErr)
+			require.Equal(t, MockAccessToken, decrypted)
+			return true, nil
+		},
+	)
+
+	api.On("LogInfo", "Encryption key changed, re-encrypting user tokens",
+		"user_count", "1").Times(1)
+
+	p.reEncryptUserData(testNewKey, testOldKey)
+
+	api.AssertExpectations(t)
+}
+
+func TestMigrateTokenEncryption(t *testing.T) {
+	p, api, mockKvStore, ctrl := setupRotationTest(t)
+	defer ctrl.Finish()
+	p.setConfiguration(&Configuration{EncryptionKey: testNewKey})
+
+	encrypted, err := encrypt([]byte(testNewKey), "token_user1")
+	require.NoError(t, err)
+	users := map[string]*GitHubUserInfo{
+		"user1": {UserID: "user1", Token: &oauth2.Token{AccessToken: encrypted}, Settings: &UserSettings{}},
+		"user2": {UserID: "user2", Token: &oauth2.Token{AccessToken: encryptLegacy(t, []byte(testNewKey), "token_user2")}, Settings: &UserSettings{}},
+		"user3": {UserID: "user3", Token: &oauth2.Token{AccessToken: "not-valid-base64-ciphertext!@#$"}, Settings: &UserSettings{}},
+	}
+
+	mockKvStore.EXPECT().Get(tokenEncryptionMigratedKey, gomock.Any()).Return(nil)
+	mockKvStore.EXPECT().ListKeys(0, keysPerPage, gomock.Any()).Return(
+		[]string{"user1" + githubTokenKey, "user2" + githubTokenKey, "user3" + githubTokenKey}, nil)
+	for userID, userInfo := range users {
+		userInfoBytes, err := json.Marshal(userInfo)
+		require.NoError(t, err)
+		mockKvStore.EXPECT().Get(userID+githubTokenKey, gomock.Any()).DoAndReturn(
+			func(key string, out any) error { return json.Unmarshal(userInfoBytes, out) },
+		)
+	}
+	mockKvStore.EXPECT().Set("user2"+githubTokenKey, gomock.Any()).DoAndReturn(
+		func(key string, value any, opts ...pluginapi.KVSetOption) (bool, error) {
+			storedInfo, ok := value.(*GitHubUserInfo)
+			require.True(t, ok, "expected *GitHubUserInfo")
+			require.False(t, isLegacyCiphertext(storedInfo.Token.AccessToken))
+			decrypted, decErr := decrypt([]byte(testNewKey), storedInfo.Token.AccessToken)
+			require.NoError(t, decErr)
+			require.Equal(t, "token_user2", decrypted)
+			return true, nil
+		},
+	)
+
+	api.On("LogInfo", "Migrating user tokens to AES-GCM encryption", "user_count", "2").Times(1)
+	api.On("LogWarn", "Failed to migrate user token to AES-GCM encryption", "user_id", "user3", "error", mock.Anything).Times(1)
+
+	p.migrateTokenEncryption()
+
+	api.AssertExpectations(t)
+	// user3's token couldn't be migrated, so the legacy format is still accepted.
+	assert.False(t, p.legacyTokensMigrated.Load())
+}
+
+func TestMigrateTokenEncryption_RejectsLegacyCiphertextsAfterwards(t *testing.T) {
+	p, api, mockKvStore, ctrl := setupRotationTest(t)
+	defer ctrl.Finish()
+
+	legacy := encryptLegacy(t, []byte(testNewKey), "token_user1")
+	userInfoBytes, err := json.Marshal(&GitHubUserInfo{UserID: "user1", Token: &oauth2.Token{AccessToken: legacy}, Settings: &UserSettings{}})
+	require.NoError(t, err)
+
+	mockKvStore.EXPECT().Get(tokenEncryptionMigratedKey, gomock.Any()).Return(nil)
+	mockKvStore.EXPECT().ListKeys(0, keysPerPage, gomock.Any()).Return([]string{"user1" + githubTokenKey}, nil)
+	mockKvStore.EXPECT().Get("user1"+githubTokenKey, gomock.Any()).DoAndReturn(
+		func(key string, out any) error { return json.Unmarshal(userInfoBytes, out) },
+	)
+	mockKvStore.EXPECT().Set("user1"+githubTokenKey, gomock.Any()).Return(true, nil)
+	mockKvStore.EXPECT().Set(tokenEncryptionMigratedKey, []byte("done")).Return(true, nil)
+
+	api.On("LogInfo", "Migrating user tokens to AES-GCM encryption", "user_count", "1").Times(1)
+	api.On("LogInfo", "All user tokens use AES-GCM encryption, legacy ciphertexts are no longer accepted").Times(1)
+
+	p.migrateTokenEncryption()
+
+	api.AssertExpectations(t)
+	require.True(t, p.legacyTokensMigrated.Load())
+
+	_, err = p.decryptToken([]byte(testNewKey), legacy)
+	assert.ErrorIs(t, err, errLegacyCiphertext)
+	_, err = p.decryptStoredRefreshToken([]byte(testNewKey), "plain-refresh-token")
+	assert.ErrorIs(t, err, errLegacyCiphertext)
+
+	encrypted, err := encrypt([]byte(testNewKey), "token_user1")
+	require.NoError(t, err)
+	decrypted, err := p.decryptToken([]byte(testNewKey), encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "token_user1", decrypted)
+}
+
+func TestMigrateTokenEncryption_AlreadyDone(t *testing.T) {
+	p, _, mockKvStore, ctrl := setupRotationTest(t)
+	defer ctrl.Finish()
+
+	mockKvStore.EXPECT().Get(tokenEncryptionMigratedKey, gomock.Any()).DoAndReturn(func(key string, out any) error {
+		*out.(*[]byte) = []byte("done")
+		return nil
+	})
+
+	p.migrateTokenEncryption()
+
+	assert.True(t, p.legacyTokensMigrated.Load())
+}
+
 func TestForceDisconnectUser_CleansUpAndNotifies(t *testing.T) {
 	p, api, mockKvStore, ctrl := setupRotationTest(t)
 	defer ctrl.Finish()
 
 	mockKvStore.EXPECT().Delete("user1" + githubTokenKey).Return(nil)
 	mockKvStore.EXPECT().Delete("user1" + githubPrivateRepoKey).Return(nil)
+	expectUserIDMapping(mockKvStore, "ghuser1", "user1")
 	mockKvStore.EXPECT().Delete("ghuser1" + githubUsernameKey).Return(nil)
 
 	api.On("GetUser", "user1").Return(&model.User{
@@ -306,7 +442,33 @@ func TestForceDisconnectUser_CleansUpAndNotifies(t *testing.T) {
 			post.Type == "custom_git_disconnect"
 	})).Return(&model.Post{}, nil)
 
-	p.forceDisconnectUser("user1", "ghuser1")
+	p.forceDisconnectUser("", "user1", "ghuser1", configurationResetDisconnectMessage)
+
+	api.AssertExpectations(t)
+}
+
+func TestDisconnectGitHubAccount_PendingTakeoverKeepsOwnerMapping(t *testing.T) {
+	p, api, mockKvStore, ctrl := setupRotationTest(t)
+	defer ctrl.Finish()
+
+	encryptedToken, err := encrypt([]byte(testNewKey), MockAccessToken)
+	require.NoError(t, err)
+	userInfoBytes, err := json.Marshal(&GitHubUserInfo{UserID: "user2", GitHubUsername: "ghuser1", Token: &oauth2.Token{AccessToken: encryptedToken}, Settings: &UserSettings{}})
+	require.NoError(t, err)
+
+	mockKvStore.EXPECT().Get("user2"+githubTokenKey, gomock.Any()).DoAndReturn(
+		func(key string, out any) error { return json.Unmarshal(userInfoBytes, out) },
+	)
+	mockKvStore.EXPECT().Delete("user2" + githubTokenKey).Return(nil)
+	mockKvStore.EXPECT().Delete("user2" + githubPrivateRepoKey).Return(nil)
+	// user2 never took the GitHub account over, so user1 keeps the mapping.
+	expectUserIDMapping(mockKvStore, "ghuser1", "user1")
+
+	api.On("GetUser", "user2").Return(&model.User{Id: "user2", Props: model.StringMap{}}, nil)
+	api.On("PublishWebSocketEvent", wsEventDisconnect, map[string]any(nil),
+		&model.WebsocketBroadcast{UserId: "user2"}).Times(1)
+
+	p.disconnectGitHubAccount("user2", "user2")
 
 	api.AssertExpectations(t)
 }
@@ -318,6 +480,7 @@ func TestForceDisconnectUser_NoGitHubUsername_FallbackFromProps(t *testing.T) {
 	mockKvStore.EXPECT().Delete("user1" + githubTokenKey).Return(nil)
 	mockKvStore.EXPECT().Delete("user1" + githubPrivateRepoKey).Return(nil)
 	// Username recovered from user props, so the mapping delete should happen
+	expectUserIDMapping(mockKvStore, "ghuser1", "user1")
 	mockKvStore.EXPECT().Delete("ghuser1" + githubUsernameKey).Return(nil)
 
 	api.On("GetUser", "user1").Return(&model.User{
@@ -330,7 +493,7 @@ func TestForceDisconnectUser_NoGitHubUsername_FallbackFromProps(t *testing.T) {
 	api.On("GetDirectChannel", "user1", MockBotID).Return(&model.Channel{Id: "dmchannel"}, nil)
 	api.On("CreatePost", mock.Anything).Return(&model.Post{}, nil)
 
-	p.forceDisconnectUser("user1", "")
+	p.forceDisconnectUser("", "user1", "", configurationResetDisconnectMessage)
 
 	api.AssertExpectations(t)
 }
@@ -352,7 +515,7 @@ func TestForceDisconnectUser_NoGitHubUsername_NoPropsFallback(t *testing.T) {
 	api.On("GetDirectChannel", "user1", MockBotID).Return(&model.Channel{Id: "dmchannel"}, nil)
 	api.On("CreatePost", mock.Anything).Return(&model.Post{}, nil)
 
-	p.forceDisconnectUser("user1", "")
+	p.forceDisconnectUser("", "user1", "", configurationResetDisconnectMessage)
 
 	api.AssertExpectations(t)
 }
@@ -363,6 +526,7 @@ func TestForceDisconnectUser_DeleteErrors(t *testing.T) {
 
 	mockKvStore.EXPECT().Delete("user1" + githubTokenKey).Return(errors.New("delete failed"))
 	mockKvStore.EXPECT().Delete("user1" + githubPrivateRepoKey).Return(errors.New("delete failed"))
+	expectUserIDMapping(mockKvStore, "ghuser1", "user1")
 	mockKvStore.EXPECT().Delete("ghuser1" + githubUsernameKey).Return(errors.New("delete failed"))
 
 	api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
@@ -372,7 +536,7 @@ func TestForceDisconnectUser_DeleteErrors(t *testing.T) {
 	api.On("GetDirectChannel", "user1", MockBotID).Return(&model.Channel{Id: "dmchannel"}, nil)
 	api.On("CreatePost", mock.Anything).Return(&model.Post{}, nil)
 
-	p.forceDisconnectUser("user1", "ghuser1")
+	p.forceDisconnectUser("", "user1", "ghuser1", configurationResetDisconnectMessage)
 
 	api.AssertExpectations(t)
 }
@@ -0,0 +1,285 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v54/github"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// rateLimitLowBudgetRatio is the share of a token's budget under which non-interactive work
+	// such as digests and reminders is deferred, so users keep their quota for their own actions.
+	rateLimitLowBudgetRatio = 0.1
+	// rateLimitMaxRetries is how many times a rate limited request is retried.
+	rateLimitMaxRetries = 2
+	// rateLimitMaxRetryWait is the longest a request waits before being retried. Longer waits
+	// fail the request instead of blocking the caller.
+	rateLimitMaxRetryWait = 30 * time.Second
+
+	headerRateLimitLimit     = "X-RateLimit-Limit"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+	headerRateLimitResource  = "X-RateLimit-Resource"
+	headerRetryAfter         = "Retry-After"
+)
+
+// rateLimitBudget is the latest rate limit state GitHub reported for a token and resource.
+type rateLimitBudget struct {
+	TokenID   string    `yaml:"token_id"`
+	Resource  string    `yaml:"resource"`
+	Limit     int       `yaml:"limit"`
+	Remaining int       `yaml:"remaining"`
+	Reset     time.Time `yaml:"reset"`
+}
+
+func (b rateLimitBudget) isLow(now time.Time) bool {
+	return now.Before(b.Reset) && float64(b.Remaining) < float64(b.Limit)*rateLimitLowBudgetRatio
+}
+
+// rateLimitTracker records the budgets of the tokens the plugin uses, shared by all their clients.
+type rateLimitTracker struct {
+	lock    sync.Mutex
+	budgets map[string]rateLimitBudget
+}
+
+func newRateLimitTracker() *rateLimitTracker {
+	return &rateLimitTracker{budgets: map[string]rateLimitBudget{}}
+}
+
+// rateLimitTokenID identifies a token without keeping the token itself.
+func rateLimitTokenID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:6])
+}
+
+func (t *rateLimitTracker) record(tokenID string, header http.Header) {
+	limit, err := strconv.Atoi(header.Get(headerRateLimitLimit))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(header.Get(headerRateLimitRemaining))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get(headerRateLimitReset), 10, 64)
+	if err != nil {
+		return
+	}
+	resource := header.Get(headerRateLimitResource)
+	if resource == "" {
+		resource = "core"
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.pruneLocked(time.Now())
+	t.budgets[tokenID+"/"+resource] = rateLimitBudget{
+		TokenID:   tokenID,
+		Resource:  resource,
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(reset, 0),
+	}
+}
+
+// isLow returns if any budget of the token is below rateLimitLowBudgetRatio until its reset.
+func (t *rateLimitTracker) isLow(token string) bool {
+	tokenID := rateLimitTokenID(token)
+	now := time.Now()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.pruneLocked(now)
+	for _, budget := range t.budgets {
+		if budget.TokenID == tokenID && budget.isLow(now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// list returns the budgets that haven't been reset yet, sorted by token and resource.
+func (t *rateLimitTracker) list() []rateLimitBudget {
+	now := time.Now()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.pruneLocked(now)
+	budgets := make([]rateLimitBudget, 0, len(t.budgets))
+	for _, budget := range t.budgets {
+		budgets = append(budgets, budget)
+	}
+	sort.Slice(budgets, func(i, j int) bool {
+		if budgets[i].TokenID != budgets[j].TokenID {
+			return budgets[i].TokenID < budgets[j].TokenID
+		}
+		return budgets[i].Resource < budgets[j].Resource
+	})
+
+	return budgets
+}
+
+// pruneLocked drops the budgets that have been reset, so tokens that are no longer used don't
+// accumulate. The caller must hold t.lock.
+func (t *rateLimitTracker) pruneLocked(now time.Time) {
+	for key, budget := range t.budgets {
+		if !now.Before(budget.Reset) {
+			delete(t.budgets, key)
+		}
+	}
+}
+
+// rateLimitTransport records the budgets reported by GitHub and retries rate limited requests
+// once GitHub allows it, when that is soon enough. It sits below the oauth2 transport so it
+// sees the token of each request.
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	tracker *rateLimitTracker
+	// onSuccess, if set, is called after each request GitHub accepted.
+	onSuccess func()
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	_, token, _ := strings.Cut(req.Header.Get("Authorization"), " ")
+	tokenID := ""
+	if token != "" {
+		tokenID = rateLimitTokenID(token)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		if tokenID != "" {
+			t.tracker.record(tokenID, resp.Header)
+		}
+
+		if t.onSuccess != nil && resp.StatusCode < http.StatusBadRequest {
+			t.onSuccess()
+		}
+
+		wait, limited := getRateLimitRetryWait(resp, time.Now())
+		if !limited || attempt >= rateLimitMaxRetries || wait > rateLimitMaxRetryWait {
+			return resp, nil
+		}
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		req = req.Clone(req.Context())
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// getRateLimitRetryWait returns how long to wait before retrying resp's request, and whether
+// the request was rate limited at all.
+func getRateLimitRetryWait(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	// Secondary rate limits tell how long to wait, in seconds or as an HTTP date.
+	if retryAfter := resp.Header.Get(headerRetryAfter); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		retryAt, err := http.ParseTime(retryAfter)
+		if err != nil {
+			return 0, false
+		}
+		return max(retryAt.Sub(now), 0), true
+	}
+
+	// The primary rate limit is exhausted until its reset.
+	if resp.Header.Get(headerRateLimitRemaining) == "0" {
+		reset, err := strconv.ParseInt(resp.Header.Get(headerRateLimitReset), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return time.Unix(reset, 0).Sub(now), true
+	}
+
+	return 0, false
+}
+
+// isRateLimitError returns if err is GitHub refusing a request because of a rate limit.
+func isRateLimitError(err error) bool {
+	var rateLimitErr *github.RateLimitError
+	var abuseRateLimitErr *github.AbuseRateLimitError
+	return errors.As(err, &rateLimitErr) || errors.As(err, &abuseRateLimitErr) ||
+		strings.Contains(err.Error(), "API rate limit exceeded")
+}
+
+// newGitHubHTTPClient returns the HTTP client authenticating GitHub requests with the token
+// source, with the budgets of its tokens tracked by the plugin and conditional requests for the
+// responses it already has.
+func (p *Plugin) newGitHubHTTPClient(ts oauth2.TokenSource) *http.Client {
+	return p.newGitHubHTTPClientWithTransport(ts, &rateLimitTransport{base: http.DefaultTransport, tracker: p.rateLimits})
+}
+
+// newUserGitHubHTTPClient returns the HTTP client for the user's token. Every request GitHub
+// accepts counts as a successful API call of the user, whichever client or code path made it.
+func (p *Plugin) newUserGitHubHTTPClient(info *GitHubUserInfo) *http.Client {
+	userID := info.UserID
+	return p.newGitHubHTTPClientWithTransport(p.newUserTokenSource(info), &rateLimitTransport{
+		base:      http.DefaultTransport,
+		tracker:   p.rateLimits,
+		onSuccess: func() { p.recordSuccessfulAPICall(userID) },
+	})
+}
+
+func (p *Plugin) newGitHubHTTPClientWithTransport(ts oauth2.TokenSource, transport *rateLimitTransport) *http.Client {
+	return &http.Client{
+		Transport: &oauth2.Transport{
+			Source: oauth2.ReuseTokenSource(nil, ts),
+			Base: &conditionalRequestTransport{
+				base:  transport,
+				cache: p.conditionalRequestCache,
+			},
+		},
+	}
+}
+
+// shouldDeferForRateLimit returns if non-interactive work using the token should wait for the
+// token's budget to reset.
+func (p *Plugin) shouldDeferForRateLimit(token, work string) bool {
+	if !p.rateLimits.isLow(token) {
+		return false
+	}
+
+	p.client.Log.Info("Deferring work until the GitHub rate limit resets", "work", work, "token_id", rateLimitTokenID(token))
+	return true
+}
@@ -0,0 +1,210 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestGetRateLimitRetryWait(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	for name, tc := range map[string]struct {
+		status          int
+		header          map[string]string
+		expectedWait    time.Duration
+		expectedLimited bool
+	}{
+		"success": {
+			status: http.StatusOK,
+			header: map[string]string{headerRetryAfter: "10"},
+		},
+		"secondary rate limit": {
+			status:          http.StatusForbidden,
+			header:          map[string]string{headerRetryAfter: "10"},
+			expectedWait:    10 * time.Second,
+			expectedLimited: true,
+		},
+		"too many requests": {
+			status:          http.StatusTooManyRequests,
+			header:          map[string]string{headerRetryAfter: "3"},
+			expectedWait:    3 * time.Second,
+			expectedLimited: true,
+		},
+		"primary rate limit exhausted": {
+			status:          http.StatusForbidden,
+			header:          map[string]string{headerRateLimitRemaining: "0", headerRateLimitReset: "1700000060"},
+			expectedWait:    time.Minute,
+			expectedLimited: true,
+		},
+		"forbidden": {
+			status: http.StatusForbidden,
+			header: map[string]string{headerRateLimitRemaining: "4000", headerRateLimitReset: "1700000060"},
+		},
+		"retry after date": {
+			status:          http.StatusForbidden,
+			header:          map[string]string{headerRetryAfter: now.Add(20 * time.Second).UTC().Format(http.TimeFormat)},
+			expectedWait:    20 * time.Second,
+			expectedLimited: true,
+		},
+		"retry after past date": {
+			status:          http.StatusTooManyRequests,
+			header:          map[string]string{headerRetryAfter: now.Add(-time.Minute).UTC().Format(http.TimeFormat)},
+			expectedLimited: true,
+		},
+		"invalid retry after": {
+			status: http.StatusForbidden,
+			header: map[string]string{headerRetryAfter: "soon"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tc.status, Header: makeRateLimitHeader(tc.header)}
+
+			wait, limited := getRateLimitRetryWait(resp, now)
+			assert.Equal(t, tc.expectedLimited, limited)
+			assert.Equal(t, tc.expectedWait, wait)
+		})
+	}
+}
+
+func makeRateLimitHeader(values map[string]string) http.Header {
+	header := http.Header{}
+	for key, value := range values {
+		header.Set(key, value)
+	}
+	return header
+}
+
+func TestRateLimitTracker(t *testing.T) {
+	tracker := newRateLimitTracker()
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	tracker.record(rateLimitTokenID("token1"), makeRateLimitHeader(map[string]string{
+		headerRateLimitLimit:     "5000",
+		headerRateLimitRemaining: "4000",
+		headerRateLimitReset:     reset,
+	}))
+	tracker.record(rateLimitTokenID("token2"), makeRateLimitHeader(map[string]string{
+		headerRateLimitLimit:     "5000",
+		headerRateLimitRemaining: "100",
+		headerRateLimitReset:     reset,
+		headerRateLimitResource:  "graphql",
+	}))
+	tracker.record(rateLimitTokenID("token3"), makeRateLimitHeader(map[string]string{
+		headerRateLimitLimit:     "5000",
+		headerRateLimitRemaining: "0",
+		headerRateLimitReset:     strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10),
+	}))
+	tracker.record(rateLimitTokenID("token4"), http.Header{})
+
+	assert.False(t, tracker.isLow("token1"))
+	assert.True(t, tracker.isLow("token2"))
+	assert.False(t, tracker.isLow("token3"))
+	assert.False(t, tracker.isLow("token4"))
+	tracker.lock.Lock()
+	assert.Len(t, tracker.budgets, 2, "reset budgets should be pruned")
+	tracker.lock.Unlock()
+
+	budgets := tracker.list()
+	require.Len(t, budgets, 2)
+	for _, budget := range budgets {
+		assert.NotContains(t, budget.TokenID, "token")
+	}
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(body))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		w.Header().Set(headerRateLimitLimit, "5000")
+		w.Header().Set(headerRateLimitRemaining, strconv.Itoa(5000-requests))
+		w.Header().Set(headerRateLimitReset, strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		if strings.HasSuffix(r.URL.Path, "/limited") || requests == 1 {
+			w.Header().Set(headerRetryAfter, "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	p := NewPlugin()
+	client := p.newGitHubHTTPClient(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}))
+
+	t.Run("retries after the rate limit", func(t *testing.T) {
+		requests = 0
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL, strings.NewReader("payload"))
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, requests)
+
+		budgets := p.rateLimits.list()
+		require.Len(t, budgets, 1)
+		assert.Equal(t, rateLimitTokenID("token"), budgets[0].TokenID)
+		assert.Equal(t, "core", budgets[0].Resource)
+		assert.Equal(t, 4998, budgets[0].Remaining)
+	})
+
+	t.Run("gives up after the maximum retries", func(t *testing.T) {
+		requests = 0
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL+"/limited", strings.NewReader("payload"))
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, rateLimitMaxRetries+1, requests)
+	})
+}
+
+func TestUserGitHubHTTPClientRecordsSuccessfulAPICalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/unauthorized" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKvStore)
+	client := p.newUserGitHubHTTPClient(&GitHubUserInfo{UserID: "user1", Token: &oauth2.Token{AccessToken: "token"}})
+
+	doRequest := func(path string) {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	// Rejected requests aren't recorded.
+	doRequest("/unauthorized")
+
+	mockKvStore.EXPECT().Set("user1"+githubLastAPICallKey, gomock.Any()).Return(true, nil).Times(1)
+	doRequest("/")
+}
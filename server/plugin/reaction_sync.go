@@ -0,0 +1,367 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v54/github"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+)
+
+const (
+	reactionSyncKeyPrefix = "reaction_sync_v1_"
+	reactionSyncMutexKey  = "reaction_sync_mutex"
+	// reactionSyncWindow is how long after a notification is posted its GitHub reactions are mirrored.
+	reactionSyncWindow       = 3 * 24 * time.Hour
+	reactionSyncPollInterval = 5 * time.Minute
+	reactionSyncTimeout      = 4 * time.Minute
+	// reactionSyncMaxObjectsPerTick bounds the GitHub requests of a single poll. Objects left out
+	// are the next to be polled on the following tick.
+	reactionSyncMaxObjectsPerTick = 100
+)
+
+// githubReactions maps the GitHub reaction contents to the Mattermost emojis mirroring them.
+var githubReactions = []struct {
+	content string
+	emoji   string
+}{
+	{content: "+1", emoji: "+1"},
+	{content: "-1", emoji: "-1"},
+	{content: "laugh", emoji: "laughing"},
+	{content: "confused", emoji: "confused"},
+	{content: "heart", emoji: "heart"},
+	{content: "hooray", emoji: "tada"},
+	{content: "rocket", emoji: "rocket"},
+	{content: "eyes", emoji: "eyes"},
+}
+
+// reactionSyncObject is a GitHub issue or comment whose reactions are mirrored onto the
+// notification posts about it.
+type reactionSyncObject struct {
+	Repo       string   `json:"repo"` // owner/repo
+	ObjectType string   `json:"object_type"`
+	ID         int64    `json:"id"`
+	PostIDs    []string `json:"post_ids"`
+	// TrackedAt is when the first notification post about the object was tracked, in milliseconds.
+	TrackedAt int64 `json:"tracked_at"`
+	// NextSyncAt is when the object is polled next, in milliseconds.
+	NextSyncAt int64 `json:"next_sync_at"`
+}
+
+// reactionSyncInterval returns how often an object tracked for age is polled. Most reactions come
+// soon after a notification, so objects are polled less often as they age.
+func reactionSyncInterval(age time.Duration) time.Duration {
+	switch {
+	case age < time.Hour:
+		return reactionSyncPollInterval
+	case age < 24*time.Hour:
+		return 30 * time.Minute
+	default:
+		return 2 * time.Hour
+	}
+}
+
+func reactionSyncKey(repo, objectType string, id int64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%d", strings.ToLower(repo), objectType, id)))
+	return reactionSyncKeyPrefix + hex.EncodeToString(sum[:16])
+}
+
+// trackReactionSyncPost records that the notification post is about the GitHub object, so the
+// object's reactions get mirrored onto it.
+func (p *Plugin) trackReactionSyncPost(repo, objectType string, id int64, postID string) {
+	if !p.getConfiguration().EnableReactionSync || postID == "" {
+		return
+	}
+
+	// Several notifications about the same object can be posted at once, e.g. to different channels.
+	err := p.store.SetAtomicWithRetries(reactionSyncKey(repo, objectType, id), func(oldValue []byte) (any, error) {
+		var obj reactionSyncObject
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &obj); err != nil {
+				return nil, err
+			}
+		}
+
+		if obj.TrackedAt == 0 {
+			obj.TrackedAt = time.Now().UnixMilli()
+		}
+		obj.Repo = repo
+		obj.ObjectType = objectType
+		obj.ID = id
+		obj.PostIDs = append(obj.PostIDs, postID)
+		return obj, nil
+	})
+	if err != nil {
+		p.client.Log.Warn("Failed to store reaction sync posts", "repo", repo, "error", err.Error())
+	}
+}
+
+// updateReactionSyncObject applies update to the stored object. Objects that are gone, e.g. because
+// they expired in the meantime, aren't stored again.
+func (p *Plugin) updateReactionSyncObject(key string, update func(obj *reactionSyncObject)) {
+	err := p.store.SetAtomicWithRetries(key, func(oldValue []byte) (any, error) {
+		if len(oldValue) == 0 {
+			return nil, nil
+		}
+		var obj reactionSyncObject
+		if err := json.Unmarshal(oldValue, &obj); err != nil {
+			return nil, err
+		}
+		update(&obj)
+		return obj, nil
+	})
+	if err != nil {
+		p.client.Log.Warn("Failed to update reaction sync object", "key", key, "error", err.Error())
+	}
+}
+
+// runReactionSyncScheduler loops until ctx is cancelled, mirroring GitHub reactions onto recent
+// notification posts. GitHub doesn't send webhooks for reactions, so they are polled.
+func (p *Plugin) runReactionSyncScheduler(ctx context.Context) {
+	for {
+		if p.getConfiguration().EnableReactionSync {
+			syncCtx, cancel := context.WithTimeout(ctx, reactionSyncTimeout)
+			p.syncReactions(syncCtx)
+			cancel()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reactionSyncPollInterval):
+		}
+	}
+}
+
+// syncReactions mirrors the reactions of the tracked GitHub objects that are due, the longest
+// overdue first, and drops the objects tracked for longer than reactionSyncWindow. A cluster mutex
+// ensures only one node polls in HA setups.
+func (p *Plugin) syncReactions(ctx context.Context) {
+	m, err := cluster.NewMutex(p.API, reactionSyncMutexKey)
+	if err != nil {
+		p.client.Log.Warn("Failed to create cluster mutex for reaction sync", "error", err.Error())
+		return
+	}
+	if err = m.LockWithContext(ctx); err != nil {
+		return
+	}
+	defer m.Unlock()
+
+	allKeys, _, err := p.listKeysWithPrefix(reactionSyncKeyPrefix)
+	if err != nil {
+		p.client.Log.Warn("Failed to list reaction sync keys", "error", err.Error())
+		return
+	}
+
+	now := time.Now()
+	type dueObject struct {
+		key string
+		obj reactionSyncObject
+	}
+	var due []dueObject
+	for _, key := range allKeys {
+		var obj reactionSyncObject
+		if err := p.store.Get(key, &obj); err != nil || obj.Repo == "" {
+			continue
+		}
+
+		if obj.TrackedAt != 0 && now.Sub(time.UnixMilli(obj.TrackedAt)) > reactionSyncWindow {
+			if err := p.store.Delete(key); err != nil {
+				p.client.Log.Warn("Failed to delete expired reaction sync object", "key", key, "error", err.Error())
+			}
+			continue
+		}
+		if obj.NextSyncAt > now.UnixMilli() {
+			continue
+		}
+		due = append(due, dueObject{key: key, obj: obj})
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].obj.NextSyncAt < due[j].obj.NextSyncAt
+	})
+	if len(due) > reactionSyncMaxObjectsPerTick {
+		due = due[:reactionSyncMaxObjectsPerTick]
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	getGitHubClient := p.getReactionSyncClientGetter(ctx)
+	if getGitHubClient == nil {
+		return
+	}
+
+	for _, d := range due {
+		if ctx.Err() != nil {
+			return
+		}
+
+		owner, _, _ := strings.Cut(d.obj.Repo, "/")
+		githubClient := getGitHubClient(owner)
+		if githubClient == nil {
+			continue
+		}
+
+		reactions, err := getGitHubReactions(ctx, githubClient, d.obj)
+		if err != nil {
+			// The object is polled again after the usual interval, so one that is gone doesn't
+			// take up a place on every tick.
+			p.client.Log.Debug("Failed to get GitHub reactions", "repo", d.obj.Repo, "id", d.obj.ID, "error", err.Error())
+		} else {
+			for _, postID := range d.obj.PostIDs {
+				p.mirrorReactions(postID, reactions)
+			}
+		}
+
+		p.updateReactionSyncObject(d.key, func(obj *reactionSyncObject) {
+			if obj.TrackedAt == 0 {
+				// Objects tracked before TrackedAt was recorded.
+				obj.TrackedAt = now.UnixMilli()
+			}
+			obj.NextSyncAt = now.Add(reactionSyncInterval(now.Sub(time.UnixMilli(obj.TrackedAt)))).UnixMilli()
+		})
+	}
+}
+
+// getReactionSyncClientGetter returns how to get the client reading the reactions of an owner's
+// objects: the GitHub App installation on the owner when the app is configured, else the service
+// user. It returns nil when there is no service user, and the getter returns nil for owners
+// whose budget is low.
+func (p *Plugin) getReactionSyncClientGetter(ctx context.Context) func(owner string) *github.Client {
+	if p.getConfiguration().IsGitHubAppConfigured() {
+		return func(owner string) *github.Client {
+			token, err := p.getInstallationToken(ctx, owner)
+			if err != nil {
+				p.client.Log.Debug("Failed to get GitHub App installation token", "owner", owner, "error", err.Error())
+				return nil
+			}
+			if p.shouldDeferForRateLimit(token, "reaction sync") {
+				return nil
+			}
+			githubClient, err := p.getInstallationClient(ctx, owner)
+			if err != nil {
+				p.client.Log.Debug("Failed to get GitHub App installation client", "owner", owner, "error", err.Error())
+				return nil
+			}
+			return githubClient
+		}
+	}
+
+	info := p.pickServiceGitHubUser(ctx)
+	if info == nil || p.shouldDeferForRateLimit(info.Token.AccessToken, "reaction sync") {
+		return nil
+	}
+	githubClient := p.githubConnectUser(ctx, info)
+	return func(string) *github.Client {
+		return githubClient
+	}
+}
+
+// getGitHubReactions returns the reactions of the object. Polls of an unchanged object don't count
+// against the rate limit, as the GitHub client transport makes them conditional on the last response.
+func getGitHubReactions(ctx context.Context, githubClient *github.Client, obj reactionSyncObject) (*github.Reactions, error) {
+	owner, repo, ok := strings.Cut(obj.Repo, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid repository %q", obj.Repo)
+	}
+
+	var u string
+	switch obj.ObjectType {
+	case githubObjectTypeIssue:
+		u = fmt.Sprintf("repos/%v/%v/issues/%d", owner, repo, obj.ID)
+	case githubObjectTypeIssueComment:
+		u = fmt.Sprintf("repos/%v/%v/issues/comments/%d", owner, repo, obj.ID)
+	case githubObjectTypePRReviewComment:
+		u = fmt.Sprintf("repos/%v/%v/pulls/comments/%d", owner, repo, obj.ID)
+	default:
+		return nil, fmt.Errorf("unsupported object type %q", obj.ObjectType)
+	}
+
+	req, err := githubClient.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Issues, issue comments and review comments all carry their reactions the same way.
+	var object struct {
+		Reactions *github.Reactions `json:"reactions"`
+	}
+	if _, err = githubClient.Do(ctx, req, &object); err != nil {
+		return nil, err
+	}
+	if object.Reactions == nil {
+		object.Reactions = &github.Reactions{}
+	}
+
+	return object.Reactions, nil
+}
+
+func getGitHubReactionCount(reactions *github.Reactions, content string) int {
+	switch content {
+	case "+1":
+		return reactions.GetPlusOne()
+	case "-1":
+		return reactions.GetMinusOne()
+	case "laugh":
+		return reactions.GetLaugh()
+	case "confused":
+		return reactions.GetConfused()
+	case "heart":
+		return reactions.GetHeart()
+	case "hooray":
+		return reactions.GetHooray()
+	case "rocket":
+		return reactions.GetRocket()
+	case "eyes":
+		return reactions.GetEyes()
+	}
+	return 0
+}
+
+// mirrorReactions makes the bot react to the post with each emoji used on GitHub. The bot doesn't
+// react when a user already did, e.g. because their reaction was synced to GitHub.
+func (p *Plugin) mirrorReactions(postID string, reactions *github.Reactions) {
+	existing, err := p.client.Post.GetReactions(postID)
+	if err != nil {
+		p.client.Log.Debug("Failed to get post reactions", "postID", postID, "error", err.Error())
+		return
+	}
+
+	byBot := map[string]bool{}
+	byUsers := map[string]bool{}
+	for _, reaction := range existing {
+		if reaction.UserId == p.BotUserID {
+			byBot[reaction.EmojiName] = true
+		} else {
+			byUsers[reaction.EmojiName] = true
+		}
+	}
+
+	for _, r := range githubReactions {
+		want := getGitHubReactionCount(reactions, r.content) > 0 && !byUsers[r.emoji]
+		reaction := &model.Reaction{UserId: p.BotUserID, PostId: postID, EmojiName: r.emoji}
+		switch {
+		case want && !byBot[r.emoji]:
+			err = p.client.Post.AddReaction(reaction)
+		case !want && byBot[r.emoji]:
+			err = p.client.Post.RemoveReaction(reaction)
+		default:
+			continue
+		}
+		if err != nil {
+			p.client.Log.Debug("Failed to mirror GitHub reaction", "postID", postID, "emoji", r.emoji, "error", err.Error())
+		}
+	}
+}
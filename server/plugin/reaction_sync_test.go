@@ -0,0 +1,120 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-github/v54/github"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackReactionSyncPost(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		mockKVStore, mockAPI, _, _, _ := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKVStore)
+
+		p.trackReactionSyncPost("mockorg/mockrepo", githubObjectTypeIssue, 1, "post1")
+	})
+
+	t.Run("appends the post to the tracked object", func(t *testing.T) {
+		mockKVStore, mockAPI, _, _, _ := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKVStore)
+		config := p.getConfiguration().Clone()
+		config.EnableReactionSync = true
+		p.setConfiguration(config)
+
+		key := reactionSyncKey("mockorg/mockrepo", githubObjectTypeIssueComment, 7)
+		mockKVStore.EXPECT().SetAtomicWithRetries(key, gomock.Any()).DoAndReturn(func(key string, valueFunc func([]byte) (any, error)) error {
+			oldValue, err := json.Marshal(reactionSyncObject{Repo: "mockorg/mockrepo", ObjectType: githubObjectTypeIssueComment, ID: 7, PostIDs: []string{"post1"}, TrackedAt: 1000})
+			require.NoError(t, err)
+
+			newValue, err := valueFunc(oldValue)
+			require.NoError(t, err)
+			assert.Equal(t, reactionSyncObject{
+				Repo:       "mockorg/mockrepo",
+				ObjectType: githubObjectTypeIssueComment,
+				ID:         7,
+				PostIDs:    []string{"post1", "post2"},
+				TrackedAt:  1000,
+			}, newValue)
+			return nil
+		}).Times(1)
+
+		p.trackReactionSyncPost("mockorg/mockrepo", githubObjectTypeIssueComment, 7, "post2")
+	})
+}
+
+func TestGetGitHubReactions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "/api-v3/repos/mockorg/mockrepo/issues/comments/7", req.URL.Path)
+		_ = json.NewEncoder(w).Encode(&github.IssueComment{Reactions: &github.Reactions{Heart: github.Int(2)}})
+	}))
+	defer server.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + baseURLPath + "/")
+
+	reactions, err := getGitHubReactions(context.Background(), client, reactionSyncObject{Repo: "mockorg/mockrepo", ObjectType: githubObjectTypeIssueComment, ID: 7})
+	require.NoError(t, err)
+	assert.Equal(t, 2, reactions.GetHeart())
+
+	_, err = getGitHubReactions(context.Background(), client, reactionSyncObject{Repo: "mockrepo", ObjectType: githubObjectTypeIssueComment, ID: 7})
+	assert.Error(t, err)
+}
+
+func TestReactionSyncInterval(t *testing.T) {
+	assert.Equal(t, reactionSyncPollInterval, reactionSyncInterval(10*time.Minute))
+	assert.Equal(t, 30*time.Minute, reactionSyncInterval(2*time.Hour))
+	assert.Equal(t, 2*time.Hour, reactionSyncInterval(48*time.Hour))
+}
+
+func TestMirrorReactions(t *testing.T) {
+	mockKVStore, mockAPI, _, _, _ := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKVStore)
+
+	mockAPI.On("GetReactions", "post1").Return([]*model.Reaction{
+		{UserId: MockBotID, PostId: "post1", EmojiName: "eyes"},
+		{UserId: MockBotID, PostId: "post1", EmojiName: "heart"},
+		{UserId: MockUserID, PostId: "post1", EmojiName: "+1"},
+	}, nil)
+	added := &model.Reaction{UserId: MockBotID, PostId: "post1", EmojiName: "tada"}
+	mockAPI.On("AddReaction", added).Return(added, nil)
+	mockAPI.On("RemoveReaction", &model.Reaction{UserId: MockBotID, PostId: "post1", EmojiName: "eyes"}).Return(nil)
+
+	p.mirrorReactions("post1", &github.Reactions{
+		PlusOne: github.Int(2),
+		Heart:   github.Int(1),
+		Hooray:  github.Int(1),
+		Eyes:    github.Int(0),
+	})
+
+	mockAPI.AssertExpectations(t)
+	mockAPI.AssertNumberOfCalls(t, "AddReaction", 1)
+	mockAPI.AssertNumberOfCalls(t, "RemoveReaction", 1)
+}
+
+func TestGetGitHubReactionCount(t *testing.T) {
+	reactions := &github.Reactions{PlusOne: github.Int(3), Hooray: github.Int(1)}
+
+	for _, r := range githubReactions {
+		expected := 0
+		switch r.content {
+		case "+1":
+			expected = 3
+		case "hooray":
+			expected = 1
+		}
+		assert.Equal(t, expected, getGitHubReactionCount(reactions, r.content), r.content)
+	}
+}
@@ -0,0 +1,374 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v54/github"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const (
+	reviewReminderKeyPrefix = "review_reminder_v1_"
+	reviewReminderIndexKey  = "review_reminder_index_v1"
+	reviewReminderMutexKey  = "review_reminder_mutex"
+
+	reviewReminderActionSnoozeHour     = "snooze_1h"
+	reviewReminderActionSnoozeTomorrow = "snooze_tomorrow"
+	reviewReminderActionDone           = "done"
+
+	// reviewReminderTomorrowHour is the local hour of the user at which "until tomorrow" reminders fire.
+	reviewReminderTomorrowHour = 9
+
+	reviewReminderPollInterval = time.Minute
+	reviewReminderCheckTimeout = 30 * time.Second
+	// reviewReminderMaxDelay is how long a due reminder is retried while GitHub can't be reached
+	// before it is dropped.
+	reviewReminderMaxDelay = 24 * time.Hour
+
+	reviewReminderMessagePrefix = "**Reminder:** "
+)
+
+// ReviewReminder is a snoozed review request waiting to be re-sent to the reviewer.
+type ReviewReminder struct {
+	UserID  string `json:"user_id"`
+	Owner   string `json:"owner"`
+	Repo    string `json:"repo"`
+	Number  int    `json:"number"`
+	Message string `json:"message"`
+	// RemindAt is the Unix time in milliseconds at which the reminder is due.
+	RemindAt int64 `json:"remind_at"`
+}
+
+// reviewReminderIndex maps the keys of the pending reminders to when they are due, so the scheduler
+// doesn't have to list the whole KV namespace to find them.
+type reviewReminderIndex map[string]int64
+
+// updateReviewReminderIndex applies update to the stored index of pending reminders.
+func (p *Plugin) updateReviewReminderIndex(update func(index reviewReminderIndex)) error {
+	return p.store.SetAtomicWithRetries(reviewReminderIndexKey, func(oldValue []byte) (any, error) {
+		var index reviewReminderIndex
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &index); err != nil {
+				return nil, err
+			}
+		}
+		if index == nil {
+			index = reviewReminderIndex{}
+		}
+		update(index)
+		return index, nil
+	})
+}
+
+// reviewReminderKey returns a stable KV key for (reviewer, repo, PR).
+func reviewReminderKey(userID, owner, repo string, prNumber int) string {
+	normalized := userID + ":" + strings.ToLower(owner) + "/" + strings.ToLower(repo) + "#" + strconv.Itoa(prNumber)
+	sum := sha256.Sum256([]byte(normalized))
+	return reviewReminderKeyPrefix + hex.EncodeToString(sum[:16])
+}
+
+// nextReviewReminderTime returns when a reminder snoozed at now with the given action is due.
+func nextReviewReminderTime(action string, now time.Time, loc *time.Location) (time.Time, bool) {
+	switch action {
+	case reviewReminderActionSnoozeHour:
+		return now.Add(time.Hour), true
+	case reviewReminderActionSnoozeTomorrow:
+		local := now.In(loc)
+		return time.Date(local.Year(), local.Month(), local.Day(), reviewReminderTomorrowHour, 0, 0, 0, loc).AddDate(0, 0, 1), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func (p *Plugin) getReviewReminderActionURL() string {
+	return fmt.Sprintf("/plugins/%s/api/v1/review-reminder", Manifest.Id)
+}
+
+// getReviewRequestMessage returns the text of the original review request of a review request
+// or reminder DM, so snoozing a reminder again doesn't stack the reminder prefix.
+func getReviewRequestMessage(message string) string {
+	return strings.TrimPrefix(message, reviewReminderMessagePrefix)
+}
+
+// makeReviewReminderAttachment returns the snooze and done buttons shown under a review request DM.
+func (p *Plugin) makeReviewReminderAttachment(userID, owner, repo string, prNumber int) *model.MessageAttachment {
+	makeAction := func(id, name, action string) *model.PostAction {
+		return &model.PostAction{
+			Id:   id,
+			Name: name,
+			Type: model.PostActionTypeButton,
+			Integration: &model.PostActionIntegration{
+				URL: p.getReviewReminderActionURL(),
+				Context: map[string]any{
+					"action":  action,
+					"user_id": userID,
+					"owner":   owner,
+					"repo":    repo,
+					"number":  prNumber,
+				},
+			},
+		}
+	}
+
+	return &model.MessageAttachment{
+		Actions: []*model.PostAction{
+			makeAction("snooze1h", "Snooze 1h", reviewReminderActionSnoozeHour),
+			makeAction("snoozetomorrow", "Until tomorrow", reviewReminderActionSnoozeTomorrow),
+			makeAction("markdone", "Mark done", reviewReminderActionDone),
+		},
+	}
+}
+
+// sendReviewRequestDM sends a review request DM with buttons to snooze it or mark it as done.
+func (p *Plugin) sendReviewRequestDM(userID, message, owner, repo string, prNumber int) {
+	post := &model.Post{
+		Message: message,
+		Type:    "custom_git_review_request",
+	}
+	if owner != "" && repo != "" && prNumber != 0 {
+		model.ParseSlackAttachment(post, []*model.MessageAttachment{p.makeReviewReminderAttachment(userID, owner, repo, prNumber)})
+	}
+
+	p.createBotDMPost(userID, post)
+}
+
+func (p *Plugin) handleReviewReminderAction(c *Context, w http.ResponseWriter, r *http.Request) {
+	var request model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		c.Log.WithError(err).Warnf("Error decoding PostActionIntegrationRequest from JSON body")
+		p.writeAPIError(w, &APIErrorResponse{Message: "invalid request body", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	action, _ := request.Context["action"].(string)
+	userID, _ := request.Context["user_id"].(string)
+	owner, _ := request.Context["owner"].(string)
+	repo, _ := request.Context["repo"].(string)
+	number, _ := request.Context["number"].(float64)
+
+	if userID != c.UserID {
+		p.writeAPIError(w, &APIErrorResponse{Message: "Not authorized.", StatusCode: http.StatusForbidden})
+		return
+	}
+	if owner == "" || repo == "" || number <= 0 {
+		p.writeAPIError(w, &APIErrorResponse{Message: "invalid request context", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	post, err := p.client.Post.GetPost(request.PostId)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to get review request post")
+		p.writeAPIError(w, &APIErrorResponse{Message: "failed to get post", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	key := reviewReminderKey(userID, owner, repo, int(number))
+
+	var note string
+	switch action {
+	case reviewReminderActionDone:
+		if err = p.store.Delete(key); err != nil {
+			c.Log.WithError(err).Warnf("Failed to delete review reminder")
+			p.writeAPIError(w, &APIErrorResponse{Message: "failed to delete review reminder", StatusCode: http.StatusInternalServerError})
+			return
+		}
+		// A leftover index entry is dropped by the scheduler once it finds the reminder gone.
+		if err = p.updateReviewReminderIndex(func(index reviewReminderIndex) { delete(index, key) }); err != nil {
+			c.Log.WithError(err).Warnf("Failed to remove review reminder from the index")
+		}
+		note = "Marked as done."
+	default:
+		loc := time.Local
+		if user, userErr := p.client.User.Get(userID); userErr == nil {
+			loc = user.GetTimezoneLocation()
+		}
+
+		remindAt, ok := nextReviewReminderTime(action, time.Now(), loc)
+		if !ok {
+			p.writeAPIError(w, &APIErrorResponse{Message: "unknown action", StatusCode: http.StatusBadRequest})
+			return
+		}
+
+		reminder := &ReviewReminder{
+			UserID:   userID,
+			Owner:    owner,
+			Repo:     repo,
+			Number:   int(number),
+			Message:  getReviewRequestMessage(post.Message),
+			RemindAt: remindAt.UnixMilli(),
+		}
+		if _, err = p.store.Set(key, reminder); err == nil {
+			err = p.updateReviewReminderIndex(func(index reviewReminderIndex) { index[key] = reminder.RemindAt })
+		}
+		if err != nil {
+			c.Log.WithError(err).Warnf("Failed to store review reminder")
+			p.writeAPIError(w, &APIErrorResponse{Message: "failed to store review reminder", StatusCode: http.StatusInternalServerError})
+			return
+		}
+		note = fmt.Sprintf("Snoozed until %s.", remindAt.In(loc).Format("Mon Jan 2 15:04 MST"))
+	}
+
+	// Replace the buttons with the chosen outcome so the same request can't be snoozed twice.
+	post.DelProp(model.PostPropsAttachments)
+	model.ParseSlackAttachment(post, []*model.MessageAttachment{{Text: note}})
+
+	p.writeJSON(w, &model.PostActionIntegrationResponse{Update: post})
+}
+
+// runReviewReminderScheduler loops until ctx is cancelled, re-sending snoozed review requests once they are due.
+// Reminders live in the KV store, so pending ones survive plugin restarts.
+func (p *Plugin) runReviewReminderScheduler(ctx context.Context) {
+	for {
+		p.sendDueReviewReminders(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reviewReminderPollInterval):
+		}
+	}
+}
+
+// sendDueReviewReminders re-sends every due reminder whose review is still pending.
+// A cluster mutex ensures only one node sends reminders in HA setups.
+func (p *Plugin) sendDueReviewReminders(ctx context.Context) {
+	m, err := cluster.NewMutex(p.API, reviewReminderMutexKey)
+	if err != nil {
+		p.client.Log.Warn("Failed to create cluster mutex for review reminders", "error", err.Error())
+		return
+	}
+	if err = m.LockWithContext(ctx); err != nil {
+		return
+	}
+	defer m.Unlock()
+
+	var index reviewReminderIndex
+	if err = p.store.Get(reviewReminderIndexKey, &index); err != nil {
+		p.client.Log.Warn("Failed to get the review reminder index", "error", err.Error())
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	var done []string
+	for key, remindAt := range index {
+		if ctx.Err() != nil {
+			break
+		}
+		if remindAt > now {
+			continue
+		}
+
+		var reminder ReviewReminder
+		if err := p.store.Get(key, &reminder); err != nil {
+			p.client.Log.Warn("Failed to get review reminder", "key", key, "error", err.Error())
+			continue
+		}
+		if reminder.UserID == "" {
+			// Marked as done in the meantime.
+			done = append(done, key)
+			continue
+		}
+
+		pending, err := p.isReviewStillPending(ctx, &reminder)
+		if err != nil {
+			if time.Duration(now-reminder.RemindAt)*time.Millisecond < reviewReminderMaxDelay {
+				// Keep the reminder and check again on the next tick.
+				continue
+			}
+			p.client.Log.Warn("Dropping review reminder after failing to check the pull request", "key", key, "error", err.Error())
+		}
+		if pending {
+			p.sendReviewRequestDM(reminder.UserID, reviewReminderMessagePrefix+getReviewRequestMessage(reminder.Message), reminder.Owner, reminder.Repo, reminder.Number)
+		}
+
+		if err := p.store.Delete(key); err != nil {
+			p.client.Log.Warn("Failed to delete review reminder", "key", key, "error", err.Error())
+			continue
+		}
+		done = append(done, key)
+	}
+
+	if len(done) == 0 {
+		return
+	}
+	if err := p.updateReviewReminderIndex(func(index reviewReminderIndex) {
+		for _, key := range done {
+			// The reminder may have been snoozed again in the meantime.
+			if index[key] <= now {
+				delete(index, key)
+			}
+		}
+	}); err != nil {
+		p.client.Log.Warn("Failed to update the review reminder index", "error", err.Error())
+	}
+}
+
+// isReviewStillPending reports whether the pull request is open and the user's review is still requested.
+// It returns an error when the user's connection couldn't be loaded or GitHub couldn't answer for now,
+// e.g. because of a rate limit, a server error or a timeout, so the reminder can be checked again later.
+func (p *Plugin) isReviewStillPending(ctx context.Context, reminder *ReviewReminder) (bool, error) {
+	info, apiErr := p.getGitHubUserInfo(reminder.UserID)
+	if apiErr != nil {
+		if apiErr.ID == apiErrorIDNotConnected {
+			return false, nil
+		}
+		return false, apiErr
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, reviewReminderCheckTimeout)
+	defer cancel()
+
+	githubClient := p.githubConnectUser(ctx, info)
+	var pr *github.PullRequest
+	if cErr := p.useGitHubClient(info, func(info *GitHubUserInfo, token *oauth2.Token) error {
+		var err error
+		pr, _, err = githubClient.PullRequests.Get(ctx, reminder.Owner, reminder.Repo, reminder.Number)
+		return err
+	}); cErr != nil {
+		if isTransientGitHubError(cErr) {
+			return false, cErr
+		}
+		return false, nil
+	}
+
+	if pr.GetState() != "open" {
+		return false, nil
+	}
+
+	for _, reviewer := range pr.RequestedReviewers {
+		if strings.EqualFold(reviewer.GetLogin(), info.GitHubUsername) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// isTransientGitHubError returns if err may go away when the request is retried later. GitHub
+// answering with a client error, e.g. because the pull request is gone, is final.
+func isTransientGitHubError(err error) bool {
+	if isRateLimitError(err) {
+		return true
+	}
+
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil {
+		return errResp.Response.StatusCode >= http.StatusInternalServerError
+	}
+
+	return true
+}
@@ -0,0 +1,252 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-github/v54/github"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-github/server/mocks"
+)
+
+func TestNextReviewReminderTime(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	now := time.Date(2024, time.March, 5, 22, 30, 0, 0, loc)
+
+	tests := []struct {
+		name     string
+		action   string
+		expected time.Time
+		ok       bool
+	}{
+		{name: "Snooze for an hour", action: reviewReminderActionSnoozeHour, expected: now.Add(time.Hour), ok: true},
+		{name: "Snooze until tomorrow morning", action: reviewReminderActionSnoozeTomorrow, expected: time.Date(2024, time.March, 6, 9, 0, 0, 0, loc), ok: true},
+		{name: "Unknown action", action: "snooze_forever", ok: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			remindAt, ok := nextReviewReminderTime(tc.action, now, loc)
+			assert.Equal(t, tc.ok, ok)
+			assert.True(t, tc.expected.Equal(remindAt))
+		})
+	}
+}
+
+func TestReviewReminderKey(t *testing.T) {
+	key := reviewReminderKey(MockUserID, "Mattermost", "Server", 12)
+
+	assert.True(t, strings.HasPrefix(key, reviewReminderKeyPrefix))
+	assert.Equal(t, key, reviewReminderKey(MockUserID, "mattermost", "server", 12))
+	assert.NotEqual(t, key, reviewReminderKey(MockUserID, "mattermost", "server", 13))
+	assert.NotEqual(t, key, reviewReminderKey("otherUserID", "mattermost", "server", 12))
+}
+
+// expectReviewReminderIndexUpdate expects the index of pending reminders, stored as oldIndex, to be
+// updated to expectedIndex.
+func expectReviewReminderIndexUpdate(t *testing.T, mockKvStore *mocks.MockKvStore, oldIndex reviewReminderIndex, expectedIndex func(index reviewReminderIndex)) *gomock.Call {
+	return mockKvStore.EXPECT().SetAtomicWithRetries(reviewReminderIndexKey, gomock.Any()).DoAndReturn(func(key string, valueFunc func([]byte) (any, error)) error {
+		oldValue, err := json.Marshal(oldIndex)
+		require.NoError(t, err)
+
+		newValue, err := valueFunc(oldValue)
+		require.NoError(t, err)
+		index, ok := newValue.(reviewReminderIndex)
+		require.True(t, ok)
+		expectedIndex(index)
+		return nil
+	})
+}
+
+func TestHandleReviewReminderAction(t *testing.T) {
+	mockKvStore, mockAPI, _, _, mockContext := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKvStore)
+
+	makeBody := func(action, userID string) string {
+		b, err := json.Marshal(model.PostActionIntegrationRequest{
+			PostId: "postID",
+			Context: map[string]any{
+				"action":  action,
+				"user_id": userID,
+				"owner":   MockOrg,
+				"repo":    MockRepo,
+				"number":  12,
+			},
+		})
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	key := reviewReminderKey(MockUserID, MockOrg, MockRepo, 12)
+
+	tests := []struct {
+		name               string
+		requestBody        string
+		setup              func()
+		expectedStatusCode int
+		expectedNote       string
+	}{
+		{
+			name:               "Button clicked by another user",
+			requestBody:        makeBody(reviewReminderActionSnoozeHour, "otherUserID"),
+			setup:              func() {},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:        "Mark done",
+			requestBody: makeBody(reviewReminderActionDone, MockUserID),
+			setup: func() {
+				mockAPI.On("GetPost", "postID").Return(&model.Post{Id: "postID", Message: "review requested"}, nil).Once()
+				mockKvStore.EXPECT().Delete(key).Return(nil).Times(1)
+				expectReviewReminderIndexUpdate(t, mockKvStore, reviewReminderIndex{key: 1, "other": 2}, func(index reviewReminderIndex) {
+					assert.Equal(t, reviewReminderIndex{"other": 2}, index)
+				}).Times(1)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedNote:       "Marked as done.",
+		},
+		{
+			name:        "Snooze for an hour",
+			requestBody: makeBody(reviewReminderActionSnoozeHour, MockUserID),
+			setup: func() {
+				mockAPI.On("GetPost", "postID").Return(&model.Post{Id: "postID", Message: "review requested"}, nil).Once()
+				mockAPI.On("GetUser", MockUserID).Return(&model.User{Id: MockUserID}, nil).Once()
+				mockKvStore.EXPECT().Set(key, gomock.Any()).DoAndReturn(func(key string, value any, _ ...any) (bool, error) {
+					reminder, ok := value.(*ReviewReminder)
+					require.True(t, ok)
+					assert.Equal(t, MockUserID, reminder.UserID)
+					assert.Equal(t, "review requested", reminder.Message)
+					assert.Greater(t, reminder.RemindAt, time.Now().UnixMilli())
+					return true, nil
+				}).Times(1)
+				expectReviewReminderIndexUpdate(t, mockKvStore, nil, func(index reviewReminderIndex) {
+					assert.Greater(t, index[key], time.Now().UnixMilli())
+				}).Times(1)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedNote:       "Snoozed until",
+		},
+		{
+			name:        "Snooze a reminder again",
+			requestBody: makeBody(reviewReminderActionSnoozeTomorrow, MockUserID),
+			setup: func() {
+				mockAPI.On("GetPost", "postID").Return(&model.Post{Id: "postID", Message: reviewReminderMessagePrefix + "review requested"}, nil).Once()
+				mockAPI.On("GetUser", MockUserID).Return(&model.User{Id: MockUserID}, nil).Once()
+				mockKvStore.EXPECT().Set(key, gomock.Any()).DoAndReturn(func(key string, value any, _ ...any) (bool, error) {
+					reminder, ok := value.(*ReviewReminder)
+					require.True(t, ok)
+					assert.Equal(t, "review requested", reminder.Message)
+					return true, nil
+				}).Times(1)
+				expectReviewReminderIndexUpdate(t, mockKvStore, nil, func(index reviewReminderIndex) {
+					assert.Contains(t, index, key)
+				}).Times(1)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedNote:       "Snoozed until",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup()
+
+			req := httptest.NewRequest(http.MethodPost, "/review-reminder", strings.NewReader(tc.requestBody))
+			rec := httptest.NewRecorder()
+
+			p.handleReviewReminderAction(mockContext, rec, req)
+
+			assert.Equal(t, tc.expectedStatusCode, rec.Result().StatusCode)
+			if tc.expectedNote == "" {
+				return
+			}
+
+			var resp model.PostActionIntegrationResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			require.NotNil(t, resp.Update)
+			attachments := resp.Update.Attachments()
+			require.Len(t, attachments, 1)
+			assert.Empty(t, attachments[0].Actions)
+			assert.Contains(t, attachments[0].Text, tc.expectedNote)
+		})
+	}
+
+	mockAPI.AssertExpectations(t)
+}
+
+func TestIsTransientGitHubError(t *testing.T) {
+	makeErrorResponse := func(statusCode int) error {
+		req := httptest.NewRequest(http.MethodGet, "/repos/owner/repo/pulls/1", nil)
+		return &github.ErrorResponse{Response: &http.Response{StatusCode: statusCode, Request: req}, Message: http.StatusText(statusCode)}
+	}
+
+	assert.False(t, isTransientGitHubError(makeErrorResponse(http.StatusNotFound)))
+	assert.False(t, isTransientGitHubError(makeErrorResponse(http.StatusUnauthorized)))
+	assert.True(t, isTransientGitHubError(makeErrorResponse(http.StatusBadGateway)))
+	assert.True(t, isTransientGitHubError(&github.RateLimitError{Response: &http.Response{StatusCode: http.StatusForbidden, Request: httptest.NewRequest(http.MethodGet, "/", nil)}}))
+	assert.True(t, isTransientGitHubError(context.DeadlineExceeded))
+}
+
+func TestSendDueReviewReminders(t *testing.T) {
+	mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKvStore)
+	mockAPI.On("KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+
+	now := time.Now().UnixMilli()
+	dueKey := reviewReminderKey("user1", MockOrg, MockRepo, 12)
+	laterKey := reviewReminderKey("user1", MockOrg, MockRepo, 13)
+	doneKey := reviewReminderKey("user1", MockOrg, MockRepo, 14)
+
+	mockKvStore.EXPECT().Get(reviewReminderIndexKey, gomock.Any()).DoAndReturn(func(key string, value any) error {
+		*value.(*reviewReminderIndex) = reviewReminderIndex{dueKey: now - 1000, laterKey: now + time.Hour.Milliseconds(), doneKey: now - 1000}
+		return nil
+	})
+	mockKvStore.EXPECT().Get(dueKey, gomock.Any()).DoAndReturn(func(key string, value any) error {
+		*value.(*ReviewReminder) = ReviewReminder{UserID: "user1", Owner: MockOrg, Repo: MockRepo, Number: 12, RemindAt: now - 1000}
+		return nil
+	})
+	mockKvStore.EXPECT().Get(doneKey, gomock.Any()).Return(nil)
+	// user1 disconnected from GitHub since, so the reminder is dropped.
+	mockKvStore.EXPECT().Get("user1"+githubTokenKey, gomock.Any()).Return(nil)
+	mockKvStore.EXPECT().Delete(dueKey).Return(nil)
+	expectReviewReminderIndexUpdate(t, mockKvStore, reviewReminderIndex{dueKey: now - 1000, laterKey: now + time.Hour.Milliseconds(), doneKey: now - 1000}, func(index reviewReminderIndex) {
+		assert.Equal(t, reviewReminderIndex{laterKey: now + time.Hour.Milliseconds()}, index)
+	})
+
+	p.sendDueReviewReminders(context.Background())
+}
+
+func TestSendDueReviewRemindersKeepsRemindersOnStoreErrors(t *testing.T) {
+	mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKvStore)
+	mockAPI.On("KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+
+	now := time.Now().UnixMilli()
+	reminderKey := reviewReminderKey("user1", MockOrg, MockRepo, 12)
+
+	mockKvStore.EXPECT().Get(reviewReminderIndexKey, gomock.Any()).DoAndReturn(func(key string, value any) error {
+		*value.(*reviewReminderIndex) = reviewReminderIndex{reminderKey: now - 1000}
+		return nil
+	})
+	mockKvStore.EXPECT().Get(reminderKey, gomock.Any()).DoAndReturn(func(key string, value any) error {
+		*value.(*ReviewReminder) = ReviewReminder{UserID: "user1", Owner: MockOrg, Repo: MockRepo, Number: 12, RemindAt: now - 1000}
+		return nil
+	})
+	// Loading the user's connection fails, so the reminder is kept for the next tick.
+	mockKvStore.EXPECT().Get("user1"+githubTokenKey, gomock.Any()).Return(errors.New("store unavailable"))
+
+	p.sendDueReviewReminders(context.Background())
+}
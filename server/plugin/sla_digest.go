@@ -191,7 +191,8 @@ func (p *Plugin) pickServiceGitHubUser(ctx context.Context) *GitHubUserInfo {
 // could not complete a real scan (no orgs configured, no connected service user, or every
 // configured org's GraphQL fetch failed); the caller should retry on the next scheduler tick
 // rather than treat that as "ran successfully and found nothing." A successful scan returns
-// ok=true even when entries is empty.
+// ok=true even when entries is empty. When a GitHub App is configured, each org is scanned as
+// the app installation on it instead of as the service user.
 func (p *Plugin) collectAllOverdueSLAItems(ctx context.Context) ([]slaDigestEntry, bool) {
 	config := p.getConfiguration()
 	targetDays := config.ReviewTargetDays
@@ -203,15 +204,75 @@ func (p *Plugin) collectAllOverdueSLAItems(ctx context.Context) ([]slaDigestEntr
 		return nil, false
 	}
 
+	if config.IsGitHubAppConfigured() {
+		return p.collectInstallationOverdueSLAItems(ctx, orgList, targetDays, now)
+	}
+
 	serviceUser := p.pickServiceGitHubUser(ctx)
 	if serviceUser == nil {
 		p.client.Log.Warn("SLA digest cannot run: no connected GitHub user available to act as the service caller")
 		return nil, false
 	}
 
+	if p.shouldDeferForRateLimit(serviceUser.Token.AccessToken, "SLA digest") {
+		return nil, false
+	}
+
 	githubClient := p.githubConnectUser(ctx, serviceUser)
 	graphQLClient := p.graphQLConnect(serviceUser)
 
+	return p.collectOverdueSLAItems(ctx, githubClient, graphQLClient, orgList, targetDays, now)
+}
+
+// collectInstallationOverdueSLAItems runs the digest scan of each configured org as the GitHub
+// App installation on that org. Orgs the app isn't installed on are logged and skipped, with the
+// same ok semantics as collectAllOverdueSLAItems across the orgs that could be scanned. The whole
+// digest is deferred when an installation is low on rate limit budget.
+func (p *Plugin) collectInstallationOverdueSLAItems(ctx context.Context, orgList []string, targetDays int, now time.Time) ([]slaDigestEntry, bool) {
+	var out []slaDigestEntry
+	anyOrgOK := false
+	for _, org := range orgList {
+		if ctx.Err() != nil {
+			return nil, false
+		}
+
+		token, err := p.getInstallationToken(ctx, org)
+		if err != nil {
+			p.client.Log.Warn("SLA digest skipping org without a GitHub App installation", "org", org, "error", err.Error())
+			continue
+		}
+		if p.shouldDeferForRateLimit(token, "SLA digest") {
+			return nil, false
+		}
+
+		githubClient, err := p.getInstallationClient(ctx, org)
+		if err != nil {
+			p.client.Log.Warn("SLA digest skipping org without a GitHub App installation", "org", org, "error", err.Error())
+			continue
+		}
+		graphQLClient, err := p.getInstallationGraphQLClient(ctx, org)
+		if err != nil {
+			p.client.Log.Warn("SLA digest skipping org without a GitHub App installation", "org", org, "error", err.Error())
+			continue
+		}
+
+		entries, ok := p.collectOverdueSLAItems(ctx, githubClient, graphQLClient, []string{org}, targetDays, now)
+		if !ok {
+			continue
+		}
+		anyOrgOK = true
+		out = append(out, entries...)
+	}
+
+	if !anyOrgOK || ctx.Err() != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// collectOverdueSLAItems scans the open pull requests of the orgs with the given clients. See
+// collectAllOverdueSLAItems for the meaning of ok.
+func (p *Plugin) collectOverdueSLAItems(ctx context.Context, githubClient *github.Client, graphQLClient *graphql.Client, orgList []string, targetDays int, now time.Time) ([]slaDigestEntry, bool) {
 	allPRs, anyOrgOK := p.fetchAllOrgOpenPRs(ctx, graphQLClient, orgList)
 	if !anyOrgOK {
 		p.client.Log.Warn("SLA digest cannot run: no configured organization returned a successful PR search")
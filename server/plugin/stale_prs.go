@@ -0,0 +1,166 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+
+	"github.com/mattermost/mattermost-plugin-github/server/plugin/graphql"
+)
+
+const (
+	settingStalePRs = "stale-prs"
+
+	stalePRReminderMutexKey = "stale_pr_reminder_mutex"
+	stalePRReminderInterval = 7 * 24 * time.Hour
+	// stalePRReminderPollInterval is how often the scheduler looks for users whose weekly reminder is due.
+	stalePRReminderPollInterval = time.Hour
+	stalePRReminderUserTimeout  = 2 * time.Minute
+
+	reviewDecisionApproved         = "APPROVED"
+	reviewDecisionChangesRequested = "CHANGES_REQUESTED"
+)
+
+// stalePRGroups are a user's open pull requests that need their attention, each PR listed in at most one group.
+type stalePRGroups struct {
+	ChangesRequested []graphql.AuthoredPR
+	Approved         []graphql.AuthoredPR
+	Inactive         []graphql.AuthoredPR
+}
+
+func (g stalePRGroups) isEmpty() bool {
+	return len(g.ChangesRequested) == 0 && len(g.Approved) == 0 && len(g.Inactive) == 0
+}
+
+// classifyStalePRs sorts the PRs into those with changes requested but no newer commits, those
+// approved but not merged, and those with no activity for staleDays.
+func classifyStalePRs(prs []graphql.AuthoredPR, now time.Time, staleDays int) stalePRGroups {
+	var groups stalePRGroups
+	staleBefore := now.AddDate(0, 0, -staleDays)
+	for _, pr := range prs {
+		switch {
+		case pr.ReviewDecision == reviewDecisionChangesRequested && !pr.LastCommitAt.After(pr.ChangesRequestedAt):
+			groups.ChangesRequested = append(groups.ChangesRequested, pr)
+		case pr.ReviewDecision == reviewDecisionApproved:
+			groups.Approved = append(groups.Approved, pr)
+		case pr.UpdatedAt.Before(staleBefore):
+			groups.Inactive = append(groups.Inactive, pr)
+		}
+	}
+
+	return groups
+}
+
+func buildStalePRMessage(groups stalePRGroups, staleDays int) string {
+	var text strings.Builder
+	text.WriteString("#### Your pull requests that need attention\n")
+
+	writeGroup := func(title string, prs []graphql.AuthoredPR) {
+		if len(prs) == 0 {
+			return
+		}
+		fmt.Fprintf(&text, "\n##### %s\n", title)
+		for _, pr := range prs {
+			fmt.Fprintf(&text, "* %s - [#%d %s](%s)\n", pr.RepoFullName, pr.Number, pr.Title, pr.URL)
+		}
+	}
+
+	writeGroup("Changes requested, no new commits", groups.ChangesRequested)
+	writeGroup("Approved but not merged", groups.Approved)
+	writeGroup(fmt.Sprintf("No activity for %d days", staleDays), groups.Inactive)
+
+	return text.String()
+}
+
+// runStalePRReminderScheduler loops until ctx is cancelled, sending the weekly stale pull request
+// reminder to every connected user who opted in with `/github settings stale-prs on`.
+func (p *Plugin) runStalePRReminderScheduler(ctx context.Context) {
+	for {
+		if p.getConfiguration().StalePullRequestDays > 0 {
+			p.sendDueStalePRReminders(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(stalePRReminderPollInterval):
+		}
+	}
+}
+
+// sendDueStalePRReminders sends the reminder to every opted-in user whose last one is at least a week old.
+// A cluster mutex ensures only one node sends reminders in HA setups.
+func (p *Plugin) sendDueStalePRReminders(ctx context.Context) {
+	m, err := cluster.NewMutex(p.API, stalePRReminderMutexKey)
+	if err != nil {
+		p.client.Log.Warn("Failed to create cluster mutex for stale PR reminders", "error", err.Error())
+		return
+	}
+	if err = m.LockWithContext(ctx); err != nil {
+		return
+	}
+	defer m.Unlock()
+
+	allKeys, _, err := p.listUserTokenKeys()
+	if err != nil {
+		p.client.Log.Warn("Failed to list connected users for stale PR reminders", "error", err.Error())
+		return
+	}
+
+	for _, key := range allKeys {
+		if ctx.Err() != nil {
+			return
+		}
+
+		info, apiErr := p.getGitHubUserInfo(strings.TrimSuffix(key, githubTokenKey))
+		if apiErr != nil || info.Settings == nil || !info.Settings.StalePRReminder {
+			continue
+		}
+		if time.Since(time.UnixMilli(info.LastStalePRReminderAt)) < stalePRReminderInterval {
+			continue
+		}
+		// The reminder is retried on a later tick, as it isn't marked as sent.
+		if p.shouldDeferForRateLimit(info.Token.AccessToken, "stale PR reminder") {
+			continue
+		}
+
+		userCtx, cancel := context.WithTimeout(ctx, stalePRReminderUserTimeout)
+		err := p.postStalePRReminder(userCtx, info)
+		cancel()
+		if err != nil {
+			p.client.Log.Warn("Failed to send stale PR reminder", "userID", info.UserID, "error", err.Error())
+			continue
+		}
+
+		sentAt := time.Now().UnixMilli()
+		if err := p.updateGitHubUserInfo(info.UserID, func(stored *GitHubUserInfo) {
+			stored.LastStalePRReminderAt = sentAt
+		}); err != nil {
+			p.client.Log.Warn("Failed to store github info after stale PR reminder", "userID", info.UserID, "error", err.Error())
+		}
+	}
+}
+
+// postStalePRReminder DMs the user their open pull requests that need attention. Nothing is sent
+// when none of them do.
+func (p *Plugin) postStalePRReminder(ctx context.Context, info *GitHubUserInfo) error {
+	prs, err := p.graphQLConnect(info).GetAuthoredOpenPRs(ctx)
+	if err != nil {
+		return err
+	}
+
+	staleDays := p.getConfiguration().StalePullRequestDays
+	groups := classifyStalePRs(prs, time.Now(), staleDays)
+	if groups.isEmpty() {
+		return nil
+	}
+
+	p.CreateBotDMPost(info.UserID, buildStalePRMessage(groups, staleDays), "custom_git_stale_prs")
+	return nil
+}
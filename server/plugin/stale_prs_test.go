@@ -0,0 +1,77 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-plugin-github/server/plugin/graphql"
+)
+
+func TestClassifyStalePRs(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) time.Time {
+		return now.AddDate(0, 0, -days)
+	}
+
+	tests := []struct {
+		name     string
+		pr       graphql.AuthoredPR
+		expected string
+	}{
+		{
+			name:     "Changes requested with no newer commits",
+			pr:       graphql.AuthoredPR{ReviewDecision: reviewDecisionChangesRequested, LastCommitAt: daysAgo(3), ChangesRequestedAt: daysAgo(2), UpdatedAt: daysAgo(2)},
+			expected: "changes_requested",
+		},
+		{
+			name:     "Changes requested but commits were pushed since",
+			pr:       graphql.AuthoredPR{ReviewDecision: reviewDecisionChangesRequested, LastCommitAt: daysAgo(1), ChangesRequestedAt: daysAgo(2), UpdatedAt: daysAgo(1)},
+			expected: "",
+		},
+		{
+			name:     "Approved but not merged",
+			pr:       graphql.AuthoredPR{ReviewDecision: reviewDecisionApproved, UpdatedAt: daysAgo(1)},
+			expected: "approved",
+		},
+		{
+			name:     "No activity for longer than the threshold",
+			pr:       graphql.AuthoredPR{ReviewDecision: "REVIEW_REQUIRED", UpdatedAt: daysAgo(10)},
+			expected: "inactive",
+		},
+		{
+			name:     "Recently updated",
+			pr:       graphql.AuthoredPR{ReviewDecision: "REVIEW_REQUIRED", UpdatedAt: daysAgo(2)},
+			expected: "",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			groups := classifyStalePRs([]graphql.AuthoredPR{tc.pr}, now, 7)
+
+			assert.Equal(t, tc.expected == "changes_requested", len(groups.ChangesRequested) == 1)
+			assert.Equal(t, tc.expected == "approved", len(groups.Approved) == 1)
+			assert.Equal(t, tc.expected == "inactive", len(groups.Inactive) == 1)
+			assert.Equal(t, tc.expected == "", groups.isEmpty())
+		})
+	}
+}
+
+func TestBuildStalePRMessage(t *testing.T) {
+	groups := stalePRGroups{
+		Approved: []graphql.AuthoredPR{{RepoFullName: "mattermost/server", Number: 12, Title: "Fix typo", URL: "https://github.com/mattermost/server/pull/12"}},
+		Inactive: []graphql.AuthoredPR{{RepoFullName: "mattermost/docs", Number: 3, Title: "Add docs", URL: "https://github.com/mattermost/docs/pull/3"}},
+	}
+
+	message := buildStalePRMessage(groups, 7)
+
+	assert.Equal(t, "#### Your pull requests that need attention\n"+
+		"\n##### Approved but not merged\n"+
+		"* mattermost/server - [#12 Fix typo](https://github.com/mattermost/server/pull/12)\n"+
+		"\n##### No activity for 7 days\n"+
+		"* mattermost/docs - [#3 Add docs](https://github.com/mattermost/docs/pull/3)\n", message)
+}
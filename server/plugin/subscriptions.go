@@ -13,6 +13,8 @@ import (
 	"strings"
 
 	"github.com/google/go-github/v54/github"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
 	"github.com/pkg/errors"
 	"golang.org/x/oauth2"
 )
@@ -197,26 +199,54 @@ func (s *Subscription) excludedRepoForSub(repo *github.Repository) bool {
 }
 
 func (p *Plugin) Subscribe(ctx context.Context, githubClient *github.Client, userID, owner, repo, channelID string, features Features, flags SubscriptionFlags) error {
+	auditRec := plugin.MakeAuditRecord("subscribe", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = userID
+	model.AddEventParameterAuditableToAuditRec(auditRec, "subscription", SubscribeAuditParams{
+		ChannelID:  channelID,
+		Repository: fullNameFromOwnerAndRepo(strings.ToLower(owner), strings.ToLower(repo)),
+		Features:   features.String(),
+		Flags:      flags.String(),
+	})
+
+	isPrivate, err := p.subscribe(ctx, githubClient, userID, owner, repo, channelID, features, flags)
+	if err != nil {
+		auditRec.AddErrorDesc(err.Error())
+		return err
+	}
+
+	auditRec.Success()
+	auditRec.AddEventResultState(SubscribeAuditResult{
+		PrivateRepository: isPrivate,
+	})
+
+	return nil
+}
+
+// subscribe adds the subscription of the channel to the repository, or to the organization when
+// repo is empty, returning if the repository is private.
+func (p *Plugin) subscribe(ctx context.Context, githubClient *github.Client, userID, owner, repo, channelID string, features Features, flags SubscriptionFlags) (bool, error) {
 	if owner == "" {
-		return errors.Errorf("invalid repository")
+		return false, errors.Errorf("invalid repository")
 	}
 
 	owner = strings.ToLower(owner)
 	repo = strings.ToLower(repo)
 
 	if err := p.checkOrg(owner); err != nil {
-		return errors.Wrap(err, "organization not supported")
+		return false, errors.Wrap(err, "organization not supported")
 	}
 
 	if flags.ExcludeOrgMembers && !p.isOrganizationLocked() {
-		return errors.New("Unable to set --exclude-org-member flag. The GitHub plugin is not locked to a single organization.")
+		return false, errors.New("Unable to set --exclude-org-member flag. The GitHub plugin is not locked to a single organization.")
 	}
 
 	if flags.IncludeOnlyOrgMembers && !p.isOrganizationLocked() {
-		return errors.New("Unable to set --include-only-org-members flag. The GitHub plugin is not locked to a single organization.")
+		return false, errors.New("Unable to set --include-only-org-members flag. The GitHub plugin is not locked to a single organization.")
 	}
 
 	var err, cErr error
+	isPrivate := false
 
 	if repo == "" {
 		var ghOrg *github.Organization
@@ -231,7 +261,7 @@ func (p *Plugin) Subscribe(ctx context.Context, githubClient *github.Client, use
 			var ghUser *github.User
 			ghUser, _, err = githubClient.Users.Get(ctx, owner)
 			if ghUser == nil {
-				return errors.Errorf("Unknown organization %s", owner)
+				return false, errors.Errorf("Unknown organization %s", owner)
 			}
 		}
 	} else {
@@ -245,13 +275,14 @@ func (p *Plugin) Subscribe(ctx context.Context, githubClient *github.Client, use
 		})
 
 		if ghRepo == nil {
-			return errors.Errorf("unknown repository %s", fullNameFromOwnerAndRepo(owner, repo))
+			return false, errors.Errorf("unknown repository %s", fullNameFromOwnerAndRepo(owner, repo))
 		}
+		isPrivate = ghRepo.GetPrivate()
 	}
 
 	if cErr != nil {
 		p.client.Log.Warn("Failed to get repository or org for subscribe action", "error", err.Error())
-		return errors.Errorf("Encountered an error subscribing to %s", fullNameFromOwnerAndRepo(owner, repo))
+		return false, errors.Errorf("Encountered an error subscribing to %s", fullNameFromOwnerAndRepo(owner, repo))
 	}
 
 	sub := &Subscription{
@@ -263,10 +294,10 @@ func (p *Plugin) Subscribe(ctx context.Context, githubClient *github.Client, use
 	}
 
 	if err := p.AddSubscription(fullNameFromOwnerAndRepo(owner, repo), sub); err != nil {
-		return errors.Wrap(err, "could not add subscription")
+		return false, errors.Wrap(err, "could not add subscription")
 	}
 
-	return nil
+	return isPrivate, nil
 }
 
 func (p *Plugin) SubscribeOrg(ctx context.Context, githubClient *github.Client, userID, org, channelID string, features Features, flags SubscriptionFlags) error {
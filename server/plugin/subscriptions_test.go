@@ -9,7 +9,9 @@ import (
 
 	"github.com/google/go-github/v54/github"
 	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost/server/public/pluginapi"
@@ -208,6 +210,13 @@ func TestSubscribe(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Helper()
+			api := &plugintest.API{}
+			api.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+				return rec.EventName == "subscribe" && rec.Status == model.AuditStatusFail &&
+					rec.EventData.Parameters["subscription"].(map[string]any)["repository"] == "test-owner/test-repo"
+			})).Return().Once()
+			tt.plugin.SetAPI(api)
+
 			err := tt.plugin.Subscribe(
 				context.Background(),
 				github.NewClient(nil),
@@ -220,6 +229,7 @@ func TestSubscribe(t *testing.T) {
 			)
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), tt.errMsg)
+			api.AssertExpectations(t)
 		})
 	}
 }
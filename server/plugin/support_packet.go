@@ -25,6 +25,9 @@ type SupportPacket struct {
 
 	ConnectedUserCount int64 `yaml:"connected_user_count"`
 	IsOAuthConfigured  bool  `yaml:"is_oauth_configured"`
+
+	// RateLimits are the GitHub rate limit budgets of the tokens used since their last reset.
+	RateLimits []rateLimitBudget `yaml:"rate_limits"`
 }
 
 func (p *Plugin) GenerateSupportData(_ *plugin.Context) ([]*model.FileData, error) {
@@ -41,6 +44,7 @@ func (p *Plugin) GenerateSupportData(_ *plugin.Context) ([]*model.FileData, erro
 		Version:            Manifest.Version,
 		ConnectedUserCount: connectedUserCount,
 		IsOAuthConfigured:  config.IsOAuthConfigured(),
+		RateLimits:         p.rateLimits.list(),
 	}
 	body, err := yaml.Marshal(diagnostics)
 	if err != nil {
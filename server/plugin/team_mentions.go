@@ -0,0 +1,252 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v54/github"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"golang.org/x/oauth2"
+)
+
+const (
+	teamMembersKeyPrefix = "team_members_v1_"
+	teamMembersCacheTTL  = time.Hour
+
+	// maxTeamMentionMembers bounds how many people a single @org/team mention can notify.
+	// Mentions of larger teams are ignored.
+	maxTeamMentionMembers = 100
+)
+
+var teamMentionRegex = regexp.MustCompile(`(^|[^\w@/])@([a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)/([a-zA-Z0-9][a-zA-Z0-9_.-]*)`)
+
+type teamMention struct {
+	Org  string
+	Slug string
+}
+
+// teamMembers is the cached member list of a GitHub team.
+type teamMembers struct {
+	Logins []string `json:"logins"`
+	// TooLarge is set when the team has more than maxTeamMentionMembers members.
+	TooLarge bool `json:"too_large"`
+	// Fetched distinguishes a cached empty team from a cache miss.
+	Fetched bool `json:"fetched"`
+}
+
+// parseGitHubTeamMentionsFromText returns the deduplicated @org/team mentions in text, along with
+// text with those mentions removed so they aren't mistaken for @org user mentions.
+func parseGitHubTeamMentionsFromText(text string) ([]teamMention, string) {
+	seen := map[string]bool{}
+	var teams []teamMention
+	for _, match := range teamMentionRegex.FindAllStringSubmatch(text, -1) {
+		team := teamMention{Org: match[2], Slug: strings.TrimRight(match[3], ".")}
+		key := strings.ToLower(team.Org + "/" + team.Slug)
+		if team.Slug == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		teams = append(teams, team)
+	}
+
+	return teams, teamMentionRegex.ReplaceAllString(text, "$1")
+}
+
+// teamMembersKey returns the cache key of the team's members as listed with the credentials
+// identified by resolverID, so a member list is only reused by whoever was able to list it.
+func teamMembersKey(resolverID, org, slug string) string {
+	sum := sha256.Sum256([]byte(resolverID + ":" + strings.ToLower(org+"/"+slug)))
+	return teamMembersKeyPrefix + hex.EncodeToString(sum[:16])
+}
+
+// getMentionedGitHubUsernames returns the GitHub users mentioned in body, expanding mentions of
+// teams of the repository's organization to their members.
+func (p *Plugin) getMentionedGitHubUsernames(body string, repo *github.Repository, sender string) []string {
+	teams, body := parseGitHubTeamMentionsFromText(body)
+	usernames := parseGitHubUsernamesFromText(body)
+	if len(teams) == 0 {
+		return usernames
+	}
+
+	seen := map[string]bool{}
+	for _, username := range usernames {
+		seen[strings.ToLower(username)] = true
+	}
+
+	org, _, _ := strings.Cut(repo.GetFullName(), "/")
+	for _, team := range teams {
+		// GitHub only notifies teams of the organization that owns the repository.
+		if !strings.EqualFold(team.Org, org) {
+			continue
+		}
+
+		for _, login := range p.getGitHubTeamMembers(team, sender) {
+			if seen[strings.ToLower(login)] {
+				continue
+			}
+			seen[strings.ToLower(login)] = true
+			usernames = append(usernames, login)
+		}
+	}
+
+	return usernames
+}
+
+// getGitHubTeamMembers returns the logins of the team's members, or nil when the team can't be
+// resolved or is larger than maxTeamMentionMembers. Member lists are cached in the KV store.
+//
+// Teams are listed with the comment author's token when they are connected, since they could see
+// the team they mentioned. Otherwise the service user or the GitHub App installation lists the team,
+// but only when it is visible to all members of the organization, so that mentioning a secret team
+// doesn't reach its members through credentials the author doesn't have.
+func (p *Plugin) getGitHubTeamMembers(team teamMention, sender string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	var resolverID string
+	var fetch func() (*teamMembers, error)
+	if info := p.getConnectedGitHubUser(sender); info != nil {
+		resolverID = rateLimitTokenID(info.Token.AccessToken)
+		fetch = func() (*teamMembers, error) { return p.fetchGitHubTeamMembers(ctx, info, team, false) }
+	} else if p.getConfiguration().IsGitHubAppConfigured() {
+		resolverID = "app"
+		fetch = func() (*teamMembers, error) { return p.fetchInstallationTeamMembers(ctx, team) }
+	} else if info := p.pickServiceGitHubUser(ctx); info != nil {
+		resolverID = rateLimitTokenID(info.Token.AccessToken)
+		fetch = func() (*teamMembers, error) { return p.fetchGitHubTeamMembers(ctx, info, team, true) }
+	} else {
+		return nil
+	}
+
+	key := teamMembersKey(resolverID, team.Org, team.Slug)
+	var cached teamMembers
+	if err := p.store.Get(key, &cached); err != nil {
+		p.client.Log.Warn("Failed to get cached team members", "team", team.Org+"/"+team.Slug, "error", err.Error())
+	}
+	if cached.Fetched {
+		if cached.TooLarge {
+			return nil
+		}
+		return cached.Logins
+	}
+
+	members, err := fetch()
+	if err != nil {
+		p.client.Log.Debug("Failed to list team members", "team", team.Org+"/"+team.Slug, "error", err.Error())
+		return nil
+	}
+
+	if _, err := p.store.Set(key, members, pluginapi.SetExpiry(teamMembersCacheTTL)); err != nil {
+		p.client.Log.Warn("Failed to cache team members", "team", team.Org+"/"+team.Slug, "error", err.Error())
+	}
+
+	if members.TooLarge {
+		return nil
+	}
+	return members.Logins
+}
+
+// getConnectedGitHubUser returns the connected account of the GitHub user, or nil when they
+// aren't connected.
+func (p *Plugin) getConnectedGitHubUser(githubUsername string) *GitHubUserInfo {
+	userID := p.getGitHubToUserIDMapping(githubUsername)
+	if userID == "" {
+		return nil
+	}
+
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		return nil
+	}
+
+	return info
+}
+
+// fetchGitHubTeamMembers lists the team's members as the connected user. With visibleOnly, only
+// the members of teams visible to all members of the organization are listed.
+func (p *Plugin) fetchGitHubTeamMembers(ctx context.Context, info *GitHubUserInfo, team teamMention, visibleOnly bool) (*teamMembers, error) {
+	githubClient := p.githubConnectUser(ctx, info)
+
+	if visibleOnly {
+		var ghTeam *github.Team
+		if err := p.useGitHubClient(info, func(info *GitHubUserInfo, token *oauth2.Token) error {
+			var err error
+			ghTeam, _, err = githubClient.Teams.GetTeamBySlug(ctx, team.Org, team.Slug)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		if !isVisibleGitHubTeam(ghTeam) {
+			return &teamMembers{Fetched: true}, nil
+		}
+	}
+
+	return listGitHubTeamMembers(func(opts *github.TeamListTeamMembersOptions) (page []*github.User, resp *github.Response, err error) {
+		err = p.useGitHubClient(info, func(info *GitHubUserInfo, token *oauth2.Token) error {
+			page, resp, err = githubClient.Teams.ListTeamMembersBySlug(ctx, team.Org, team.Slug, opts)
+			return err
+		})
+		return page, resp, err
+	})
+}
+
+// fetchInstallationTeamMembers lists the members of the team as the GitHub App installation on its
+// org, when the team is visible to all members of the organization.
+func (p *Plugin) fetchInstallationTeamMembers(ctx context.Context, team teamMention) (*teamMembers, error) {
+	githubClient, err := p.getInstallationClient(ctx, team.Org)
+	if err != nil {
+		return nil, err
+	}
+
+	ghTeam, _, err := githubClient.Teams.GetTeamBySlug(ctx, team.Org, team.Slug)
+	if err != nil {
+		return nil, err
+	}
+	if !isVisibleGitHubTeam(ghTeam) {
+		return &teamMembers{Fetched: true}, nil
+	}
+
+	return listGitHubTeamMembers(func(opts *github.TeamListTeamMembersOptions) ([]*github.User, *github.Response, error) {
+		return githubClient.Teams.ListTeamMembersBySlug(ctx, team.Org, team.Slug, opts)
+	})
+}
+
+// isVisibleGitHubTeam returns if the team is visible to all members of its organization, as
+// opposed to secret teams only their members and owners can see.
+func isVisibleGitHubTeam(team *github.Team) bool {
+	return team.GetPrivacy() == "closed"
+}
+
+func listGitHubTeamMembers(listPage func(opts *github.TeamListTeamMembersOptions) ([]*github.User, *github.Response, error)) (*teamMembers, error) {
+	members := &teamMembers{Fetched: true}
+	opts := &github.TeamListTeamMembersOptions{ListOptions: github.ListOptions{PerPage: 100}}
+
+	for {
+		page, resp, err := listPage(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, user := range page {
+			if login := user.GetLogin(); login != "" {
+				members.Logins = append(members.Logins, login)
+			}
+		}
+
+		if len(members.Logins) > maxTeamMentionMembers {
+			return &teamMembers{Fetched: true, TooLarge: true}, nil
+		}
+
+		if resp == nil || resp.NextPage == 0 {
+			return members, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
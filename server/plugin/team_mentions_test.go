@@ -0,0 +1,163 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-github/v54/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestParseGitHubTeamMentionsFromText(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		expectedTeams []teamMention
+		expectedText  string
+	}{
+		{
+			name:         "No team mentions",
+			text:         "cc @user1 and @user2",
+			expectedText: "cc @user1 and @user2",
+		},
+		{
+			name:          "Team mention at the end of a sentence",
+			text:          "Could @mattermost/core-devs take a look.",
+			expectedTeams: []teamMention{{Org: "mattermost", Slug: "core-devs"}},
+			expectedText:  "Could  take a look.",
+		},
+		{
+			name:          "Team and user mentions are split",
+			text:          "@mattermost/web @user1 @Mattermost/WEB",
+			expectedTeams: []teamMention{{Org: "mattermost", Slug: "web"}},
+			expectedText:  " @user1 ",
+		},
+		{
+			name:         "Email addresses and paths are ignored",
+			text:         "mail me at user@example.com/path",
+			expectedText: "mail me at user@example.com/path",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			teams, text := parseGitHubTeamMentionsFromText(tc.text)
+			assert.Equal(t, tc.expectedTeams, teams)
+			assert.Equal(t, tc.expectedText, text)
+		})
+	}
+}
+
+func TestGetMentionedGitHubUsernames(t *testing.T) {
+	repo := &github.Repository{FullName: github.String(MockOrgRepo)}
+
+	tests := []struct {
+		name     string
+		body     string
+		cached   teamMembers
+		expected []string
+	}{
+		{
+			name:     "Team members are added once",
+			body:     "@otherUser please ask @mockOrg/reviewers",
+			cached:   teamMembers{Fetched: true, Logins: []string{"OtherUser", "teamUser"}},
+			expected: []string{"otherUser", "teamUser"},
+		},
+		{
+			name:     "Teams above the size limit are ignored",
+			body:     "@mockOrg/reviewers",
+			cached:   teamMembers{Fetched: true, TooLarge: true},
+			expected: []string{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockKVStore, mockAPI, _, _, _ := GetTestSetup(t)
+			p := getPluginTest(mockAPI, mockKVStore)
+			info, err := GetMockGHUserInfo(p)
+			require.NoError(t, err)
+			expectUserIDMapping(mockKVStore, MockSender, MockUserID)
+			ExpectStoredGitHubUserInfo(mockKVStore, info)
+
+			// Member lists are cached per token, here the one of the connected comment author.
+			mockKVStore.EXPECT().Get(teamMembersKey(rateLimitTokenID(MockAccessToken), MockOrg, "reviewers"), mock.MatchedBy(func(val any) bool {
+				_, ok := val.(*teamMembers)
+				return ok
+			})).DoAndReturn(func(key string, value any) error {
+				*value.(*teamMembers) = tc.cached
+				return nil
+			}).Times(1)
+
+			usernames := p.getMentionedGitHubUsernames(tc.body, repo, MockSender)
+
+			assert.Equal(t, tc.expected, usernames)
+		})
+	}
+
+	t.Run("Teams of other organizations are ignored", func(t *testing.T) {
+		mockKVStore, mockAPI, _, _, _ := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKVStore)
+
+		usernames := p.getMentionedGitHubUsernames("@otherOrg/reviewers", repo, MockSender)
+
+		assert.Empty(t, usernames)
+	})
+}
+
+func TestFetchGitHubTeamMembers(t *testing.T) {
+	var memberRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/api/v3/orgs/mockOrg/teams/secret":
+			_ = json.NewEncoder(w).Encode(&github.Team{Slug: github.String("secret"), Privacy: github.String("secret")})
+		case "/api/v3/orgs/mockOrg/teams/reviewers":
+			_ = json.NewEncoder(w).Encode(&github.Team{Slug: github.String("reviewers"), Privacy: github.String("closed")})
+		case "/api/v3/orgs/mockOrg/teams/secret/members", "/api/v3/orgs/mockOrg/teams/reviewers/members":
+			memberRequests++
+			_ = json.NewEncoder(w).Encode([]*github.User{{Login: github.String("teamUser")}})
+		default:
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	mockKVStore, mockAPI, _, _, _ := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKVStore)
+	p.setConfiguration(&Configuration{EnterpriseBaseURL: server.URL, EnterpriseUploadURL: server.URL})
+	info := &GitHubUserInfo{UserID: MockUserID, GitHubUsername: MockUsername, Token: &oauth2.Token{AccessToken: "token"}}
+	mockKVStore.EXPECT().Set(MockUserID+githubLastAPICallKey, gomock.Any()).Return(true, nil).AnyTimes()
+
+	t.Run("the author's token lists secret teams", func(t *testing.T) {
+		memberRequests = 0
+		members, err := p.fetchGitHubTeamMembers(context.Background(), info, teamMention{Org: MockOrg, Slug: "secret"}, false)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"teamUser"}, members.Logins)
+		assert.Equal(t, 1, memberRequests)
+	})
+
+	t.Run("other tokens don't list secret teams", func(t *testing.T) {
+		memberRequests = 0
+		members, err := p.fetchGitHubTeamMembers(context.Background(), info, teamMention{Org: MockOrg, Slug: "secret"}, true)
+		require.NoError(t, err)
+		assert.True(t, members.Fetched)
+		assert.Empty(t, members.Logins)
+		assert.Zero(t, memberRequests)
+	})
+
+	t.Run("other tokens list visible teams", func(t *testing.T) {
+		memberRequests = 0
+		members, err := p.fetchGitHubTeamMembers(context.Background(), info, teamMention{Org: MockOrg, Slug: "reviewers"}, true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"teamUser"}, members.Logins)
+		assert.Equal(t, 1, memberRequests)
+	})
+}
@@ -68,6 +68,11 @@ func init() {
 		return mdCommentRegex.ReplaceAllString(body, "")
 	}
 
+	// Trim away the marker of comments replied from a Mattermost thread
+	funcMap["removeSyncedCommentMarker"] = func(body string) string {
+		return syncedCommentMarkerRegex.ReplaceAllString(body, "")
+	}
+
 	funcMap["cleanBody"] = func(body string) string {
 		cleaned := body
 		if strings.Contains(cleaned, "notifications@github.com") {
@@ -286,6 +291,17 @@ Reviewers: {{range $i, $el := .RequestedReviewers -}} {{- if $i}}, {{end}}{{temp
 
 	template.Must(masterTemplate.New("reopenedPR").Funcs(funcMap).Parse(`
 {{template "repo" .GetRepo}} Pull request {{template "pullRequest" .GetPullRequest}} was reopened by {{template "user" .GetSender}}.
+`))
+
+	template.Must(masterTemplate.New("livePR").Funcs(funcMap).Parse(`
+#### {{.PullRequest.GetTitle}}
+##### [{{.Repo.GetFullName}}#{{.PullRequest.GetNumber}}]({{.PullRequest.GetHTMLURL}})
+**{{.Status}}** pull request by {{template "user" .PullRequest.GetUser}}
+{{- template "labels" dict "Labels" .PullRequest.Labels "RepositoryURL" .Repo.GetHTMLURL }}
+{{- template "assignee" .PullRequest }}
+{{- if .Reviewers }}
+Reviewers: {{range $i, $el := .Reviewers -}} {{- if $i}}, {{end}}{{template "user" $el.User}} ({{$el.State}}){{end -}}
+{{- end }}
 `))
 
 	template.Must(masterTemplate.New("pullRequestLabelled").Funcs(funcMap).Parse(`
@@ -298,6 +314,10 @@ Reviewers: {{range $i, $el := .RequestedReviewers -}} {{- if $i}}, {{end}}{{temp
 {{template "user" .GetSender}} mentioned you on [{{.GetRepo.GetFullName}}#{{.GetPullRequest.GetNumber}}]({{.GetPullRequest.GetHTMLURL}}) - {{.GetPullRequest.GetTitle}}:
 {{.GetPullRequest.GetBody | trimBody | quote | replaceAllGitHubUsernames}}`))
 
+	template.Must(masterTemplate.New("issueMentionNotification").Funcs(funcMap).Parse(`
+{{template "user" .GetSender}} mentioned you on [{{.GetRepo.GetFullName}}#{{.GetIssue.GetNumber}}]({{.GetIssue.GetHTMLURL}}) - {{.GetIssue.GetTitle}}:
+{{.GetIssue.GetBody | removeComments | trimBody | quote | replaceAllGitHubUsernames}}`))
+
 	template.Must(masterTemplate.New("newIssue").Funcs(funcMap).Parse(`
 {{ if eq .Config.Style "collapsed" -}}
 {{template "repo" .Event.GetRepo}} New issue {{template "issue" .Event.GetIssue}} opened by {{template "user" .Event.GetSender}}{{template "subscriptionLabel" .Label}}.
@@ -351,7 +371,7 @@ Reviewers: {{range $i, $el := .RequestedReviewers -}} {{- if $i}}, {{end}}{{temp
 	template.Must(masterTemplate.New("issueComment").Funcs(funcMap).Parse(`
 {{template "repo" .GetRepo}} New comment by {{template "user" .GetSender}} on {{template "issue" .Issue}}:
 
-{{.GetComment.GetBody | trimBody | replaceAllGitHubUsernames}}
+{{.GetComment.GetBody | removeSyncedCommentMarker | trimBody | replaceAllGitHubUsernames}}
 `))
 
 	template.Must(masterTemplate.New("pullRequestReviewEvent").Funcs(funcMap).Parse(`
@@ -367,7 +387,7 @@ Reviewers: {{range $i, $el := .RequestedReviewers -}} {{- if $i}}, {{end}}{{temp
 	template.Must(masterTemplate.New("newReviewComment").Funcs(funcMap).Parse(`
 {{template "repo" .GetRepo}} New review comment by {{template "user" .GetSender}} on {{template "pullRequest" .GetPullRequest}}:
 
-{{.GetComment.GetBody | trimBody | replaceAllGitHubUsernames}}
+{{.GetComment.GetBody | removeSyncedCommentMarker | trimBody | replaceAllGitHubUsernames}}
 `))
 
 	template.Must(masterTemplate.New("reviewCommentMentionNotification").Funcs(funcMap).Parse(`
@@ -509,17 +529,24 @@ Reviewers: {{range $i, $el := .RequestedReviewers -}} {{- if $i}}, {{end}}{{temp
 		"    	* Defaults to `pulls,issues,creates,deletes`\n\n" +
 		"    * `--exclude-org-member` - events triggered by organization members will not be delivered (the GitHub organization config should be set, otherwise this flag has not effect)\n" +
 		"    * `--include-only-org-members` - events triggered only by organization members will be delivered (the GitHub organization config should be set, otherwise this flag has not effect)\n" +
-		"    * `--render-style` - notifications will be delivered in the specified style (for example, the body of a pull request will not be displayed). Supported values are `collapsed`, `skip-body` or `default` (same as omitting the flag).\n" +
+		"    * `--render-style` - notifications will be delivered in the specified style (for example, the body of a pull request will not be displayed). Supported values are `collapsed`, `skip-body`, `live` or `default` (same as omitting the flag). With `live`, each pull request gets a single post that is updated with its status, labels and reviewers, and its events are posted as replies in the thread of that post.\n" +
 		"* `/github subscriptions delete owner[/repo]` - Unsubscribe the current channel from a repository\n" +
 		"* `/github me` - Display the connected GitHub account\n" +
 		"* `/github settings [setting] [value]` - Update your user settings\n" +
-		"  * `setting` can be `notifications` or `reminders`\n" +
+		"  * `setting` can be `notifications`, `reminders`, `stale-prs` or `thread-replies`\n" +
 		"  * `value` can be `on` or `off`\n" +
 		"* `/github setup` - Setup your Github plugin\n" +
-		"* `/github mute` - Managed muted GitHub users. You'll not receive notifications for comments in your PRs and issues from those users.\n" +
-		"  * `/github mute list` - list your muted GitHub users\n" +
+		"* `/github admin users` - List the users connected to GitHub, for System Admins\n" +
+		"* `/github admin disconnect @username` - Disconnect a user from their GitHub account, for System Admins\n" +
+		"* `/github admin mappings` - Report GitHub accounts connected by several users or mapped to the wrong user, for System Admins\n" +
+		"* `/github mute` - Managed muted GitHub users, repositories, organizations and keywords. You'll not receive notifications for comments in your PRs and issues from those users, or about those repositories, organizations and titles.\n" +
+		"  * `/github mute list` - list your muted GitHub users, repositories, organizations and keywords\n" +
 		"  * `/github mute add [username]` - add a GitHub user to your muted list\n" +
+		"  * `/github mute repo owner/repo [--for duration]` - mute notifications about a repository, optionally for a duration like `12h`, `7d` or `2w`\n" +
+		"  * `/github mute org owner [--for duration]` - mute notifications about an organization\n" +
+		"  * `/github mute keyword [keyword] [--for duration]` - mute notifications about issues and pull requests whose title contains the keyword\n" +
 		"  * `/github mute delete [username]` - remove a GitHub user from your muted list\n" +
+		"  * `/github mute delete repo|org|keyword [value]` - remove a repository, organization or keyword from your muted list\n" +
 		"  * `/github mute delete-all` - unmute all GitHub users\n" +
 		"* `/github default-repo` - Manage the default repository per channel for the user. The default repository will be auto selected for creating the issues\n" +
 		"  * `/github default-repo set owner[/repo]` - set the default repo for the channel\n" +
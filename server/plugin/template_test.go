@@ -897,6 +897,25 @@ git-get-head sounds like a great feature we should support
 		require.Equal(t, expected, actual)
 	})
 
+	t.Run("body replied from a Mattermost thread", func(t *testing.T) {
+		expected := `
+[\[mattermost-plugin-github\]](https://github.com/mattermost/mattermost-plugin-github) New comment by [panda](https://github.com/panda) on [#1 Implement git-get-head](https://github.com/mattermost/mattermost-plugin-github/issues/1):
+
+git-get-head sounds like a great feature we should support
+`
+
+		actual, err := renderTemplate("issueComment", &github.IssueCommentEvent{
+			Repo:   &repo,
+			Issue:  &issue,
+			Sender: &user,
+			Comment: &github.IssueComment{
+				Body: sToP("git-get-head sounds like a great feature we should support\n\n" + getSyncedCommentMarker("pq8ty6jogjgs9d5mwt1zzqy5xo")),
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
 	t.Run("non-email body with mentions", withGitHubUserNameMapping(func(t *testing.T) {
 		expected := `
 [\[mattermost-plugin-github\]](https://github.com/mattermost/mattermost-plugin-github) New comment by @pandabot on [#1 Implement git-get-head](https://github.com/mattermost/mattermost-plugin-github/issues/1):
@@ -1331,6 +1350,21 @@ func TestIssueNotification(t *testing.T) {
 	})
 }
 
+func TestIssueMentionNotificationTemplate(t *testing.T) {
+	expected := `
+[panda](https://github.com/panda) mentioned you on [mattermost-plugin-github#1](https://github.com/mattermost/mattermost-plugin-github/issues/1) - Implement git-get-head:
+>git-get-head sounds like a great feature we should support`
+
+	actual, err := renderTemplate("issueMentionNotification", &github.IssuesEvent{
+		Repo:   &repo,
+		Action: sToP("edited"),
+		Sender: &user,
+		Issue:  &issue,
+	})
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
 func TestPullRequestReviewNotification(t *testing.T) {
 	t.Run("approved", func(t *testing.T) {
 		expected := `
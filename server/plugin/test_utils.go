@@ -59,6 +59,21 @@ func GetMockGHUserInfo(p *Plugin) (*GitHubUserInfo, error) {
 	return gitHubUserInfo, nil
 }
 
+// ExpectStoredGitHubUserInfo makes the store return a copy of the stored, encrypted, info of the user.
+func ExpectStoredGitHubUserInfo(mockKvStore *mocks.MockKvStore, info *GitHubUserInfo) *gomock.Call {
+	return mockKvStore.EXPECT().Get(info.UserID+githubTokenKey, gomock.Any()).DoAndReturn(func(key string, value any) error {
+		stored := *info
+		token := *info.Token
+		stored.Token = &token
+		if info.Settings != nil {
+			settings := *info.Settings
+			stored.Settings = &settings
+		}
+		*value.(**GitHubUserInfo) = &stored
+		return nil
+	})
+}
+
 func GetTestSetup(t *testing.T) (*mocks.MockKvStore, *plugintest.API, *mocks.MockLogger, *mocks.MockLogger, *Context) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -324,6 +339,12 @@ func GetMockIssueCommentEvent(action, body, sender string) *github.IssueCommentE
 	}
 }
 
+func GetMockIssueCommentEventWithChanges(body, previousBody, sender string) *github.IssueCommentEvent {
+	event := GetMockIssueCommentEvent(actionEdited, body, sender)
+	event.Changes = &github.EditChange{Body: &github.EditBody{From: github.String(previousBody)}}
+	return event
+}
+
 func GetMockIssueCommentEventWithURL(action, body, sender, url string) *github.IssueCommentEvent {
 	event := GetMockIssueCommentEvent(action, body, sender)
 	event.Issue.HTMLURL = github.String(url)
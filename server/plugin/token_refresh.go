@@ -0,0 +1,156 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+)
+
+const tokenRefreshMutexKeyPrefix = "token_refresh_mutex_"
+
+// errTokenRefreshFailed is returned when GitHub rejects the refresh of an expiring user token,
+// meaning the user has to connect again.
+var errTokenRefreshFailed = errors.New("failed to refresh the GitHub access token")
+
+// userTokenSource provides the access token of a connected user, refreshing it when it expires.
+type userTokenSource struct {
+	p      *Plugin
+	userID string
+
+	lock  sync.Mutex
+	token *oauth2.Token
+}
+
+// newUserTokenSource returns the token source of the user's GitHub clients. Tokens without a
+// refresh token don't expire and are used as is.
+func (p *Plugin) newUserTokenSource(info *GitHubUserInfo) oauth2.TokenSource {
+	if info.Token.RefreshToken == "" {
+		return oauth2.StaticTokenSource(info.Token)
+	}
+
+	return &userTokenSource{p: p, userID: info.UserID, token: info.Token}
+}
+
+func (s *userTokenSource) Token() (*oauth2.Token, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.token.Valid() {
+		return s.token, nil
+	}
+
+	token, err := s.p.refreshUserToken(s.userID, s.token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	s.token = token
+
+	return token, nil
+}
+
+// refreshUserToken exchanges the user's refresh token for a new access token and stores it.
+// staleAccessToken is the access token the caller found expired or rejected. GitHub refresh
+// tokens can only be used once, so the refresh runs under a per-user cluster mutex, and a
+// token another request already refreshed is returned as is.
+func (p *Plugin) refreshUserToken(userID, staleAccessToken string) (*oauth2.Token, error) {
+	m, err := cluster.NewMutex(p.API, tokenRefreshMutexKeyPrefix+userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create mutex")
+	}
+	m.Lock()
+	defer m.Unlock()
+
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if info.Token.AccessToken != staleAccessToken && info.Token.Valid() {
+		return info.Token, nil
+	}
+	if info.Token.RefreshToken == "" {
+		return nil, errors.Wrap(errTokenRefreshFailed, "no refresh token")
+	}
+
+	conf, err := p.getOAuthConfig(info.AllowedPrivateRepos)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate OAuthConfig")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	token, err := conf.TokenSource(ctx, &oauth2.Token{RefreshToken: info.Token.RefreshToken}).Token()
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) {
+			return nil, errors.Wrap(errTokenRefreshFailed, err.Error())
+		}
+		// Network errors and the like don't mean the refresh token is invalid.
+		return nil, errors.Wrap(err, "failed to refresh the GitHub access token")
+	}
+
+	// storeGitHubUserInfo encrypts the token it stores in place.
+	stored := *token
+	info.Token = &stored
+	if err = p.storeGitHubUserInfo(info, p.getConfiguration().EncryptionKey); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// isTokenRevoked returns if err shows the user's GitHub authorization is gone: refreshing the
+// expiring token failed, or GitHub rejected a token that can't be refreshed.
+func (p *Plugin) isTokenRevoked(info *GitHubUserInfo, err error) bool {
+	if errors.Is(err, errTokenRefreshFailed) {
+		return true
+	}
+	if !strings.Contains(err.Error(), invalidTokenError) {
+		return false
+	}
+
+	token := info.Token
+	if token == nil {
+		stored, apiErr := p.getGitHubUserInfo(info.UserID)
+		if apiErr != nil {
+			return true
+		}
+		token = stored.Token
+	}
+	if token.RefreshToken == "" {
+		return true
+	}
+
+	// GitHub can reject an expiring token before its expiry, e.g. after the app's permissions
+	// changed, so the refresh decides whether the authorization is gone.
+	_, err = p.refreshUserToken(info.UserID, token.AccessToken)
+	return errors.Is(err, errTokenRefreshFailed)
+}
+
+// updateGitHubUserInfo applies update to the stored user info and stores it. The record is read
+// again under the user's token refresh mutex, so that a token refreshed while the caller was
+// using its copy of the record isn't overwritten by the stale, already used, refresh token.
+func (p *Plugin) updateGitHubUserInfo(userID string, update func(info *GitHubUserInfo)) error {
+	m, err := cluster.NewMutex(p.API, tokenRefreshMutexKeyPrefix+userID)
+	if err != nil {
+		return errors.Wrap(err, "failed to create mutex")
+	}
+	m.Lock()
+	defer m.Unlock()
+
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		return apiErr
+	}
+	update(info)
+
+	return p.storeGitHubUserInfo(info, p.getConfiguration().EncryptionKey)
+}
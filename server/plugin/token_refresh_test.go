@@ -0,0 +1,210 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestRefreshUserToken(t *testing.T) {
+	var refreshRequests int
+	var rejectRefresh bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		refreshRequests++
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "refresh_token", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "oldRefreshToken", r.PostForm.Get("refresh_token"))
+
+		w.Header().Set("Content-Type", "application/json")
+		if rejectRefresh {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "bad_refresh_token"}`)
+			return
+		}
+		fmt.Fprint(w, `{"access_token": "newAccessToken", "refresh_token": "newRefreshToken", "token_type": "bearer", "expires_in": 28800}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	testOAuthServerURL = server.URL
+	t.Cleanup(func() { testOAuthServerURL = "" })
+
+	storedUserInfo := func(t *testing.T, accessToken string, expiry time.Time) *GitHubUserInfo {
+		encryptedAccessToken, err := encrypt([]byte(testNewKey), accessToken)
+		require.NoError(t, err)
+		encryptedRefreshToken, err := encrypt([]byte(testNewKey), "oldRefreshToken")
+		require.NoError(t, err)
+
+		return &GitHubUserInfo{
+			UserID: "user1",
+			Token: &oauth2.Token{
+				AccessToken:  encryptedAccessToken,
+				RefreshToken: encryptedRefreshToken,
+				Expiry:       expiry,
+			},
+		}
+	}
+
+	setup := func(t *testing.T, info *GitHubUserInfo) (*Plugin, *gomock.Controller) {
+		p, api, mockKvStore, ctrl := setupRotationTest(t)
+		p.setConfiguration(&Configuration{
+			EncryptionKey:           testNewKey,
+			GitHubOAuthClientID:     "clientID",
+			GitHubOAuthClientSecret: "clientSecret",
+		})
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		mockKvStore.EXPECT().Get("user1"+githubTokenKey, gomock.Any()).DoAndReturn(func(key string, value **GitHubUserInfo) error {
+			*value = info
+			return nil
+		}).Times(1)
+		mockKvStore.EXPECT().Set("user1"+githubTokenKey, gomock.Any()).DoAndReturn(
+			func(key string, value any, opts ...pluginapi.KVSetOption) (bool, error) {
+				stored := value.(*GitHubUserInfo)
+				accessToken, err := decrypt([]byte(testNewKey), stored.Token.AccessToken)
+				require.NoError(t, err)
+				assert.Equal(t, "newAccessToken", accessToken)
+				refreshToken, err := decrypt([]byte(testNewKey), stored.Token.RefreshToken)
+				require.NoError(t, err)
+				assert.Equal(t, "newRefreshToken", refreshToken)
+				return true, nil
+			}).MaxTimes(1)
+
+		return p, ctrl
+	}
+
+	t.Run("refreshes and stores the token", func(t *testing.T) {
+		refreshRequests, rejectRefresh = 0, false
+		p, ctrl := setup(t, storedUserInfo(t, "oldAccessToken", time.Now().Add(-time.Minute)))
+		defer ctrl.Finish()
+
+		token, err := p.refreshUserToken("user1", "oldAccessToken")
+		require.NoError(t, err)
+		assert.Equal(t, "newAccessToken", token.AccessToken)
+		assert.Equal(t, "newRefreshToken", token.RefreshToken)
+		assert.True(t, token.Valid())
+		assert.Equal(t, 1, refreshRequests)
+	})
+
+	t.Run("uses a token refreshed by another request", func(t *testing.T) {
+		refreshRequests, rejectRefresh = 0, false
+		p, ctrl := setup(t, storedUserInfo(t, "otherAccessToken", time.Now().Add(time.Hour)))
+		defer ctrl.Finish()
+
+		token, err := p.refreshUserToken("user1", "oldAccessToken")
+		require.NoError(t, err)
+		assert.Equal(t, "otherAccessToken", token.AccessToken)
+		assert.Equal(t, 0, refreshRequests)
+	})
+
+	t.Run("rejected refresh", func(t *testing.T) {
+		refreshRequests, rejectRefresh = 0, true
+		p, ctrl := setup(t, storedUserInfo(t, "oldAccessToken", time.Now().Add(-time.Minute)))
+		defer ctrl.Finish()
+
+		_, err := p.refreshUserToken("user1", "oldAccessToken")
+		assert.True(t, errors.Is(err, errTokenRefreshFailed))
+		assert.Equal(t, 1, refreshRequests)
+	})
+}
+
+func TestNewUserTokenSource(t *testing.T) {
+	p := NewPlugin()
+
+	token := &oauth2.Token{AccessToken: "accessToken"}
+	ts := p.newUserTokenSource(&GitHubUserInfo{UserID: "user1", Token: token})
+	got, err := ts.Token()
+	require.NoError(t, err)
+	assert.Equal(t, token, got)
+
+	token = &oauth2.Token{AccessToken: "accessToken", RefreshToken: "refreshToken", Expiry: time.Now().Add(time.Hour)}
+	ts = p.newUserTokenSource(&GitHubUserInfo{UserID: "user1", Token: token})
+	require.IsType(t, &userTokenSource{}, ts)
+	got, err = ts.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "accessToken", got.AccessToken)
+}
+
+func TestDecryptRefreshToken(t *testing.T) {
+	encrypted, err := encrypt([]byte(testNewKey), "refreshToken")
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		stored   string
+		expected string
+	}{
+		"empty":      {stored: "", expected: ""},
+		"plain text": {stored: "ghr_refreshToken", expected: "ghr_refreshToken"},
+		"encrypted":  {stored: encrypted, expected: "refreshToken"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			actual, err := decryptRefreshToken([]byte(testNewKey), tc.stored)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestUpdateGitHubUserInfo(t *testing.T) {
+	p, _, mockKvStore, ctrl := setupRotationTest(t)
+	defer ctrl.Finish()
+
+	storedUserInfo := func(t *testing.T, accessToken, refreshToken string) *GitHubUserInfo {
+		encryptedAccessToken, err := encrypt([]byte(testNewKey), accessToken)
+		require.NoError(t, err)
+		encryptedRefreshToken, err := encrypt([]byte(testNewKey), refreshToken)
+		require.NoError(t, err)
+
+		return &GitHubUserInfo{
+			UserID:   "user1",
+			Token:    &oauth2.Token{AccessToken: encryptedAccessToken, RefreshToken: encryptedRefreshToken},
+			Settings: &UserSettings{},
+		}
+	}
+
+	stored := storedUserInfo(t, "oldAccessToken", "oldRefreshToken")
+	mockKvStore.EXPECT().Get("user1"+githubTokenKey, gomock.Any()).DoAndReturn(func(key string, value **GitHubUserInfo) error {
+		info := *stored
+		token := *stored.Token
+		info.Token = &token
+		*value = &info
+		return nil
+	}).Times(2)
+	mockKvStore.EXPECT().Set("user1"+githubTokenKey, gomock.Any()).DoAndReturn(
+		func(key string, value any, opts ...pluginapi.KVSetOption) (bool, error) {
+			info := value.(*GitHubUserInfo)
+			accessToken, err := decrypt([]byte(testNewKey), info.Token.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, "newAccessToken", accessToken)
+			refreshToken, err := decrypt([]byte(testNewKey), info.Token.RefreshToken)
+			require.NoError(t, err)
+			assert.Equal(t, "newRefreshToken", refreshToken)
+			assert.Equal(t, int64(1234), info.LastStalePRReminderAt)
+			return true, nil
+		}).Times(1)
+
+	info, apiErr := p.getGitHubUserInfo("user1")
+	require.Nil(t, apiErr)
+	assert.Equal(t, "oldAccessToken", info.Token.AccessToken)
+
+	// The token is refreshed, and stored, while the caller is using its copy of the record.
+	stored = storedUserInfo(t, "newAccessToken", "newRefreshToken")
+
+	err := p.updateGitHubUserInfo(info.UserID, func(stored *GitHubUserInfo) {
+		stored.LastStalePRReminderAt = 1234
+	})
+	require.NoError(t, err)
+}
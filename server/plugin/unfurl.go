@@ -0,0 +1,394 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v54/github"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// maxLinkPreviews sets the maximum number of issue, pull request and commit
+// links that are unfurled for a single message.
+const maxLinkPreviews = 5
+
+const (
+	linkKindPull   = "pull"
+	linkKindIssue  = "issues"
+	linkKindCommit = "commit"
+
+	colorOpen   = "#2cbe4e"
+	colorDraft  = "#6a737d"
+	colorMerged = "#6f42c1"
+	colorClosed = "#cb2431"
+)
+
+// linkPreview holds the parts of an issue, pull request or commit link that
+// is unfurled as a post attachment.
+type linkPreview struct {
+	word  string // the link
+	owner string
+	repo  string
+	kind  string // linkKindPull, linkKindIssue or linkKindCommit
+	id    string // issue or pull request number, or commit SHA
+}
+
+// newGitHubLinkRegex returns the regex matching issue, pull request and commit links on the GitHub host of baseURL.
+func newGitHubLinkRegex(baseURL string) *regexp.Regexp {
+	return regexp.MustCompile(`https?://(?:www\.)?` + githubHostPattern(baseURL) + `/(?P<user>[\w-]+)/(?P<repo>[\w-.]+)/(?P<kind>pull|issues|commit)/(?P<id>\w+)\b`)
+}
+
+// getLinkPreviews returns the deduplicated issue, pull request and commit links in msg. Links to a
+// file in a diff are left out, as they get a diff preview instead, and so are links in code and
+// markdown links, which the user quoted as is.
+func (p *Plugin) getLinkPreviews(msg string) []linkPreview {
+	skipped := getMarkdownQuotedRanges(msg)
+	for _, r := range p.getDiffReplacements(msg) {
+		skipped = append(skipped, [2]int{r.index, r.index + len(r.word)})
+	}
+
+	seen := map[string]bool{}
+	var previews []linkPreview
+	linkRegex := p.getGitHubLinkRegex()
+	indices := linkRegex.FindAllStringIndex(msg, -1)
+	for i, m := range linkRegex.FindAllStringSubmatch(msg, -1) {
+		if len(previews) >= maxLinkPreviews {
+			break
+		}
+		if _, ok := getEnclosingRangeEnd(skipped, indices[i][0]); ok {
+			continue
+		}
+
+		l := linkPreview{word: m[0]}
+		for j, name := range linkRegex.SubexpNames() {
+			switch name {
+			case "user":
+				l.owner = m[j]
+			case "repo":
+				l.repo = m[j]
+			case "kind":
+				l.kind = m[j]
+			case "id":
+				l.id = m[j]
+			}
+		}
+
+		if !isValidLinkPreviewID(l.kind, l.id) {
+			continue
+		}
+
+		key := strings.ToLower(strings.Join([]string{l.owner, l.repo, l.kind, l.id}, "/"))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		previews = append(previews, l)
+	}
+
+	return previews
+}
+
+func isValidLinkPreviewID(kind, id string) bool {
+	switch kind {
+	case linkKindPull, linkKindIssue:
+		n, err := strconv.Atoi(id)
+		return err == nil && n > 0
+	case linkKindCommit:
+		if len(id) < 7 || len(id) > 40 {
+			return false
+		}
+		for _, c := range id {
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// makeLinkPreviewAttachments fetches the linked issues, pull requests and commits
+// and returns an attachment for each one the user is allowed to preview. All links are
+// fetched concurrently under the deadline of ctx.
+func (p *Plugin) makeLinkPreviewAttachments(ctx context.Context, previews []linkPreview, ghClient *github.Client) []*model.MessageAttachment {
+	var wg sync.WaitGroup
+	results := make([]*model.MessageAttachment, len(previews))
+	for i, l := range previews {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = p.makeLinkPreviewAttachment(ctx, l, ghClient)
+		}()
+	}
+	wg.Wait()
+
+	var attachments []*model.MessageAttachment
+	for _, attachment := range results {
+		if attachment != nil {
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	return attachments
+}
+
+func (p *Plugin) makeLinkPreviewAttachment(ctx context.Context, l linkPreview, ghClient *github.Client) *model.MessageAttachment {
+	if allowed, _ := p.checkPermalinkRepo(ctx, l.owner, l.repo, ghClient); !allowed {
+		return nil
+	}
+
+	switch l.kind {
+	case linkKindPull:
+		number, _ := strconv.Atoi(l.id)
+		return p.makePullRequestPreview(ctx, ghClient, l.owner, l.repo, number)
+	case linkKindIssue:
+		number, _ := strconv.Atoi(l.id)
+		issue, _, err := ghClient.Issues.Get(ctx, l.owner, l.repo, number)
+		if err != nil {
+			p.client.Log.Warn("Error while fetching issue", "error", err.Error(), "repo", l.repo, "user", l.owner, "number", l.id)
+			return nil
+		}
+		// GitHub serves pull requests under /issues/ as well.
+		if issue.IsPullRequest() {
+			return p.makePullRequestPreview(ctx, ghClient, l.owner, l.repo, number)
+		}
+		return newIssuePreviewAttachment(issue, fullNameFromOwnerAndRepo(l.owner, l.repo))
+	case linkKindCommit:
+		commit, _, err := ghClient.Repositories.GetCommit(ctx, l.owner, l.repo, l.id, nil)
+		if err != nil {
+			p.client.Log.Warn("Error while fetching commit", "error", err.Error(), "repo", l.repo, "user", l.owner, "sha", l.id)
+			return nil
+		}
+		checks := p.getChecksSummary(ctx, ghClient, l.owner, l.repo, commit.GetSHA())
+		return newCommitPreviewAttachment(commit, fullNameFromOwnerAndRepo(l.owner, l.repo), checks)
+	default:
+		return nil
+	}
+}
+
+func (p *Plugin) makePullRequestPreview(ctx context.Context, ghClient *github.Client, owner, repo string, number int) *model.MessageAttachment {
+	pr, _, err := ghClient.PullRequests.Get(ctx, owner, repo, number)
+	if err != nil {
+		p.client.Log.Warn("Error while fetching pull request", "error", err.Error(), "repo", repo, "user", owner, "number", number)
+		return nil
+	}
+
+	reviews, _, err := ghClient.PullRequests.ListReviews(ctx, owner, repo, number, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		p.client.Log.Debug("Error while fetching pull request reviews", "error", err.Error(), "repo", repo, "user", owner, "number", number)
+	}
+
+	reviewStatus := getReviewSummary(reviews, len(pr.RequestedReviewers)+len(pr.RequestedTeams))
+	checks := p.getChecksSummary(ctx, ghClient, owner, repo, pr.GetHead().GetSHA())
+
+	return newPullRequestPreviewAttachment(pr, fullNameFromOwnerAndRepo(owner, repo), reviewStatus, checks)
+}
+
+// getChecksSummary combines the commit statuses and check runs of ref into a single CI state.
+func (p *Plugin) getChecksSummary(ctx context.Context, ghClient *github.Client, owner, repo, ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	combined, _, err := ghClient.Repositories.GetCombinedStatus(ctx, owner, repo, ref, nil)
+	if err != nil {
+		p.client.Log.Debug("Error while fetching combined status", "error", err.Error(), "repo", repo, "user", owner, "ref", ref)
+	}
+
+	runs, _, err := ghClient.Checks.ListCheckRunsForRef(ctx, owner, repo, ref, &github.ListCheckRunsOptions{ListOptions: github.ListOptions{PerPage: 100}})
+	if err != nil {
+		p.client.Log.Debug("Error while fetching check runs", "error", err.Error(), "repo", repo, "user", owner, "ref", ref)
+	}
+
+	return summarizeChecks(combined, runs)
+}
+
+// getReviewSummary describes the review state of a pull request from the latest review of each reviewer.
+func getReviewSummary(reviews []*github.PullRequestReview, pendingReviewers int) string {
+	latest := map[string]string{}
+	for _, review := range reviews {
+		state := review.GetState()
+		if state == "COMMENTED" || state == "PENDING" {
+			continue
+		}
+		latest[review.GetUser().GetLogin()] = state
+	}
+
+	approvals := 0
+	for _, state := range latest {
+		switch state {
+		case "CHANGES_REQUESTED":
+			return "Changes requested"
+		case "APPROVED":
+			approvals++
+		}
+	}
+
+	switch {
+	case approvals > 0:
+		return fmt.Sprintf("Approved (%d)", approvals)
+	case pendingReviewers > 0:
+		return "Review required"
+	default:
+		return "No reviews"
+	}
+}
+
+// summarizeChecks returns "Failing", "Pending" or "Passing", or an empty string when there are no checks.
+func summarizeChecks(combined *github.CombinedStatus, runs *github.ListCheckRunsResults) string {
+	failing, pending, passing := false, false, false
+
+	if combined.GetTotalCount() > 0 {
+		switch combined.GetState() {
+		case "failure", "error":
+			failing = true
+		case "pending":
+			pending = true
+		case "success":
+			passing = true
+		}
+	}
+
+	var checkRuns []*github.CheckRun
+	if runs != nil {
+		checkRuns = runs.CheckRuns
+	}
+	for _, run := range checkRuns {
+		if run.GetStatus() != "completed" {
+			pending = true
+			continue
+		}
+		switch run.GetConclusion() {
+		case "failure", "timed_out", "cancelled", "action_required":
+			failing = true
+		case "success":
+			passing = true
+		}
+	}
+
+	switch {
+	case failing:
+		return "Failing"
+	case pending:
+		return "Pending"
+	case passing:
+		return "Passing"
+	default:
+		return ""
+	}
+}
+
+func newPullRequestPreviewAttachment(pr *github.PullRequest, repoFullName, reviewStatus, checks string) *model.MessageAttachment {
+	state, color := "Open", colorOpen
+	switch {
+	case pr.GetMerged():
+		state, color = "Merged", colorMerged
+	case pr.GetState() == "closed":
+		state, color = "Closed", colorClosed
+	case pr.GetDraft():
+		state, color = "Draft", colorDraft
+	}
+
+	fields := []*model.MessageAttachmentField{
+		{Title: "State", Value: state, Short: true},
+		{Title: "Reviews", Value: reviewStatus, Short: true},
+	}
+	if checks != "" {
+		fields = append(fields, &model.MessageAttachmentField{Title: "Checks", Value: checks, Short: true})
+	}
+	fields = append(fields, &model.MessageAttachmentField{
+		Title: "Changes",
+		Value: fmt.Sprintf("+%d −%d in %d files", pr.GetAdditions(), pr.GetDeletions(), pr.GetChangedFiles()),
+		Short: true,
+	})
+	if labels := getLabelNames(pr.Labels); labels != "" {
+		fields = append(fields, &model.MessageAttachmentField{Title: "Labels", Value: labels, Short: false})
+	}
+
+	return &model.MessageAttachment{
+		Color:      color,
+		AuthorName: pr.GetUser().GetLogin(),
+		AuthorLink: pr.GetUser().GetHTMLURL(),
+		AuthorIcon: pr.GetUser().GetAvatarURL(),
+		Title:      fmt.Sprintf("%s#%d: %s", repoFullName, pr.GetNumber(), pr.GetTitle()),
+		TitleLink:  pr.GetHTMLURL(),
+		Fields:     fields,
+	}
+}
+
+func newIssuePreviewAttachment(issue *github.Issue, repoFullName string) *model.MessageAttachment {
+	state, color := "Open", colorOpen
+	if issue.GetState() == "closed" {
+		state, color = "Closed", colorMerged
+		if issue.GetStateReason() == "not_planned" {
+			state, color = "Closed as not planned", colorDraft
+		}
+	}
+
+	fields := []*model.MessageAttachmentField{
+		{Title: "State", Value: state, Short: true},
+		{Title: "Comments", Value: strconv.Itoa(issue.GetComments()), Short: true},
+	}
+	if labels := getLabelNames(issue.Labels); labels != "" {
+		fields = append(fields, &model.MessageAttachmentField{Title: "Labels", Value: labels, Short: false})
+	}
+
+	return &model.MessageAttachment{
+		Color:      color,
+		AuthorName: issue.GetUser().GetLogin(),
+		AuthorLink: issue.GetUser().GetHTMLURL(),
+		AuthorIcon: issue.GetUser().GetAvatarURL(),
+		Title:      fmt.Sprintf("%s#%d: %s", repoFullName, issue.GetNumber(), issue.GetTitle()),
+		TitleLink:  issue.GetHTMLURL(),
+		Fields:     fields,
+	}
+}
+
+func newCommitPreviewAttachment(commit *github.RepositoryCommit, repoFullName, checks string) *model.MessageAttachment {
+	message, _, _ := strings.Cut(commit.GetCommit().GetMessage(), "\n")
+	sha := commit.GetSHA()
+	if len(sha) > 7 {
+		sha = sha[:7]
+	}
+
+	author := commit.GetAuthor().GetLogin()
+	if author == "" {
+		author = commit.GetCommit().GetAuthor().GetName()
+	}
+
+	fields := []*model.MessageAttachmentField{{
+		Title: "Changes",
+		Value: fmt.Sprintf("+%d −%d in %d files", commit.GetStats().GetAdditions(), commit.GetStats().GetDeletions(), len(commit.Files)),
+		Short: true,
+	}}
+	if checks != "" {
+		fields = append(fields, &model.MessageAttachmentField{Title: "Checks", Value: checks, Short: true})
+	}
+
+	return &model.MessageAttachment{
+		Color:      colorDraft,
+		AuthorName: author,
+		AuthorLink: commit.GetAuthor().GetHTMLURL(),
+		AuthorIcon: commit.GetAuthor().GetAvatarURL(),
+		Title:      fmt.Sprintf("%s@%s: %s", repoFullName, sha, message),
+		TitleLink:  commit.GetHTMLURL(),
+		Fields:     fields,
+	}
+}
+
+func getLabelNames(labels []*github.Label) string {
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		names = append(names, "`"+label.GetName()+"`")
+	}
+
+	return strings.Join(names, " ")
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v54/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLinkPreviews(t *testing.T) {
+	p := NewPlugin()
+
+	tcs := []struct {
+		name     string
+		input    string
+		previews []linkPreview
+	}{
+		{
+			name:  "pull request, issue and commit links",
+			input: "see https://github.com/mattermost/mattermost/pull/123, https://github.com/mattermost/docs/issues/45 and https://github.com/mattermost/mattermost/commit/cbb25838a61872b624ac512556d7bc932486a64c",
+			previews: []linkPreview{
+				{word: "https://github.com/mattermost/mattermost/pull/123", owner: "mattermost", repo: "mattermost", kind: linkKindPull, id: "123"},
+				{word: "https://github.com/mattermost/docs/issues/45", owner: "mattermost", repo: "docs", kind: linkKindIssue, id: "45"},
+				{word: "https://github.com/mattermost/mattermost/commit/cbb25838a61872b624ac512556d7bc932486a64c", owner: "mattermost", repo: "mattermost", kind: linkKindCommit, id: "cbb25838a61872b624ac512556d7bc932486a64c"},
+			},
+		},
+		{
+			name:  "links to sub pages and duplicates",
+			input: "https://github.com/mattermost/mattermost/pull/123/files https://github.com/mattermost/mattermost/pull/123#issuecomment-1",
+			previews: []linkPreview{
+				{word: "https://github.com/mattermost/mattermost/pull/123", owner: "mattermost", repo: "mattermost", kind: linkKindPull, id: "123"},
+			},
+		},
+		{
+			name:  "links to a file in a diff are ignored",
+			input: "https://github.com/mattermost/mattermost/pull/123/files#diff-0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdefR12 https://github.com/mattermost/mattermost/issues/45",
+			previews: []linkPreview{
+				{word: "https://github.com/mattermost/mattermost/issues/45", owner: "mattermost", repo: "mattermost", kind: linkKindIssue, id: "45"},
+			},
+		},
+		{
+			name:  "links in code and markdown links are ignored",
+			input: "`https://github.com/mattermost/mattermost/pull/1` [the fix](https://github.com/mattermost/mattermost/pull/2) [https://github.com/mattermost/mattermost/pull/3](https://example.com)\n```\nhttps://github.com/mattermost/mattermost/pull/4\n```\nhttps://github.com/mattermost/mattermost/issues/45",
+			previews: []linkPreview{
+				{word: "https://github.com/mattermost/mattermost/issues/45", owner: "mattermost", repo: "mattermost", kind: linkKindIssue, id: "45"},
+			},
+		},
+		{
+			name:  "invalid numbers and SHAs are ignored",
+			input: "https://github.com/mattermost/mattermost/pull/abc https://github.com/mattermost/mattermost/commit/xyz1234 https://github.com/mattermost/mattermost/commit/abc",
+		},
+		{
+			name:  "other pages are ignored",
+			input: "https://github.com/mattermost/mattermost/blob/master/README.md#L1 https://github.com/mattermost/mattermost/pulls",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.previews, p.getLinkPreviews(tc.input))
+		})
+	}
+}
+
+func TestMakeLinkPreviewAttachments(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{EnableCodePreview: "public"})
+	p.repoVisibilityCache.add("mattermost/secret", true)
+
+	var mu sync.Mutex
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		requested = append(requested, req.URL.Path)
+		mu.Unlock()
+
+		switch req.URL.Path {
+		case "/api-v3/repos/mattermost/mattermost":
+			_ = json.NewEncoder(w).Encode(&github.Repository{Private: github.Bool(false)})
+		case "/api-v3/repos/mattermost/mattermost/issues/1", "/api-v3/repos/mattermost/mattermost/issues/2":
+			number, _ := strconv.Atoi(path.Base(req.URL.Path))
+			_ = json.NewEncoder(w).Encode(&github.Issue{Number: github.Int(number), Title: github.String("Issue")})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + baseURLPath + "/")
+
+	attachments := p.makeLinkPreviewAttachments(context.Background(), p.getLinkPreviews(
+		"https://github.com/mattermost/mattermost/issues/1 https://github.com/mattermost/secret/issues/3 https://github.com/mattermost/mattermost/issues/2",
+	), client)
+
+	require.Len(t, attachments, 2)
+	assert.Equal(t, "mattermost/mattermost#1: Issue", attachments[0].Title)
+	assert.Equal(t, "mattermost/mattermost#2: Issue", attachments[1].Title)
+	// The cached visibility of the private repository is used instead of fetching it.
+	assert.NotContains(t, requested, "/api-v3/repos/mattermost/secret")
+	assert.NotContains(t, requested, "/api-v3/repos/mattermost/secret/issues/3")
+}
+
+func TestGetReviewSummary(t *testing.T) {
+	review := func(login, state string) *github.PullRequestReview {
+		return &github.PullRequestReview{User: &github.User{Login: github.String(login)}, State: github.String(state)}
+	}
+
+	tcs := []struct {
+		name             string
+		reviews          []*github.PullRequestReview
+		pendingReviewers int
+		expected         string
+	}{
+		{name: "no reviews", expected: "No reviews"},
+		{name: "review requested", pendingReviewers: 1, expected: "Review required"},
+		{
+			name:     "approved by two reviewers",
+			reviews:  []*github.PullRequestReview{review("a", "APPROVED"), review("b", "COMMENTED"), review("b", "APPROVED")},
+			expected: "Approved (2)",
+		},
+		{
+			name:     "changes requested wins",
+			reviews:  []*github.PullRequestReview{review("a", "APPROVED"), review("b", "CHANGES_REQUESTED")},
+			expected: "Changes requested",
+		},
+		{
+			name:     "later approval replaces requested changes",
+			reviews:  []*github.PullRequestReview{review("a", "CHANGES_REQUESTED"), review("a", "APPROVED")},
+			expected: "Approved (1)",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, getReviewSummary(tc.reviews, tc.pendingReviewers))
+		})
+	}
+}
+
+func TestSummarizeChecks(t *testing.T) {
+	run := func(status, conclusion string) *github.CheckRun {
+		return &github.CheckRun{Status: github.String(status), Conclusion: github.String(conclusion)}
+	}
+
+	tcs := []struct {
+		name     string
+		combined *github.CombinedStatus
+		runs     *github.ListCheckRunsResults
+		expected string
+	}{
+		{name: "no checks", expected: ""},
+		{
+			name:     "passing statuses and check runs",
+			combined: &github.CombinedStatus{TotalCount: github.Int(1), State: github.String("success")},
+			runs:     &github.ListCheckRunsResults{CheckRuns: []*github.CheckRun{run("completed", "success")}},
+			expected: "Passing",
+		},
+		{
+			name:     "pending check run",
+			runs:     &github.ListCheckRunsResults{CheckRuns: []*github.CheckRun{run("completed", "success"), run("in_progress", "")}},
+			expected: "Pending",
+		},
+		{
+			name:     "failing status",
+			combined: &github.CombinedStatus{TotalCount: github.Int(2), State: github.String("failure")},
+			runs:     &github.ListCheckRunsResults{CheckRuns: []*github.CheckRun{run("in_progress", "")}},
+			expected: "Failing",
+		},
+		{
+			name:     "combined state without statuses is ignored",
+			combined: &github.CombinedStatus{TotalCount: github.Int(0), State: github.String("pending")},
+			expected: "",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, summarizeChecks(tc.combined, tc.runs))
+		})
+	}
+}
+
+func TestNewPullRequestPreviewAttachment(t *testing.T) {
+	pr := &github.PullRequest{
+		Number:       github.Int(123),
+		Title:        github.String("Add link previews"),
+		HTMLURL:      github.String("https://github.com/mattermost/mattermost/pull/123"),
+		State:        github.String("closed"),
+		Merged:       github.Bool(true),
+		Additions:    github.Int(10),
+		Deletions:    github.Int(2),
+		ChangedFiles: github.Int(3),
+		User:         &github.User{Login: github.String("panda")},
+		Labels:       []*github.Label{{Name: github.String("bug")}},
+	}
+
+	attachment := newPullRequestPreviewAttachment(pr, "mattermost/mattermost", "Approved (1)", "Passing")
+
+	assert.Equal(t, colorMerged, attachment.Color)
+	assert.Equal(t, "panda", attachment.AuthorName)
+	assert.Equal(t, "mattermost/mattermost#123: Add link previews", attachment.Title)
+	assert.Equal(t, "https://github.com/mattermost/mattermost/pull/123", attachment.TitleLink)
+	require.Len(t, attachment.Fields, 5)
+	assert.Equal(t, "Merged", attachment.Fields[0].Value)
+	assert.Equal(t, "Approved (1)", attachment.Fields[1].Value)
+	assert.Equal(t, "Passing", attachment.Fields[2].Value)
+	assert.Equal(t, "+10 −2 in 3 files", attachment.Fields[3].Value)
+	assert.Equal(t, "`bug`", attachment.Fields[4].Value)
+}
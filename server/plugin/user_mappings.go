@@ -0,0 +1,273 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+)
+
+// getOtherConnectedUser returns the info of the Mattermost user other than userID that the GitHub
+// login is connected to and mapped to, if any.
+func (p *Plugin) getOtherConnectedUser(login, userID string) *GitHubUserInfo {
+	previousUserID := p.getGitHubToUserIDMapping(login)
+	if previousUserID == "" || previousUserID == userID {
+		return nil
+	}
+
+	var previousInfo *GitHubUserInfo
+	if err := p.store.Get(previousUserID+githubTokenKey, &previousInfo); err != nil {
+		p.client.Log.Warn("Failed to load the user info of the previous connection of a GitHub account", "user_id", previousUserID, "error", err.Error())
+		return nil
+	}
+	if previousInfo == nil || !strings.EqualFold(previousInfo.GitHubUsername, login) {
+		// The mapping is orphaned and simply gets overwritten.
+		return nil
+	}
+
+	return previousInfo
+}
+
+func (p *Plugin) getTakeOverActionURL() string {
+	return fmt.Sprintf("/plugins/%s/api/v1/takeover", Manifest.Id)
+}
+
+const (
+	takeOverActionTakeOver = "takeover"
+	takeOverActionDismiss  = "dismiss"
+)
+
+// offerGitHubAccountTakeover DMs the user who connected a GitHub login already connected by
+// previousUserID buttons to take it over or to leave it to the previous user. Until then, the
+// notifications of the login keep going to the previous user.
+func (p *Plugin) offerGitHubAccountTakeover(userID, login, previousUserID string) {
+	previousUsername := previousUserID
+	if user, err := p.client.User.Get(previousUserID); err == nil {
+		previousUsername = user.Username
+	}
+
+	post := &model.Post{
+		Message: fmt.Sprintf("Your GitHub account %s is also connected by @%s, who gets its notifications. "+
+			"You won't get notifications for it until you take it over, which disconnects it from @%s.", login, previousUsername, previousUsername),
+		Type: "custom_git_takeover",
+	}
+	makeAction := func(id, name, style, action string) *model.PostAction {
+		return &model.PostAction{
+			Id:    id,
+			Name:  name,
+			Type:  model.PostActionTypeButton,
+			Style: style,
+			Integration: &model.PostActionIntegration{
+				URL: p.getTakeOverActionURL(),
+				Context: map[string]any{
+					"action":          action,
+					"user_id":         userID,
+					"github_username": login,
+				},
+			},
+		}
+	}
+	model.ParseSlackAttachment(post, []*model.MessageAttachment{{
+		Actions: []*model.PostAction{
+			makeAction("takeover", "Take over", "primary", takeOverActionTakeOver),
+			makeAction("keepowner", "Keep current owner", "default", takeOverActionDismiss),
+		},
+	}})
+
+	p.createBotDMPost(userID, post)
+}
+
+// handleTakeOverAction takes over the GitHub login for the user who confirmed it, as long as they
+// are still connected to it, or dismisses the offer.
+func (p *Plugin) handleTakeOverAction(c *Context, w http.ResponseWriter, r *http.Request) {
+	var request model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		c.Log.WithError(err).Warnf("Error decoding PostActionIntegrationRequest from JSON body")
+		p.writeAPIError(w, &APIErrorResponse{Message: "invalid request body", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	action, _ := request.Context["action"].(string)
+	userID, _ := request.Context["user_id"].(string)
+	login, _ := request.Context["github_username"].(string)
+	if userID != c.UserID {
+		p.writeAPIError(w, &APIErrorResponse{Message: "Not authorized.", StatusCode: http.StatusForbidden})
+		return
+	}
+
+	post, err := p.client.Post.GetPost(request.PostId)
+	if err != nil {
+		c.Log.WithError(err).Warnf("Failed to get the takeover post")
+		p.writeAPIError(w, &APIErrorResponse{Message: "failed to get post", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	var note string
+	if action == takeOverActionDismiss {
+		note = fmt.Sprintf("You left the GitHub account %s to its current owner. You won't get its notifications.", login)
+	} else {
+		info, apiErr := p.getGitHubUserInfo(userID)
+		if apiErr != nil || !strings.EqualFold(info.GitHubUsername, login) {
+			note = fmt.Sprintf("You aren't connected to the GitHub account %s anymore.", login)
+		} else {
+			if err = p.takeOverGitHubAccount(info.GitHubUsername, userID); err != nil {
+				c.Log.WithError(err).Warnf("Failed to take over GitHub account")
+				p.writeAPIError(w, &APIErrorResponse{Message: "failed to take over the GitHub account", StatusCode: http.StatusInternalServerError})
+				return
+			}
+			note = fmt.Sprintf("You took over the GitHub account %s.", login)
+		}
+	}
+
+	// Replace the buttons with the outcome so the takeover can't be confirmed twice.
+	post.DelProp(model.PostPropsAttachments)
+	model.ParseSlackAttachment(post, []*model.MessageAttachment{{Text: note}})
+
+	p.writeJSON(w, &model.PostActionIntegrationResponse{Update: post})
+}
+
+// takeOverGitHubAccount maps the GitHub login to userID, disconnecting the previous Mattermost
+// user it was connected to with a DM, so that notifications for the login keep going to a single
+// user. It must only run once userID's connection to the login is stored.
+func (p *Plugin) takeOverGitHubAccount(login, userID string) error {
+	auditRec := plugin.MakeAuditRecord("takeOverGitHubAccount", model.AuditStatusFail)
+	defer p.API.LogAuditRec(auditRec)
+	auditRec.Actor.UserId = userID
+
+	previousInfo := p.getOtherConnectedUser(login, userID)
+	params := TakeOverGitHubAccountAuditParams{
+		GitHubUsername: login,
+		UserID:         userID,
+	}
+	if previousInfo != nil {
+		params.PreviousUserID = previousInfo.UserID
+	}
+	model.AddEventParameterAuditableToAuditRec(auditRec, "account", params)
+
+	if previousInfo != nil {
+		username := userID
+		if user, err := p.client.User.Get(userID); err == nil {
+			username = user.Username
+		}
+
+		p.client.Log.Info("Disconnecting the previous user of a GitHub account taken over by another user",
+			"github_username", login, "user_id", userID, "previous_user_id", previousInfo.UserID)
+		p.forceDisconnectUser(userID, previousInfo.UserID, previousInfo.GitHubUsername, fmt.Sprintf(
+			"Your GitHub account %s was taken over by @%s, so it has been disconnected from your Mattermost account. "+
+				"If this wasn't expected, review the authorized OAuth apps of your GitHub account and reconnect it using `/github connect`.",
+			login, username))
+	}
+
+	if err := p.storeGitHubToUserIDMapping(login, userID); err != nil {
+		auditRec.AddErrorDesc(err.Error())
+		return err
+	}
+
+	auditRec.Success()
+	return nil
+}
+
+// userMappingIssue is a GitHub login whose mapping to a Mattermost user is inconsistent.
+type userMappingIssue struct {
+	GitHubUsername string
+	// MappedUserID is the user the login is mapped to, if any.
+	MappedUserID string
+	// ConnectedUserIDs are the users connected to the login.
+	ConnectedUserIDs []string
+}
+
+// userMappingReport lists the inconsistencies between the GitHub login mappings and the
+// connected users.
+type userMappingReport struct {
+	// Orphaned are mappings to users who aren't connected to the login.
+	Orphaned []userMappingIssue
+	// Conflicting are logins connected by several users, of which only the mapped one is notified.
+	Conflicting []userMappingIssue
+	// Unmapped are logins whose connected user isn't mapped to them, so isn't notified.
+	Unmapped []userMappingIssue
+}
+
+// checkUserMappings compares the GitHub login to user mappings with the logins of the connected
+// users. It only reports inconsistencies, without fixing them.
+func (p *Plugin) checkUserMappings() (*userMappingReport, error) {
+	tokenKeys, _, err := p.listUserTokenKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	connected := map[string][]string{}
+	logins := map[string]string{}
+	for _, key := range tokenKeys {
+		var userInfo *GitHubUserInfo
+		if err = p.store.Get(key, &userInfo); err != nil {
+			return nil, err
+		}
+		if userInfo == nil || userInfo.GitHubUsername == "" {
+			continue
+		}
+
+		login := strings.ToLower(userInfo.GitHubUsername)
+		logins[login] = userInfo.GitHubUsername
+		connected[login] = append(connected[login], strings.TrimSuffix(key, githubTokenKey))
+	}
+
+	mappingKeys, _, err := p.listKeysWithSuffix(githubUsernameKey)
+	if err != nil {
+		return nil, err
+	}
+
+	mapped := map[string]string{}
+	for _, key := range mappingKeys {
+		var userID []byte
+		if err = p.store.Get(key, &userID); err != nil {
+			return nil, err
+		}
+
+		githubUsername := strings.TrimSuffix(key, githubUsernameKey)
+		login := strings.ToLower(githubUsername)
+		mapped[login] = string(userID)
+		if _, ok := logins[login]; !ok {
+			logins[login] = githubUsername
+		}
+	}
+
+	report := &userMappingReport{}
+	for login, githubUsername := range logins {
+		mappedUserID := mapped[login]
+		connectedUserIDs := connected[login]
+		sort.Strings(connectedUserIDs)
+		issue := userMappingIssue{
+			GitHubUsername:   githubUsername,
+			MappedUserID:     mappedUserID,
+			ConnectedUserIDs: connectedUserIDs,
+		}
+
+		switch {
+		case len(connectedUserIDs) > 1:
+			report.Conflicting = append(report.Conflicting, issue)
+		case mappedUserID != "" && !slices.Contains(connectedUserIDs, mappedUserID):
+			report.Orphaned = append(report.Orphaned, issue)
+		case mappedUserID == "" && len(connectedUserIDs) == 1:
+			report.Unmapped = append(report.Unmapped, issue)
+		}
+	}
+
+	sortIssues := func(issues []userMappingIssue) {
+		sort.Slice(issues, func(i, j int) bool {
+			return strings.ToLower(issues[i].GitHubUsername) < strings.ToLower(issues[j].GitHubUsername)
+		})
+	}
+	sortIssues(report.Orphaned)
+	sortIssues(report.Conflicting)
+	sortIssues(report.Unmapped)
+
+	return report, nil
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-github/server/mocks"
+)
+
+func expectUserIDMapping(mockKvStore *mocks.MockKvStore, githubUsername, userID string) {
+	mockKvStore.EXPECT().Get(githubUsername+githubUsernameKey, gomock.Any()).DoAndReturn(func(key string, value any) error {
+		*value.(*[]byte) = []byte(userID)
+		return nil
+	})
+}
+
+func expectRawUserInfo(mockKvStore *mocks.MockKvStore, userID string, info *GitHubUserInfo) {
+	mockKvStore.EXPECT().Get(userID+githubTokenKey, gomock.Any()).DoAndReturn(func(key string, value any) error {
+		*value.(**GitHubUserInfo) = info
+		return nil
+	})
+}
+
+func TestGetOtherConnectedUser(t *testing.T) {
+	t.Run("login not connected", func(t *testing.T) {
+		mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKvStore)
+		mockKvStore.EXPECT().Get("ghuser1"+githubUsernameKey, gomock.Any()).Return(nil)
+
+		assert.Nil(t, p.getOtherConnectedUser("ghuser1", "user2"))
+	})
+
+	t.Run("login connected by the same user", func(t *testing.T) {
+		mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKvStore)
+		expectUserIDMapping(mockKvStore, "ghuser1", "user2")
+
+		assert.Nil(t, p.getOtherConnectedUser("ghuser1", "user2"))
+	})
+
+	t.Run("orphaned mapping", func(t *testing.T) {
+		mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKvStore)
+		expectUserIDMapping(mockKvStore, "ghuser1", "user1")
+		expectRawUserInfo(mockKvStore, "user1", &GitHubUserInfo{UserID: "user1", GitHubUsername: "otheruser"})
+
+		assert.Nil(t, p.getOtherConnectedUser("ghuser1", "user2"))
+	})
+
+	t.Run("login connected by another user", func(t *testing.T) {
+		mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKvStore)
+		expectUserIDMapping(mockKvStore, "ghuser1", "user1")
+		expectRawUserInfo(mockKvStore, "user1", &GitHubUserInfo{UserID: "user1", GitHubUsername: "GHUser1"})
+
+		previousInfo := p.getOtherConnectedUser("ghuser1", "user2")
+		require.NotNil(t, previousInfo)
+		assert.Equal(t, "user1", previousInfo.UserID)
+	})
+}
+
+func TestTakeOverGitHubAccount(t *testing.T) {
+	t.Run("login not connected", func(t *testing.T) {
+		mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKvStore)
+		mockKvStore.EXPECT().Get("ghuser1"+githubUsernameKey, gomock.Any()).Return(nil)
+		mockKvStore.EXPECT().Set("ghuser1"+githubUsernameKey, []byte("user2")).Return(true, nil)
+		mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+			return rec.EventName == "takeOverGitHubAccount" && rec.Status == model.AuditStatusSuccess
+		})).Return().Once()
+
+		require.NoError(t, p.takeOverGitHubAccount("ghuser1", "user2"))
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("login connected by another user", func(t *testing.T) {
+		mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKvStore)
+		expectUserIDMapping(mockKvStore, "ghuser1", "user1")
+		expectRawUserInfo(mockKvStore, "user1", &GitHubUserInfo{UserID: "user1", GitHubUsername: "GHUser1"})
+
+		mockAPI.On("GetUser", "user2").Return(&model.User{Id: "user2", Username: "bob"}, nil)
+		mockAPI.On("LogInfo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+		gomock.InOrder(
+			mockKvStore.EXPECT().Get("GHUser1"+githubUsernameKey, gomock.Any()).DoAndReturn(func(key string, value any) error {
+				*value.(*[]byte) = []byte("user1")
+				return nil
+			}),
+			mockKvStore.EXPECT().Delete("GHUser1"+githubUsernameKey).Return(nil),
+			mockKvStore.EXPECT().Set("ghuser1"+githubUsernameKey, []byte("user2")).Return(true, nil),
+		)
+		mockKvStore.EXPECT().Delete("user1" + githubTokenKey).Return(nil)
+		mockKvStore.EXPECT().Delete("user1" + githubPrivateRepoKey).Return(nil)
+		mockAPI.On("GetUser", "user1").Return(&model.User{Id: "user1", Props: model.StringMap{}}, nil)
+		mockAPI.On("PublishWebSocketEvent", wsEventDisconnect, map[string]any(nil),
+			&model.WebsocketBroadcast{UserId: "user1"}).Once()
+		mockAPI.On("GetDirectChannel", "user1", MockBotID).Return(&model.Channel{Id: "dmchannel"}, nil)
+		mockAPI.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "dmchannel" && assert.Contains(t, post.Message, "was taken over by @bob")
+		})).Return(&model.Post{}, nil).Once()
+		mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+			return rec.EventName == "forceDisconnectUser" && rec.Status == model.AuditStatusSuccess && rec.Actor.UserId == "user2"
+		})).Return().Once()
+		mockAPI.On("LogAuditRec", mock.MatchedBy(func(rec *model.AuditRecord) bool {
+			return rec.EventName == "takeOverGitHubAccount" && rec.Status == model.AuditStatusSuccess
+		})).Return().Once()
+
+		require.NoError(t, p.takeOverGitHubAccount("ghuser1", "user2"))
+		mockAPI.AssertExpectations(t)
+	})
+}
+
+func TestOfferGitHubAccountTakeover(t *testing.T) {
+	mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKvStore)
+
+	mockAPI.On("GetUser", "user1").Return(&model.User{Id: "user1", Username: "alice"}, nil)
+	mockAPI.On("GetDirectChannel", "user2", MockBotID).Return(&model.Channel{Id: "dmchannel"}, nil)
+	mockAPI.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		attachments := post.Attachments()
+		if len(attachments) != 1 || len(attachments[0].Actions) != 2 {
+			return false
+		}
+		return strings.Contains(post.Message, "You won't get notifications for it until you take it over") &&
+			attachments[0].Actions[0].Integration.Context["action"] == takeOverActionTakeOver &&
+			attachments[0].Actions[1].Integration.Context["action"] == takeOverActionDismiss
+	})).Return(&model.Post{}, nil).Once()
+
+	p.offerGitHubAccountTakeover("user2", "ghuser1", "user1")
+
+	mockAPI.AssertExpectations(t)
+}
+
+func TestHandleTakeOverAction(t *testing.T) {
+	makeBody := func(action, userID string) string {
+		b, err := json.Marshal(model.PostActionIntegrationRequest{
+			PostId: "postID",
+			Context: map[string]any{
+				"action":          action,
+				"user_id":         userID,
+				"github_username": "otherlogin",
+			},
+		})
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	t.Run("button clicked by another user", func(t *testing.T) {
+		mockKvStore, mockAPI, _, _, mockContext := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKvStore)
+
+		rec := httptest.NewRecorder()
+		p.handleTakeOverAction(mockContext, rec, httptest.NewRequest(http.MethodPost, "/takeover", strings.NewReader(makeBody(takeOverActionTakeOver, "otherUserID"))))
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("user connected to another login since", func(t *testing.T) {
+		mockKvStore, mockAPI, _, _, mockContext := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKvStore)
+		info, err := GetMockGHUserInfo(p)
+		require.NoError(t, err)
+		ExpectStoredGitHubUserInfo(mockKvStore, info)
+		mockAPI.On("GetPost", "postID").Return(&model.Post{Id: "postID"}, nil).Once()
+
+		rec := httptest.NewRecorder()
+		p.handleTakeOverAction(mockContext, rec, httptest.NewRequest(http.MethodPost, "/takeover", strings.NewReader(makeBody(takeOverActionTakeOver, MockUserID))))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp model.PostActionIntegrationResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		require.NotNil(t, resp.Update)
+		attachments := resp.Update.Attachments()
+		require.Len(t, attachments, 1)
+		assert.Empty(t, attachments[0].Actions)
+		assert.Contains(t, attachments[0].Text, "You aren't connected to the GitHub account otherlogin anymore.")
+	})
+
+	t.Run("offer dismissed", func(t *testing.T) {
+		mockKvStore, mockAPI, _, _, mockContext := GetTestSetup(t)
+		p := getPluginTest(mockAPI, mockKvStore)
+		mockAPI.On("GetPost", "postID").Return(&model.Post{Id: "postID"}, nil).Once()
+
+		rec := httptest.NewRecorder()
+		p.handleTakeOverAction(mockContext, rec, httptest.NewRequest(http.MethodPost, "/takeover", strings.NewReader(makeBody(takeOverActionDismiss, MockUserID))))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp model.PostActionIntegrationResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		require.NotNil(t, resp.Update)
+		attachments := resp.Update.Attachments()
+		require.Len(t, attachments, 1)
+		assert.Empty(t, attachments[0].Actions)
+		assert.Contains(t, attachments[0].Text, "You left the GitHub account otherlogin to its current owner.")
+	})
+}
+
+func TestCheckUserMappings(t *testing.T) {
+	mockKvStore, mockAPI, _, _, _ := GetTestSetup(t)
+	p := getPluginTest(mockAPI, mockKvStore)
+
+	gomock.InOrder(
+		mockKvStore.EXPECT().ListKeys(0, keysPerPage, gomock.Any()).Return([]string{
+			"user1" + githubTokenKey,
+			"user2" + githubTokenKey,
+			"user3" + githubTokenKey,
+			"user4" + githubTokenKey,
+			"user5" + githubTokenKey,
+		}, nil),
+		mockKvStore.EXPECT().ListKeys(0, keysPerPage, gomock.Any()).Return([]string{
+			"shared" + githubUsernameKey,
+			"ghuser3" + githubUsernameKey,
+			"gone" + githubUsernameKey,
+			"ghuser5" + githubUsernameKey,
+		}, nil),
+	)
+
+	expectRawUserInfo(mockKvStore, "user1", &GitHubUserInfo{UserID: "user1", GitHubUsername: "shared"})
+	expectRawUserInfo(mockKvStore, "user2", &GitHubUserInfo{UserID: "user2", GitHubUsername: "shared"})
+	expectRawUserInfo(mockKvStore, "user3", &GitHubUserInfo{UserID: "user3", GitHubUsername: "ghuser3"})
+	expectRawUserInfo(mockKvStore, "user4", &GitHubUserInfo{UserID: "user4", GitHubUsername: "ghuser4"})
+	expectRawUserInfo(mockKvStore, "user5", &GitHubUserInfo{UserID: "user5", GitHubUsername: "ghuser5"})
+	expectUserIDMapping(mockKvStore, "shared", "user2")
+	expectUserIDMapping(mockKvStore, "ghuser3", "user3")
+	expectUserIDMapping(mockKvStore, "gone", "user6")
+	expectUserIDMapping(mockKvStore, "ghuser5", "user1")
+
+	report, err := p.checkUserMappings()
+	require.NoError(t, err)
+
+	assert.Equal(t, []userMappingIssue{
+		{GitHubUsername: "shared", MappedUserID: "user2", ConnectedUserIDs: []string{"user1", "user2"}},
+	}, report.Conflicting)
+	assert.Equal(t, []userMappingIssue{
+		{GitHubUsername: "ghuser5", MappedUserID: "user1", ConnectedUserIDs: []string{"user5"}},
+		{GitHubUsername: "gone", MappedUserID: "user6"},
+	}, report.Orphaned)
+	assert.Equal(t, []userMappingIssue{
+		{GitHubUsername: "ghuser4", ConnectedUserIDs: []string{"user4"}},
+	}, report.Unmapped)
+}
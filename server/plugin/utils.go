@@ -5,7 +5,6 @@ package plugin
 
 import (
 	"bufio"
-	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -14,6 +13,7 @@ import (
 	"io"
 	"net/url"
 	"path"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -72,12 +72,6 @@ func buildSearchQuery(query, username string, orgs []string) string {
 	return fmt.Sprintf(query, username, orgField)
 }
 
-func pad(src []byte) []byte {
-	padding := aes.BlockSize - len(src)%aes.BlockSize
-	padtext := bytes.Repeat([]byte{byte(padding)}, padding)
-	return append(src, padtext...)
-}
-
 func unpad(src []byte) ([]byte, error) {
 	length := len(src)
 	if length == 0 {
@@ -98,26 +92,90 @@ func unpad(src []byte) ([]byte, error) {
 	return src[:(length - unpadding)], nil
 }
 
-func encrypt(key []byte, text string) (string, error) {
+// gcmCiphertextPrefix versions ciphertexts encrypted with AES-GCM. Ciphertexts without it use the
+// legacy AES-CFB format, which isn't authenticated and is only read to support existing tokens.
+const gcmCiphertextPrefix = "v2:"
+
+// isLegacyCiphertext reports whether text was encrypted with the legacy AES-CFB format. The legacy
+// format is plain base64, which never contains the colon of the version prefix.
+func isLegacyCiphertext(text string) bool {
+	return !strings.HasPrefix(text, gcmCiphertextPrefix)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", errors.Wrap(err, "could not create a cipher block, check key")
+		return nil, errors.Wrap(err, "could not create a cipher block, check key")
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create a GCM cipher")
 	}
 
-	msg := pad([]byte(text))
-	ciphertext := make([]byte, aes.BlockSize+len(msg))
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+	return gcm, nil
+}
+
+func encrypt(key []byte, text string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", errors.Wrap(err, "readFull was unsuccessful, check buffer size")
 	}
 
-	cfb := cipher.NewCFBEncrypter(block, iv) //nolint:staticcheck // SA1019: changing encryption would be a breaking change
-	cfb.XORKeyStream(ciphertext[aes.BlockSize:], msg)
-	finalMsg := base64.URLEncoding.EncodeToString(ciphertext)
-	return finalMsg, nil
+	sealed := gcm.Seal(nonce, nonce, []byte(text), nil)
+	return gcmCiphertextPrefix + base64.URLEncoding.EncodeToString(sealed), nil
 }
 
+// errLegacyCiphertext is returned for legacy AES-CFB ciphertexts once all stored tokens were
+// migrated to AES-GCM.
+var errLegacyCiphertext = errors.New("legacy AES-CFB ciphertexts are no longer accepted")
+
+// decrypt reads both the AES-GCM and the legacy AES-CFB ciphertext formats. Stored tokens are
+// read with Plugin.decryptToken, which rejects the legacy format after the migration.
 func decrypt(key []byte, text string) (string, error) {
+	if isLegacyCiphertext(text) {
+		return decryptLegacy(key, text)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	decodedMsg, err := base64.URLEncoding.DecodeString(strings.TrimPrefix(text, gcmCiphertextPrefix))
+	if err != nil {
+		return "", errors.Wrap(err, "could not decode the message")
+	}
+
+	if len(decodedMsg) < gcm.NonceSize() {
+		return "", errors.New("ciphertext is too short")
+	}
+
+	nonce, sealed := decodedMsg[:gcm.NonceSize()], decodedMsg[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "could not authenticate the message, check key")
+	}
+
+	return string(plaintext), nil
+}
+
+// decryptRefreshToken decrypts a stored refresh token. Refresh tokens were stored in plain text
+// before they were encrypted, so those are returned as is.
+func decryptRefreshToken(key []byte, text string) (string, error) {
+	if text == "" || isLegacyCiphertext(text) {
+		return text, nil
+	}
+
+	return decrypt(key, text)
+}
+
+func decryptLegacy(key []byte, text string) (string, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", errors.Wrap(err, "could not create a cipher block, check key")
@@ -128,14 +186,14 @@ func decrypt(key []byte, text string) (string, error) {
 		return "", errors.Wrap(err, "could not decode the message")
 	}
 
-	if (len(decodedMsg) % aes.BlockSize) != 0 {
+	if len(decodedMsg) == 0 || (len(decodedMsg)%aes.BlockSize) != 0 {
 		return "", errors.New("blocksize must be multiple of decoded message length")
 	}
 
 	iv := decodedMsg[:aes.BlockSize]
 	msg := decodedMsg[aes.BlockSize:]
 
-	cfb := cipher.NewCFBDecrypter(block, iv) //nolint:staticcheck // SA1019: changing encryption would be a breaking change
+	cfb := cipher.NewCFBDecrypter(block, iv) //nolint:staticcheck // SA1019: only used to read tokens stored before AES-GCM
 	cfb.XORKeyStream(msg, msg)
 
 	unpadMsg, err := unpad(msg)
@@ -212,14 +270,16 @@ func parseFlag(flag string) string {
 }
 
 // filterLines filters lines in a string from start to end.
-func filterLines(s string, start, end int) (string, error) {
+func filterLines(s string, start, end, maxLineLength int) (string, error) {
 	scanner := bufio.NewScanner(strings.NewReader(s))
+	// Allow lines as long as the whole file, since minified files are often a single line.
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), len(s)+1)
 	var buf strings.Builder
 	for i := 1; scanner.Scan() && i <= end; i++ {
 		if i < start {
 			continue
 		}
-		buf.Write(scanner.Bytes())
+		buf.WriteString(collapseLine(scanner.Text(), maxLineLength))
 		buf.WriteByte(byte('\n'))
 	}
 
@@ -229,9 +289,20 @@ func filterLines(s string, start, end int) (string, error) {
 	return buf.String(), nil
 }
 
+// collapseLine cuts line off after maxLength characters.
+func collapseLine(line string, maxLength int) string {
+	if utf8.RuneCountInString(line) <= maxLength {
+		return line
+	}
+
+	runes := []rune(line)
+	return string(runes[:maxLength]) + fmt.Sprintf(" … (%d more characters)", len(runes)-maxLength)
+}
+
 // getLineNumbers return the start and end lines from an anchor tag
 // of a github permalink.
-func getLineNumbers(s string) (start, end int) {
+// contextLines is the number of lines shown before and after a single linked line.
+func getLineNumbers(s string, contextLines int) (start, end int) {
 	// split till -
 	parts := strings.Split(s, "-")
 
@@ -246,10 +317,10 @@ func getLineNumbers(s string) (start, end int) {
 		if l == -1 {
 			return -1, -1
 		}
-		if l < permalinkLineContext {
-			return 0, l + permalinkLineContext
+		if l < contextLines {
+			return 0, l + contextLines
 		}
-		return l - permalinkLineContext, l + permalinkLineContext
+		return l - contextLines, l + contextLines
 	case 2:
 		// a line range
 		start := getLine(parts[0])
@@ -313,18 +384,84 @@ func isInsideLink(msg string, index int) bool {
 	return false
 }
 
-// getCodeMarkdown returns the constructed markdown for a permalink.
-func getCodeMarkdown(user, repo, repoPath, word, lines string, isTruncated bool) string {
+// markdownLinkRegex matches markdown links, [text](url), including their text.
+var markdownLinkRegex = regexp.MustCompile(`\[[^\[\]]*\]\([^()\s]*\)`)
+
+// getMarkdownQuotedRanges returns the [start, end) byte ranges of msg in fenced code blocks, code
+// spans and markdown links, which are shown as the user wrote them. Like isInsideLink, it is a
+// rough approximation of markdown that errs on the side of reporting quoted text.
+func getMarkdownQuotedRanges(msg string) [][2]int {
+	var ranges [][2]int
+
+	offset, fenceStart := 0, -1
+	var fence string
+	for _, line := range strings.SplitAfter(msg, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		switch {
+		case fenceStart < 0 && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+			fenceStart, fence = offset, trimmed[:3]
+		case fenceStart >= 0 && strings.HasPrefix(trimmed, fence):
+			ranges = append(ranges, [2]int{fenceStart, offset + len(line)})
+			fenceStart = -1
+		}
+		offset += len(line)
+	}
+	if fenceStart >= 0 {
+		// An unclosed code block runs to the end of the message.
+		ranges = append(ranges, [2]int{fenceStart, len(msg)})
+	}
+
+	codeBlocks := len(ranges)
+	for i := 0; i < len(msg); {
+		if end, ok := getEnclosingRangeEnd(ranges[:codeBlocks], i); ok {
+			i = end
+			continue
+		}
+		if msg[i] != '`' {
+			i++
+			continue
+		}
+
+		run := len(msg[i:]) - len(strings.TrimLeft(msg[i:], "`"))
+		closing := strings.Index(msg[i+run:], strings.Repeat("`", run))
+		if closing < 0 {
+			i += run
+			continue
+		}
+		end := i + run + closing + run
+		ranges = append(ranges, [2]int{i, end})
+		i = end
+	}
+
+	for _, loc := range markdownLinkRegex.FindAllStringIndex(msg, -1) {
+		ranges = append(ranges, [2]int{loc[0], loc[1]})
+	}
+
+	return ranges
+}
+
+// getEnclosingRangeEnd returns the end of the range containing index, if any.
+func getEnclosingRangeEnd(ranges [][2]int, index int) (int, bool) {
+	for _, r := range ranges {
+		if index >= r[0] && index < r[1] {
+			return r[1], true
+		}
+	}
+	return 0, false
+}
+
+// getCodeMarkdown returns the constructed markdown for a permalink. When pinnedURL is set, the
+// permalink pointed to a branch or tag and the preview is labeled with the commit it was resolved to.
+func getCodeMarkdown(user, repo, repoPath, word, lines, language string, isTruncated bool, commit, pinnedURL string) string {
 	user = strings.ReplaceAll(user, "_", "\\_")
 	repo = strings.ReplaceAll(repo, "_", "\\_")
 	repoPath = strings.ReplaceAll(repoPath, "_", "\\_")
-	final := fmt.Sprintf("\n[%s/%s/%s](%s)\n", user, repo, repoPath, word)
-	ext := path.Ext(repoPath)
-	// remove the preceding dot
-	if len(ext) > 1 {
-		ext = strings.TrimPrefix(ext, ".")
+	final := fmt.Sprintf("\n[%s/%s/%s](%s)", user, repo, repoPath, word)
+	if pinnedURL != "" {
+		final += fmt.Sprintf(" at [`%.7s`](%s)", commit, pinnedURL)
 	}
-	final += "```" + ext + "\n"
+	final += "\n"
+	final += "```" + language + "\n"
 	final += lines
 	if isTruncated { // add an ellipsis if lines were cut off
 		final += "...\n"
@@ -333,6 +470,99 @@ func getCodeMarkdown(user, repo, repoPath, word, lines string, isTruncated bool)
 	return final
 }
 
+// codeLanguagesByExtension maps file extensions to the language names used for
+// syntax highlighting of Markdown code blocks.
+var codeLanguagesByExtension = map[string]string{
+	".bash":   "bash",
+	".c":      "c",
+	".cc":     "cpp",
+	".clj":    "clojure",
+	".cpp":    "cpp",
+	".cs":     "csharp",
+	".css":    "css",
+	".cxx":    "cpp",
+	".dart":   "dart",
+	".diff":   "diff",
+	".erl":    "erlang",
+	".ex":     "elixir",
+	".exs":    "elixir",
+	".go":     "go",
+	".gradle": "groovy",
+	".groovy": "groovy",
+	".h":      "c",
+	".hpp":    "cpp",
+	".hs":     "haskell",
+	".htm":    "html",
+	".html":   "html",
+	".ini":    "ini",
+	".java":   "java",
+	".js":     "javascript",
+	".json":   "json",
+	".jsx":    "javascript",
+	".kt":     "kotlin",
+	".kts":    "kotlin",
+	".less":   "less",
+	".lua":    "lua",
+	".m":      "objectivec",
+	".md":     "markdown",
+	".mjs":    "javascript",
+	".mm":     "objectivec",
+	".patch":  "diff",
+	".php":    "php",
+	".pl":     "perl",
+	".proto":  "protobuf",
+	".ps1":    "powershell",
+	".py":     "python",
+	".r":      "r",
+	".rb":     "ruby",
+	".rs":     "rust",
+	".scala":  "scala",
+	".scss":   "scss",
+	".sh":     "bash",
+	".sql":    "sql",
+	".swift":  "swift",
+	".tf":     "hcl",
+	".toml":   "toml",
+	".ts":     "typescript",
+	".tsx":    "typescript",
+	".vue":    "html",
+	".xml":    "xml",
+	".yaml":   "yaml",
+	".yml":    "yaml",
+	".zsh":    "bash",
+}
+
+// codeLanguagesByFileName maps well known file names without a telling extension
+// to their language.
+var codeLanguagesByFileName = map[string]string{
+	"dockerfile":     "dockerfile",
+	"makefile":       "makefile",
+	"gemfile":        "ruby",
+	"rakefile":       "ruby",
+	"jenkinsfile":    "groovy",
+	"go.mod":         "go",
+	"cmakelists.txt": "cmake",
+}
+
+// getCodeLanguage returns the language of the file at filePath for syntax highlighting.
+// Unknown extensions are passed through so the client can still try to highlight them.
+func getCodeLanguage(filePath string) string {
+	name := strings.ToLower(path.Base(filePath))
+	if lang, ok := codeLanguagesByFileName[name]; ok {
+		return lang
+	}
+	if strings.HasPrefix(name, "dockerfile.") {
+		return "dockerfile"
+	}
+
+	ext := path.Ext(name)
+	if lang, ok := codeLanguagesByExtension[ext]; ok {
+		return lang
+	}
+
+	return strings.TrimPrefix(ext, ".")
+}
+
 // getToDoDisplayText returns the text to be displayed in todo listings.
 func getToDoDisplayText(baseURL, title, url, notifType string, repository *github.Repository) string {
 	var owner, repo, repoURL, titlePart string
@@ -4,13 +4,21 @@
 package plugin
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-github/v54/github"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost/server/public/model"
 )
@@ -126,41 +134,54 @@ func TestParseFlag(t *testing.T) {
 
 func TestGetLineNumbers(t *testing.T) {
 	tcs := []struct {
-		input      string
-		start, end int
+		input        string
+		contextLines int
+		start, end   int
 	}{
 		{
-			input: "L19",
-			start: 16,
-			end:   22,
+			input:        "L19",
+			contextLines: 3,
+			start:        16,
+			end:          22,
 		}, {
-			input: "L19-L23",
-			start: 19,
-			end:   23,
+			input:        "L19-L23",
+			contextLines: 3,
+			start:        19,
+			end:          23,
 		}, {
-			input: "L23-L19",
-			start: -1,
-			end:   -1,
+			input:        "L23-L19",
+			contextLines: 3,
+			start:        -1,
+			end:          -1,
 		}, {
-			input: "L",
-			start: -1,
-			end:   -1,
+			input:        "L",
+			contextLines: 3,
+			start:        -1,
+			end:          -1,
 		}, {
-			input: "bad",
-			start: -1,
-			end:   -1,
+			input:        "bad",
+			contextLines: 3,
+			start:        -1,
+			end:          -1,
 		}, {
-			input: "L99-",
-			start: 99,
-			end:   -1,
+			input:        "L99-",
+			contextLines: 3,
+			start:        99,
+			end:          -1,
 		}, {
-			input: "L2",
-			start: 0,
-			end:   5,
+			input:        "L2",
+			contextLines: 3,
+			start:        0,
+			end:          5,
+		}, {
+			input:        "L19",
+			contextLines: 0,
+			start:        19,
+			end:          19,
 		},
 	}
 	for _, tc := range tcs {
-		start, end := getLineNumbers(tc.input)
+		start, end := getLineNumbers(tc.input, tc.contextLines)
 		assert.Equalf(t, tc.start, start, "unexpected start index for getLineNumbers(%q)", tc.input)
 		assert.Equalf(t, tc.end, end, "unexpected end index for getLineNumbers(%q)", tc.input)
 	}
@@ -430,3 +451,105 @@ func TestValidateOAuthScopes(t *testing.T) {
 		})
 	}
 }
+
+func TestGetCodeLanguage(t *testing.T) {
+	tcs := []struct {
+		path     string
+		expected string
+	}{
+		{path: "server/plugin/plugin.go", expected: "go"},
+		{path: "webapp/src/index.tsx", expected: "typescript"},
+		{path: "config.YML", expected: "yaml"},
+		{path: "build/Dockerfile", expected: "dockerfile"},
+		{path: "Dockerfile.build", expected: "dockerfile"},
+		{path: "Makefile", expected: "makefile"},
+		{path: "LICENSE", expected: ""},
+		{path: "data.unknownext", expected: "unknownext"},
+		{path: "main.zig", expected: "zig"},
+	}
+	for _, tc := range tcs {
+		assert.Equalf(t, tc.expected, getCodeLanguage(tc.path), "unexpected language for %q", tc.path)
+	}
+}
+
+func TestFilterLines(t *testing.T) {
+	long := strings.Repeat("a", 100000)
+	content := "one\ntwo\n" + long + "\nfour\n"
+
+	lines, err := filterLines(content, 2, 4, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "two\naaaaaaaaaa … (99990 more characters)\nfour\n", lines)
+}
+
+// encryptLegacy encrypts text in the AES-CFB format used before AES-GCM.
+func encryptLegacy(t *testing.T, key []byte, text string) string {
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+
+	padding := aes.BlockSize - len(text)%aes.BlockSize
+	msg := append([]byte(text), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	ciphertext := make([]byte, aes.BlockSize+len(msg))
+	iv := ciphertext[:aes.BlockSize]
+	_, err = io.ReadFull(rand.Reader, iv)
+	require.NoError(t, err)
+
+	cfb := cipher.NewCFBEncrypter(block, iv) //nolint:staticcheck // SA1019: the legacy format is still read
+	cfb.XORKeyStream(ciphertext[aes.BlockSize:], msg)
+	return base64.URLEncoding.EncodeToString(ciphertext)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := []byte("dummyEncryptKey1")
+
+	encrypted, err := encrypt(key, MockAccessToken)
+	require.NoError(t, err)
+	assert.False(t, isLegacyCiphertext(encrypted))
+
+	decrypted, err := decrypt(key, encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, MockAccessToken, decrypted)
+
+	t.Run("wrong key", func(t *testing.T) {
+		_, err := decrypt([]byte("dummyEncryptKey2"), encrypted)
+		assert.Error(t, err)
+	})
+
+	t.Run("tampered ciphertext", func(t *testing.T) {
+		sealed, err := base64.URLEncoding.DecodeString(strings.TrimPrefix(encrypted, gcmCiphertextPrefix))
+		require.NoError(t, err)
+		sealed[len(sealed)-1] ^= 1
+
+		_, err = decrypt(key, gcmCiphertextPrefix+base64.URLEncoding.EncodeToString(sealed))
+		assert.Error(t, err)
+	})
+
+	t.Run("truncated ciphertext", func(t *testing.T) {
+		_, err := decrypt(key, gcmCiphertextPrefix+"AAAA")
+		assert.Error(t, err)
+	})
+
+	t.Run("legacy ciphertext", func(t *testing.T) {
+		legacy := encryptLegacy(t, key, MockAccessToken)
+		assert.True(t, isLegacyCiphertext(legacy))
+
+		decrypted, err := decrypt(key, legacy)
+		require.NoError(t, err)
+		assert.Equal(t, MockAccessToken, decrypted)
+	})
+}
+
+func TestGetMarkdownQuotedRanges(t *testing.T) {
+	quoted := func(msg string) []string {
+		var parts []string
+		for _, r := range getMarkdownQuotedRanges(msg) {
+			parts = append(parts, msg[r[0]:r[1]])
+		}
+		return parts
+	}
+
+	assert.Equal(t, []string{"`a`", "``b ` c``"}, quoted("x `a` y ``b ` c`` z"))
+	assert.Equal(t, []string{"```go\n`x`\n```\n"}, quoted("```go\n`x`\n```\nafter"))
+	assert.Equal(t, []string{"~~~\nunclosed"}, quoted("before\n~~~\nunclosed"))
+	assert.Equal(t, []string{"[text](https://example.com)"}, quoted("see [text](https://example.com) and `"))
+	assert.Empty(t, quoted("plain text"))
+}
@@ -32,6 +32,7 @@ const (
 	actionReopened             = "reopened"
 	actionSubmitted            = "submitted"
 	actionLabeled              = "labeled"
+	actionUnlabeled            = "unlabeled"
 	actionAssigned             = "assigned"
 
 	actionCreated   = "created"
@@ -47,6 +48,8 @@ const (
 	postPropGithubRepo       = "gh_repo"
 	postPropGithubObjectID   = "gh_object_id"
 	postPropGithubObjectType = "gh_object_type"
+	// postPropGithubIssueNumber is the number of the issue or pull request a comment notification belongs to.
+	postPropGithubIssueNumber = "gh_issue_number"
 
 	githubObjectTypeIssue             = "issue"
 	githubObjectTypeIssueComment      = "issue_comment"
@@ -268,6 +271,7 @@ func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		handler = func() {
 			p.postIssueEvent(event)
 			p.handleIssueNotification(event)
+			p.handleIssueDescriptionMentionNotification(event)
 		}
 	case *github.IssueCommentEvent:
 		repo = event.GetRepo()
@@ -391,6 +395,11 @@ func (p *Plugin) excludeConfigOrgMember(user *github.User, subscription *Subscri
 		return false
 	}
 
+	organization := p.getConfiguration().GitHubOrg
+	if isMember, ok := p.isInstallationOrganizationMember(user, organization); ok {
+		return isMember
+	}
+
 	info, err := p.getGitHubUserInfo(subscription.CreatorID)
 	if err != nil {
 		p.client.Log.Warn("Failed to exclude org member", "error", err.Message)
@@ -398,7 +407,6 @@ func (p *Plugin) excludeConfigOrgMember(user *github.User, subscription *Subscri
 	}
 
 	githubClient := p.githubConnectUser(context.Background(), info)
-	organization := p.getConfiguration().GitHubOrg
 
 	return p.isUserOrganizationMember(githubClient, user, info, organization)
 }
@@ -408,6 +416,10 @@ func (p *Plugin) shouldDenyEventDueToNotOrgMember(user *github.User, subscriptio
 		return false
 	}
 
+	if isMember, ok := p.isInstallationOrganizationMember(user, p.getConfiguration().GitHubOrg); ok {
+		return !isMember
+	}
+
 	githubClient, err := p.GetGitHubClient(context.Background(), subscription.CreatorID)
 	if err != nil {
 		p.client.Log.Warn("Failed to get user info", "error", err.Error())
@@ -437,6 +449,7 @@ func (p *Plugin) postPullRequestEvent(event *github.PullRequestEvent) {
 		actionReopened,
 		actionMarkedReadyForReview,
 		actionLabeled,
+		actionUnlabeled,
 		actionClosed:
 	default:
 		return
@@ -505,8 +518,13 @@ func (p *Plugin) postPullRequestEvent(event *github.PullRequestEvent) {
 		post.AddProp(postPropGithubObjectID, prNumber)
 		post.AddProp(postPropGithubObjectType, githubObjectTypeIssue)
 
-		if action == actionLabeled {
-			if label != "" && label == eventLabel {
+		isLive := sub.RenderStyle() == renderStyleLive
+		// Label changes of live subscriptions only refresh the labels of the live post.
+		refreshLiveOnly := false
+
+		if action == actionLabeled || action == actionUnlabeled {
+			switch {
+			case action == actionLabeled && label != "" && label == eventLabel:
 				pullRequestLabelledMessage, err := renderTemplate("pullRequestLabelled", event)
 				if err != nil {
 					p.client.Log.Warn("Failed to render template", "error", err.Error())
@@ -514,7 +532,9 @@ func (p *Plugin) postPullRequestEvent(event *github.PullRequestEvent) {
 				}
 
 				post.Message = pullRequestLabelledMessage
-			} else {
+			case isLive:
+				refreshLiveOnly = true
+			default:
 				continue
 			}
 		}
@@ -561,10 +581,30 @@ func (p *Plugin) postPullRequestEvent(event *github.PullRequestEvent) {
 			post.Message = closedPRMessage
 		}
 
+		if isLive {
+			// The live post itself announces the opened pull request.
+			reply := post
+			if action == actionOpened || refreshLiveOnly {
+				reply = nil
+			}
+			if err := p.postLivePREvent(sub.ChannelID, repo, pr, nil, reply); err != nil {
+				p.client.Log.Warn("Error posting live pull request event", "channel_id", sub.ChannelID, "error", err.Error())
+			}
+			continue
+		}
+
+		if p.getConfiguration().EnablePullRequestActions && (action == actionOpened || action == actionReopened || action == actionMarkedReadyForReview) {
+			model.ParseSlackAttachment(post, []*model.MessageAttachment{
+				p.makePRActionsAttachment(repo.GetOwner().GetLogin(), repo.GetName(), pr.GetNumber(), isPRInDraftState),
+			})
+		}
+
 		post.ChannelId = sub.ChannelID
 		if err := p.client.Post.CreatePost(post); err != nil {
 			p.client.Log.Warn("Error webhook post", "channel_id", post.ChannelId, "error", err.Error())
+			continue
 		}
+		p.trackReactionSyncPost(repoName, githubObjectTypeIssue, int64(pr.GetNumber()), post.Id)
 	}
 }
 
@@ -577,15 +617,42 @@ func (p *Plugin) sanitizeDescription(description string) string {
 	return strings.TrimSpace(description)
 }
 
-func (p *Plugin) handlePRDescriptionMentionNotification(event *github.PullRequestEvent) {
-	action := event.GetAction()
-	if action != actionOpened {
-		return
+// getMentionedGitHubUsernamesForAction returns the users to notify about mentions in body. For
+// edits, only users mentioned in the new body but not in the previous one are returned, so people
+// already mentioned aren't notified again.
+func (p *Plugin) getMentionedGitHubUsernamesForAction(action, body string, changes *github.EditChange, repo *github.Repository, sender string) []string {
+	switch action {
+	case actionOpened, actionCreated:
+		return p.getMentionedGitHubUsernames(body, repo, sender)
+	case actionEdited:
+		if changes == nil || changes.Body == nil || changes.Body.From == nil {
+			return nil
+		}
+
+		previouslyMentioned := map[string]bool{}
+		for _, username := range p.getMentionedGitHubUsernames(changes.Body.GetFrom(), repo, sender) {
+			previouslyMentioned[strings.ToLower(username)] = true
+		}
+
+		var usernames []string
+		for _, username := range p.getMentionedGitHubUsernames(body, repo, sender) {
+			if !previouslyMentioned[strings.ToLower(username)] {
+				usernames = append(usernames, username)
+			}
+		}
+		return usernames
+	default:
+		return nil
 	}
+}
 
+func (p *Plugin) handlePRDescriptionMentionNotification(event *github.PullRequestEvent) {
 	body := event.GetPullRequest().GetBody()
 
-	mentionedUsernames := parseGitHubUsernamesFromText(body)
+	mentionedUsernames := p.getMentionedGitHubUsernamesForAction(event.GetAction(), body, event.GetChanges(), event.GetRepo(), event.GetSender().GetLogin())
+	if len(mentionedUsernames) == 0 {
+		return
+	}
 
 	message, err := renderTemplate("pullRequestMentionNotification", event)
 	if err != nil {
@@ -613,7 +680,61 @@ func (p *Plugin) handlePRDescriptionMentionNotification(event *github.PullReques
 			continue
 		}
 
-		if p.senderMutedByReceiver(userID, event.GetSender().GetLogin()) {
+		if p.notificationMutedByReceiver(userID, event.GetSender().GetLogin(), event.GetRepo().GetFullName(), event.GetPullRequest().GetTitle()) {
+			continue
+		}
+
+		channel, err := p.client.Channel.GetDirect(userID, p.BotUserID)
+		if err != nil {
+			continue
+		}
+
+		post := p.makeBotPost(message, "custom_git_mention")
+		post.ChannelId = channel.Id
+
+		if err = p.client.Post.CreatePost(post); err != nil {
+			p.client.Log.Warn("Error webhook post", "channel_id", post.ChannelId, "error", err.Error())
+		}
+
+		p.sendRefreshEvent(userID)
+	}
+}
+
+func (p *Plugin) handleIssueDescriptionMentionNotification(event *github.IssuesEvent) {
+	body := event.GetIssue().GetBody()
+
+	mentionedUsernames := p.getMentionedGitHubUsernamesForAction(event.GetAction(), body, event.GetChanges(), event.GetRepo(), event.GetSender().GetLogin())
+	if len(mentionedUsernames) == 0 {
+		return
+	}
+
+	message, err := renderTemplate("issueMentionNotification", event)
+	if err != nil {
+		p.client.Log.Warn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	for _, username := range mentionedUsernames {
+		// Don't notify user of their own edit
+		if username == event.GetSender().GetLogin() {
+			continue
+		}
+
+		// Notifications for issue authors are handled separately
+		if username == event.GetIssue().GetUser().GetLogin() {
+			continue
+		}
+
+		userID := p.getGitHubToUserIDMapping(username)
+		if userID == "" {
+			continue
+		}
+
+		if event.GetRepo().GetPrivate() && !p.permissionToRepo(userID, event.GetRepo().GetFullName()) {
+			continue
+		}
+
+		if p.notificationMutedByReceiver(userID, event.GetSender().GetLogin(), event.GetRepo().GetFullName(), event.GetIssue().GetTitle()) {
 			continue
 		}
 
@@ -725,10 +846,18 @@ func (p *Plugin) postIssueEvent(event *github.IssuesEvent) {
 			}
 		}
 
+		if p.getConfiguration().EnableIssueActions {
+			model.ParseSlackAttachment(post, []*model.MessageAttachment{
+				p.makeIssueActionsAttachment(repo.GetOwner().GetLogin(), repo.GetName(), issue.GetNumber(), action == actionClosed),
+			})
+		}
+
 		post.ChannelId = sub.ChannelID
 		if err = p.client.Post.CreatePost(post); err != nil {
 			p.client.Log.Warn("Error webhook post", "channel_id", post.ChannelId, "error", err.Error())
+			continue
 		}
+		p.trackReactionSyncPost(repoName, githubObjectTypeIssue, int64(issue.GetNumber()), post.Id)
 	}
 }
 
@@ -882,11 +1011,18 @@ func (p *Plugin) postIssueCommentEvent(event *github.IssueCommentEvent) {
 		labels[i] = v.GetName()
 	}
 
+	syncedFromChannelID := getSyncedCommentChannelID(event.GetComment().GetBody())
+
 	for _, sub := range subs {
 		if !sub.IssueComments() {
 			continue
 		}
 
+		// The comment was replied from a thread in this channel, so it is already there.
+		if sub.ChannelID == syncedFromChannelID {
+			continue
+		}
+
 		if p.excludeConfigOrgMember(event.GetSender(), sub) {
 			continue
 		}
@@ -916,16 +1052,24 @@ func (p *Plugin) postIssueCommentEvent(event *github.IssueCommentEvent) {
 		post.AddProp(postPropGithubRepo, repoName)
 		post.AddProp(postPropGithubObjectID, commentID)
 		post.AddProp(postPropGithubObjectType, githubObjectTypeIssueComment)
+		post.AddProp(postPropGithubIssueNumber, event.GetIssue().GetNumber())
 
 		if event.GetAction() == actionCreated {
 			post.Message = message
 		}
 
+		// The event doesn't include the pull request, so the live post is left as is.
+		if sub.RenderStyle() == renderStyleLive && event.GetIssue().IsPullRequest() {
+			post.RootId = p.getLivePRPostID(sub.ChannelID, repoName, event.GetIssue().GetNumber())
+		}
+
 		post.ChannelId = sub.ChannelID
 
 		if err = p.client.Post.CreatePost(post); err != nil {
 			p.client.Log.Warn("Error webhook post", "channel_id", post.ChannelId, "error", err.Error())
+			continue
 		}
+		p.trackReactionSyncPost(repoName, githubObjectTypeIssueComment, commentID, post.Id)
 	}
 }
 
@@ -1011,6 +1155,13 @@ func (p *Plugin) postPullRequestReviewEvent(event *github.PullRequestReviewEvent
 
 		post := p.makeBotPost(newReviewMessage, "custom_git_pull_review")
 
+		if sub.RenderStyle() == renderStyleLive {
+			if err = p.postLivePREvent(sub.ChannelID, repo, event.GetPullRequest(), event.GetReview(), post); err != nil {
+				p.client.Log.Warn("Error posting live pull request event", "channel_id", sub.ChannelID, "error", err.Error())
+			}
+			continue
+		}
+
 		post.ChannelId = sub.ChannelID
 		if err = p.client.Post.CreatePost(post); err != nil {
 			p.client.Log.Warn("Error webhook post", "channel_id", post.ChannelId, "error", err.Error())
@@ -1041,11 +1192,18 @@ func (p *Plugin) postPullRequestReviewCommentEvent(event *github.PullRequestRevi
 		labels[i] = v.GetName()
 	}
 
+	syncedFromChannelID := getSyncedCommentChannelID(event.GetComment().GetBody())
+
 	for _, sub := range subs {
 		if !sub.PullReviews() {
 			continue
 		}
 
+		// The comment was replied from a thread in this channel, so it is already there.
+		if sub.ChannelID == syncedFromChannelID {
+			continue
+		}
+
 		if p.excludeConfigOrgMember(event.GetSender(), sub) {
 			continue
 		}
@@ -1075,16 +1233,27 @@ func (p *Plugin) postPullRequestReviewCommentEvent(event *github.PullRequestRevi
 		post.AddProp(postPropGithubRepo, repoName)
 		post.AddProp(postPropGithubObjectID, commentID)
 		post.AddProp(postPropGithubObjectType, githubObjectTypePRReviewComment)
+		post.AddProp(postPropGithubIssueNumber, event.GetPullRequest().GetNumber())
 
-		post.ChannelId = sub.ChannelID
-		if err = p.client.Post.CreatePost(post); err != nil {
-			p.client.Log.Warn("Error webhook post", "channel_id", post.ChannelId, "error", err.Error())
+		if sub.RenderStyle() == renderStyleLive {
+			if err = p.postLivePREvent(sub.ChannelID, repo, event.GetPullRequest(), nil, post); err != nil {
+				p.client.Log.Warn("Error posting live pull request event", "channel_id", sub.ChannelID, "error", err.Error())
+				continue
+			}
+		} else {
+			post.ChannelId = sub.ChannelID
+			if err = p.client.Post.CreatePost(post); err != nil {
+				p.client.Log.Warn("Error webhook post", "channel_id", post.ChannelId, "error", err.Error())
+				continue
+			}
 		}
+		p.trackReactionSyncPost(repoName, githubObjectTypePRReviewComment, commentID, post.Id)
 	}
 }
 
 func (p *Plugin) handleReviewCommentMentionNotification(event *github.PullRequestReviewCommentEvent) {
-	if event.GetAction() != actionCreated {
+	action := event.GetAction()
+	if action != actionCreated && action != actionEdited {
 		return
 	}
 
@@ -1096,7 +1265,10 @@ func (p *Plugin) handleReviewCommentMentionNotification(event *github.PullReques
 
 	body = mdCommentRegex.ReplaceAllString(body, "")
 
-	mentionedUsernames := parseGitHubUsernamesFromText(body)
+	mentionedUsernames := p.getMentionedGitHubUsernamesForAction(action, body, event.GetChanges(), event.GetRepo(), event.GetSender().GetLogin())
+	if len(mentionedUsernames) == 0 {
+		return
+	}
 
 	message, err := renderTemplate("reviewCommentMentionNotification", event)
 	if err != nil {
@@ -1122,7 +1294,7 @@ func (p *Plugin) handleReviewCommentMentionNotification(event *github.PullReques
 			continue
 		}
 
-		if p.senderMutedByReceiver(userID, event.GetSender().GetLogin()) {
+		if p.notificationMutedByReceiver(userID, event.GetSender().GetLogin(), event.GetRepo().GetFullName(), event.GetPullRequest().GetTitle()) {
 			continue
 		}
 
@@ -1160,7 +1332,7 @@ func (p *Plugin) handleReviewCommentAuthorNotification(event *github.PullRequest
 		return
 	}
 
-	if p.senderMutedByReceiver(authorUserID, event.GetSender().GetLogin()) {
+	if p.notificationMutedByReceiver(authorUserID, event.GetSender().GetLogin(), event.GetRepo().GetFullName(), event.GetPullRequest().GetTitle()) {
 		return
 	}
 
@@ -1176,7 +1348,7 @@ func (p *Plugin) handleReviewCommentAuthorNotification(event *github.PullRequest
 
 func (p *Plugin) handleCommentMentionNotification(event *github.IssueCommentEvent) {
 	action := event.GetAction()
-	if action == actionEdited || action == actionDeleted {
+	if action == actionDeleted {
 		return
 	}
 
@@ -1187,7 +1359,10 @@ func (p *Plugin) handleCommentMentionNotification(event *github.IssueCommentEven
 		body = strings.Split(body, "\n\nOn")[0]
 	}
 
-	mentionedUsernames := parseGitHubUsernamesFromText(body)
+	mentionedUsernames := p.getMentionedGitHubUsernamesForAction(action, body, event.GetChanges(), event.GetRepo(), event.GetSender().GetLogin())
+	if len(mentionedUsernames) == 0 {
+		return
+	}
 
 	message, err := renderTemplate("commentMentionNotification", event)
 	if err != nil {
@@ -1230,7 +1405,7 @@ func (p *Plugin) handleCommentMentionNotification(event *github.IssueCommentEven
 			continue
 		}
 
-		if p.senderMutedByReceiver(userID, event.GetSender().GetLogin()) {
+		if p.notificationMutedByReceiver(userID, event.GetSender().GetLogin(), event.GetRepo().GetFullName(), event.GetIssue().GetTitle()) {
 			continue
 		}
 
@@ -1286,7 +1461,7 @@ func (p *Plugin) handleCommentAuthorNotification(event *github.IssueCommentEvent
 		return
 	}
 
-	if p.senderMutedByReceiver(authorUserID, event.GetSender().GetLogin()) {
+	if p.notificationMutedByReceiver(authorUserID, event.GetSender().GetLogin(), event.GetRepo().GetFullName(), event.GetIssue().GetTitle()) {
 		p.client.Log.Debug("Commenter is muted, skipping notification")
 		return
 	}
@@ -1364,7 +1539,7 @@ func (p *Plugin) handleCommentAssigneeNotification(event *github.IssueCommentEve
 			continue
 		}
 
-		if p.senderMutedByReceiver(assigneeID, event.GetSender().GetLogin()) {
+		if p.notificationMutedByReceiver(assigneeID, event.GetSender().GetLogin(), repoName, event.GetIssue().GetTitle()) {
 			p.client.Log.Debug("Commenter is muted, skipping notification")
 			continue
 		}
@@ -1440,12 +1615,13 @@ func (p *Plugin) handlePullRequestNotification(event *github.PullRequestEvent) {
 		return
 	}
 
-	if len(requestedUserID) > 0 && !p.senderMutedByReceiver(requestedUserID, sender) {
-		p.CreateBotDMPost(requestedUserID, message, "custom_git_review_request")
+	title := event.GetPullRequest().GetTitle()
+	if len(requestedUserID) > 0 && !p.notificationMutedByReceiver(requestedUserID, sender, repoName, title) {
+		p.sendReviewRequestDM(requestedUserID, message, event.GetRepo().GetOwner().GetLogin(), event.GetRepo().GetName(), event.GetPullRequest().GetNumber())
 		p.sendRefreshEvent(requestedUserID)
 	}
 
-	p.postIssueNotification(message, sender, authorUserID, assigneeUserID)
+	p.postIssueNotification(message, sender, repoName, title, authorUserID, assigneeUserID)
 }
 
 func (p *Plugin) handleIssueNotification(event *github.IssuesEvent) {
@@ -1492,16 +1668,16 @@ func (p *Plugin) handleIssueNotification(event *github.IssuesEvent) {
 		return
 	}
 
-	p.postIssueNotification(message, sender, authorUserID, assigneeUserID)
+	p.postIssueNotification(message, sender, repoName, event.GetIssue().GetTitle(), authorUserID, assigneeUserID)
 }
 
-func (p *Plugin) postIssueNotification(message, sender, authorUserID, assigneeUserID string) {
-	if len(authorUserID) > 0 && !p.senderMutedByReceiver(authorUserID, sender) {
+func (p *Plugin) postIssueNotification(message, sender, repoName, title, authorUserID, assigneeUserID string) {
+	if len(authorUserID) > 0 && !p.notificationMutedByReceiver(authorUserID, sender, repoName, title) {
 		p.CreateBotDMPost(authorUserID, message, "custom_git_author")
 		p.sendRefreshEvent(authorUserID)
 	}
 
-	if len(assigneeUserID) > 0 && !p.senderMutedByReceiver(assigneeUserID, sender) {
+	if len(assigneeUserID) > 0 && !p.notificationMutedByReceiver(assigneeUserID, sender, repoName, title) {
 		p.CreateBotDMPost(assigneeUserID, message, "custom_git_assigned")
 		p.sendRefreshEvent(assigneeUserID)
 	}
@@ -1526,7 +1702,7 @@ func (p *Plugin) handlePullRequestReviewNotification(event *github.PullRequestRe
 		return
 	}
 
-	if p.senderMutedByReceiver(authorUserID, event.GetSender().GetLogin()) {
+	if p.notificationMutedByReceiver(authorUserID, event.GetSender().GetLogin(), event.GetRepo().GetFullName(), event.GetPullRequest().GetTitle()) {
 		return
 	}
 
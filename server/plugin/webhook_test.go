@@ -574,6 +574,10 @@ func TestHandleReviewCommentMentionNotification(t *testing.T) {
 					_, ok := val.(*[]uint8)
 					return ok
 				})).Return(nil).Times(1)
+				mockKVStore.EXPECT().Get("otherUserID-mute-rules", mock.MatchedBy(func(val any) bool {
+					_, ok := val.(*[]MuteRule)
+					return ok
+				})).Return(nil).Times(1)
 				mockKVStore.EXPECT().Get("otherUserID_githubtoken", mock.MatchedBy(func(val any) bool {
 					_, ok := val.(**GitHubUserInfo)
 					return ok
@@ -636,6 +640,10 @@ func TestHandleReviewCommentAuthorNotification(t *testing.T) {
 					_, ok := val.(*[]uint8)
 					return ok
 				})).Return(nil).Times(1)
+				mockKVStore.EXPECT().Get("authorUserID-mute-rules", mock.MatchedBy(func(val any) bool {
+					_, ok := val.(*[]MuteRule)
+					return ok
+				})).Return(nil).Times(1)
 				mockKVStore.EXPECT().Get("authorUserID_githubtoken", mock.MatchedBy(func(val any) bool {
 					_, ok := val.(**GitHubUserInfo)
 					return ok
@@ -688,9 +696,24 @@ func TestHandleCommentMentionNotification(t *testing.T) {
 	}{
 		{
 			name:  "Unsupported action",
-			event: GetMockIssueCommentEvent(actionEdited, "mockBody", "mockUser"),
+			event: GetMockIssueCommentEvent(actionDeleted, "mockBody", "mockUser"),
 			setup: func(_ *plugintest.API, _ *mocks.MockKvStore) {},
 		},
+		{
+			name:  "Edit without a body change",
+			event: GetMockIssueCommentEvent(actionEdited, "mention @otherUser", "mockUser"),
+			setup: func(_ *plugintest.API, _ *mocks.MockKvStore) {},
+		},
+		{
+			name:  "Edit only notifies newly mentioned users",
+			event: GetMockIssueCommentEventWithChanges("mention @otherUser and @newUser", "mention @otherUser", "mockUser"),
+			setup: func(_ *plugintest.API, mockKVStore *mocks.MockKvStore) {
+				mockKVStore.EXPECT().Get("newUser_githubusername", mock.MatchedBy(func(val any) bool {
+					_, ok := val.(*[]uint8)
+					return ok
+				})).Return(nil).Times(1)
+			},
+		},
 		{
 			name:  "Commenter is the same as mentioned user",
 			event: GetMockIssueCommentEvent(actionCreated, "mention @mockUser", "mockUser"),
@@ -723,6 +746,10 @@ func TestHandleCommentMentionNotification(t *testing.T) {
 					_, ok := val.(*[]uint8)
 					return ok
 				})).Return(nil).Times(1)
+				mockKVStore.EXPECT().Get("otherUserID-mute-rules", mock.MatchedBy(func(val any) bool {
+					_, ok := val.(*[]MuteRule)
+					return ok
+				})).Return(nil).Times(1)
 				mockAPI.On("GetDirectChannel", "otherUserID", "mockBotID").Return(nil, &model.AppError{Message: "error getting channel"}).Times(1)
 			},
 		},
@@ -738,6 +765,10 @@ func TestHandleCommentMentionNotification(t *testing.T) {
 					_, ok := val.(*[]uint8)
 					return ok
 				})).Return(nil).Times(1)
+				mockKVStore.EXPECT().Get("otherUserID-mute-rules", mock.MatchedBy(func(val any) bool {
+					_, ok := val.(*[]MuteRule)
+					return ok
+				})).Return(nil).Times(1)
 				mockKVStore.EXPECT().Get("otherUserID_githubtoken", mock.MatchedBy(func(val any) bool {
 					_, ok := val.(**GitHubUserInfo)
 					return ok
@@ -759,6 +790,10 @@ func TestHandleCommentMentionNotification(t *testing.T) {
 					_, ok := val.(*[]uint8)
 					return ok
 				})).Return(nil).Times(1)
+				mockKVStore.EXPECT().Get("otherUserID-mute-rules", mock.MatchedBy(func(val any) bool {
+					_, ok := val.(*[]MuteRule)
+					return ok
+				})).Return(nil).Times(1)
 				mockKVStore.EXPECT().Get("otherUserID_githubtoken", mock.MatchedBy(func(val any) bool {
 					_, ok := val.(**GitHubUserInfo)
 					return ok
@@ -866,6 +901,10 @@ func TestHandleCommentAuthorNotification(t *testing.T) {
 					_, ok := val.(*[]uint8)
 					return ok
 				})).Return(nil).Times(1)
+				mockKVStore.EXPECT().Get("authorUserID-mute-rules", mock.MatchedBy(func(val any) bool {
+					_, ok := val.(*[]MuteRule)
+					return ok
+				})).Return(nil).Times(1)
 				mockKVStore.EXPECT().Get("authorUserID_githubtoken", mock.MatchedBy(func(val any) bool {
 					_, ok := val.(**GitHubUserInfo)
 					return ok
@@ -886,6 +925,10 @@ func TestHandleCommentAuthorNotification(t *testing.T) {
 					_, ok := val.(*[]uint8)
 					return ok
 				})).Return(nil).Times(1)
+				mockKVStore.EXPECT().Get("authorUserID-mute-rules", mock.MatchedBy(func(val any) bool {
+					_, ok := val.(*[]MuteRule)
+					return ok
+				})).Return(nil).Times(1)
 				mockKVStore.EXPECT().Get("authorUserID_githubtoken", mock.MatchedBy(func(val any) bool {
 					_, ok := val.(**GitHubUserInfo)
 					return ok
@@ -1044,6 +1087,10 @@ func TestHandlePullRequestNotification(t *testing.T) {
 					_, ok := val.(*[]uint8)
 					return ok
 				})).Return(nil).Times(1)
+				mockKVStore.EXPECT().Get("authorUserID-mute-rules", mock.MatchedBy(func(val any) bool {
+					_, ok := val.(*[]MuteRule)
+					return ok
+				})).Return(nil).Times(1)
 				mockKVStore.EXPECT().Get("authorUserID_githubtoken", mock.MatchedBy(func(val any) bool {
 					_, ok := val.(**GitHubUserInfo)
 					return ok
@@ -1079,6 +1126,10 @@ func TestHandlePullRequestNotification(t *testing.T) {
 					_, ok := val.(*[]uint8)
 					return ok
 				})).Return(nil).Times(1)
+				mockKVStore.EXPECT().Get("assigneeUserID-mute-rules", mock.MatchedBy(func(val any) bool {
+					_, ok := val.(*[]MuteRule)
+					return ok
+				})).Return(nil).Times(1)
 				mockKVStore.EXPECT().Get("assigneeUserID_githubtoken", mock.MatchedBy(func(val any) bool {
 					_, ok := val.(**GitHubUserInfo)
 					return ok
@@ -1099,6 +1150,10 @@ func TestHandlePullRequestNotification(t *testing.T) {
 					_, ok := val.(*[]uint8)
 					return ok
 				})).Return(nil).Times(1)
+				mockKVStore.EXPECT().Get("requestedUserID-mute-rules", mock.MatchedBy(func(val any) bool {
+					_, ok := val.(*[]MuteRule)
+					return ok
+				})).Return(nil).Times(1)
 				mockKVStore.EXPECT().Get("requestedUserID_githubtoken", mock.MatchedBy(func(val any) bool {
 					_, ok := val.(**GitHubUserInfo)
 					return ok
@@ -1388,6 +1443,10 @@ func TestHandlePullRequestReviewNotification(t *testing.T) {
 					_, ok := val.(*[]uint8)
 					return ok
 				})).Return(nil).Times(1)
+				mockKvStore.EXPECT().Get("authorUserID-mute-rules", mock.MatchedBy(func(val any) bool {
+					_, ok := val.(*[]MuteRule)
+					return ok
+				})).Return(nil).Times(1)
 				mockAPI.On("GetDirectChannel", "authorUserID", "mockBotID").Return(nil, &model.AppError{Message: "error getting channel"}).Times(1)
 				mockAPI.On("LogWarn", "Couldn't get bot's DM channel", "userID", "authorUserID", "error", "error getting channel")
 				mockKvStore.EXPECT().Get("authorUserID_githubtoken", mock.MatchedBy(func(val any) bool {